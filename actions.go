@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"enum/aws"
+	"enum/config"
+	"enum/ssh"
+)
+
+// Action targets.
+const (
+	actionTargetHost      = "host"
+	actionTargetContainer = "container"
+)
+
+// actionPlaceholder matches a {name} placeholder in an action's command template.
+var actionPlaceholder = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// runAction renders the named action from the config file with params and, once
+// confirmed, runs it on target: an instance ID or name for host actions, a container
+// ID for container actions.
+func runAction(name string, params []string, target string, assumeYes bool) error {
+	action, ok := userConfig.Actions[name]
+	if !ok {
+		return fmt.Errorf("unknown action %q; run `enum action` to list the configured actions", name)
+	}
+	values, err := parseActionParams(params)
+	if err != nil {
+		return err
+	}
+	command, err := renderAction(action, values)
+	if err != nil {
+		return fmt.Errorf("action %s: %v", name, err)
+	}
+
+	var host, where string
+	switch action.Target {
+	case actionTargetHost:
+		instance, err := findRunningInstance(target)
+		if err != nil {
+			return err
+		}
+		host, where = instance.PrivateIP, fmt.Sprintf("host %s (%s)", instance.Name, instance.InstanceID)
+	case actionTargetContainer:
+		instance, err := locateContainer(target)
+		if err != nil {
+			return err
+		}
+		command = fmt.Sprintf("sudo %s exec %s sh -c %s", containerCLI(instance), ssh.ShellQuote(target), ssh.ShellQuote(command))
+		host, where = instance.PrivateIP, fmt.Sprintf("container %s on %s", target, instance.Name)
+	default:
+		return fmt.Errorf("action %s has unsupported target %q, want host or container", name, action.Target)
+	}
+
+	fmt.Printf("Action %s will run on %s:\n\n  %s\n\n", name, where, command)
+	if err := confirmChange("Run it?", assumeYes); err == errNotConfirmed {
+		return fmt.Errorf("aborted")
+	} else if err != nil {
+		return err
+	}
+
+	result, err := ssh.SSHRun(host, command, verbose)
+	if err != nil {
+		return err
+	}
+	fmt.Print(result.Stdout)
+	fmt.Fprint(os.Stderr, result.Stderr)
+	if result.ExitCode != 0 {
+		return fmt.Errorf("action %s exited with status %d", name, result.ExitCode)
+	}
+	return nil
+}
+
+// parseActionParams parses --param k=v flags.
+func parseActionParams(params []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, param := range params {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --param %q, want key=value", param)
+		}
+		if _, dup := values[key]; dup {
+			return nil, fmt.Errorf("--param %s given more than once", key)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// renderAction validates values against the action's declared parameters and substitutes
+// them, shell-quoted, into its command template. Undeclared parameters, missing required
+// ones, values not matching their pattern and placeholders without a declared parameter
+// are all errors.
+func renderAction(action config.Action, values map[string]string) (string, error) {
+	declared := make(map[string]config.ActionParam)
+	resolved := make(map[string]string)
+	for _, param := range action.Params {
+		if _, dup := declared[param.Name]; dup {
+			return "", fmt.Errorf("parameter %s declared more than once", param.Name)
+		}
+		declared[param.Name] = param
+		value, ok := values[param.Name]
+		if !ok {
+			if param.Required {
+				return "", fmt.Errorf("missing required parameter %s", param.Name)
+			}
+			value = param.Default
+		}
+		if param.Pattern != "" {
+			pattern, err := regexp.Compile("^(?:" + param.Pattern + ")$")
+			if err != nil {
+				return "", fmt.Errorf("invalid pattern for parameter %s: %v", param.Name, err)
+			}
+			if !pattern.MatchString(value) {
+				return "", fmt.Errorf("parameter %s=%q doesn't match %s", param.Name, value, param.Pattern)
+			}
+		}
+		resolved[param.Name] = value
+	}
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			return "", fmt.Errorf("unknown parameter %s", name)
+		}
+	}
+
+	var undeclared []string
+	command := actionPlaceholder.ReplaceAllStringFunc(action.Command, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		value, ok := resolved[name]
+		if !ok {
+			undeclared = append(undeclared, name)
+			return placeholder
+		}
+		return ssh.ShellQuote(value)
+	})
+	if len(undeclared) > 0 {
+		return "", fmt.Errorf("command uses undeclared parameters %s", strings.Join(undeclared, ", "))
+	}
+	return command, nil
+}
+
+// findRunningInstance returns the running instance of the cluster with the given ID or name.
+func findRunningInstance(target string) (aws.InstanceData, error) {
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return aws.InstanceData{}, fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	for _, instance := range instances {
+		if instance.InstanceID == target || instance.Name == target {
+			if instance.PrivateIP == "" {
+				return aws.InstanceData{}, fmt.Errorf("instance %s has no private IP", target)
+			}
+			return instance, nil
+		}
+	}
+	return aws.InstanceData{}, fmt.Errorf("no running instance %s in the cluster", target)
+}
+
+// listActions prints the actions configured in the config file.
+func listActions() {
+	if len(userConfig.Actions) == 0 {
+		fmt.Println("No actions configured; add them under \"actions\" in the config file.")
+		return
+	}
+	names := make([]string, 0, len(userConfig.Actions))
+	for name := range userConfig.Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Action\tTarget\tParameters\tDescription")
+	for _, name := range names {
+		action := userConfig.Actions[name]
+		var params []string
+		for _, param := range action.Params {
+			if param.Required {
+				params = append(params, param.Name)
+			} else {
+				params = append(params, param.Name+"="+param.Default)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, action.Target, strings.Join(params, ", "), action.Description)
+	}
+	w.Flush()
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"enum/config"
+)
+
+func TestParseActionParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  []string
+		want    map[string]string
+		wantErr string
+	}{
+		{name: "none", want: map[string]string{}},
+		{name: "simple", params: []string{"path=/var/log/app.log"}, want: map[string]string{"path": "/var/log/app.log"}},
+		{name: "value with equals", params: []string{"q=a=b"}, want: map[string]string{"q": "a=b"}},
+		{name: "empty value", params: []string{"q="}, want: map[string]string{"q": ""}},
+		{name: "no equals", params: []string{"path"}, wantErr: "invalid --param"},
+		{name: "empty key", params: []string{"=x"}, wantErr: "invalid --param"},
+		{name: "duplicate", params: []string{"a=1", "a=2"}, wantErr: "more than once"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseActionParams(tt.params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderAction(t *testing.T) {
+	rotate := config.Action{
+		Command: "mv {path} {path}.1",
+		Params:  []config.ActionParam{{Name: "path", Required: true, Pattern: "/var/log/[A-Za-z0-9._/-]+"}},
+	}
+	echo := config.Action{
+		Command: "echo {msg}",
+		Params:  []config.ActionParam{{Name: "msg", Required: true}},
+	}
+
+	tests := []struct {
+		name    string
+		action  config.Action
+		values  map[string]string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "quoted value",
+			action: rotate,
+			values: map[string]string{"path": "/var/log/app.log"},
+			want:   "mv '/var/log/app.log' '/var/log/app.log'.1",
+		},
+		{
+			name:   "single quote is escaped",
+			action: echo,
+			values: map[string]string{"msg": "it's'; rm -rf / #"},
+			want:   `echo 'it'\''s'\''; rm -rf / #'`,
+		},
+		{
+			name:   "command substitution stays literal",
+			action: echo,
+			values: map[string]string{"msg": "$(reboot) `id` ${HOME}"},
+			want:   "echo '$(reboot) `id` ${HOME}'",
+		},
+		{
+			name:   "newline stays inside quotes",
+			action: echo,
+			values: map[string]string{"msg": "a\nreboot"},
+			want:   "echo 'a\nreboot'",
+		},
+		{
+			name:   "placeholder syntax in a value is not expanded",
+			action: echo,
+			values: map[string]string{"msg": "{msg}"},
+			want:   "echo '{msg}'",
+		},
+		{
+			name:    "pattern is anchored at the end",
+			action:  rotate,
+			values:  map[string]string{"path": "/var/log/x; reboot"},
+			wantErr: "doesn't match",
+		},
+		{
+			name:    "pattern is anchored at the start",
+			action:  rotate,
+			values:  map[string]string{"path": "/etc/shadow#/var/log/x"},
+			wantErr: "doesn't match",
+		},
+		{
+			name: "alternation is anchored as a whole",
+			action: config.Action{
+				Command: "echo {level}",
+				Params:  []config.ActionParam{{Name: "level", Required: true, Pattern: "debug|info"}},
+			},
+			values:  map[string]string{"level": "info; reboot"},
+			wantErr: "doesn't match",
+		},
+		{
+			name: "invalid pattern",
+			action: config.Action{
+				Command: "echo {a}",
+				Params:  []config.ActionParam{{Name: "a", Pattern: "("}},
+			},
+			wantErr: "invalid pattern",
+		},
+		{
+			name:    "missing required",
+			action:  rotate,
+			values:  map[string]string{},
+			wantErr: "missing required parameter path",
+		},
+		{
+			name:    "undeclared value",
+			action:  echo,
+			values:  map[string]string{"msg": "hi", "extra": "x"},
+			wantErr: "unknown parameter extra",
+		},
+		{
+			name: "duplicate declaration",
+			action: config.Action{
+				Command: "echo {a}",
+				Params:  []config.ActionParam{{Name: "a"}, {Name: "a"}},
+			},
+			wantErr: "declared more than once",
+		},
+		{
+			name:    "placeholder without declaration",
+			action:  config.Action{Command: "kill {pid}"},
+			wantErr: "undeclared parameters pid",
+		},
+		{
+			name: "default applies",
+			action: config.Action{
+				Command: "tail -n {lines} /var/log/messages",
+				Params:  []config.ActionParam{{Name: "lines", Default: "100", Pattern: "[0-9]+"}},
+			},
+			want: "tail -n '100' /var/log/messages",
+		},
+		{
+			name: "default is checked against the pattern",
+			action: config.Action{
+				Command: "tail -n {lines} /var/log/messages",
+				Params:  []config.ActionParam{{Name: "lines", Default: "all", Pattern: "[0-9]+"}},
+			},
+			wantErr: "doesn't match",
+		},
+		{
+			name: "empty default",
+			action: config.Action{
+				Command: "grep -r {flags} x",
+				Params:  []config.ActionParam{{Name: "flags"}},
+			},
+			want: "grep -r '' x",
+		},
+		{
+			name: "required param given empty",
+			action: config.Action{
+				Command: "echo {msg}",
+				Params:  []config.ActionParam{{Name: "msg", Required: true}},
+			},
+			values: map[string]string{"msg": ""},
+			want:   "echo ''",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderAction(tt.action, tt.values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"enum/aws"
+)
+
+// clusterWorkers bounds the number of clusters fetched concurrently with --all-clusters.
+const clusterWorkers = 4
+
+// fetchAllClusterInstances fetches the instances of every cluster in the account.
+// Each instance's Cluster field says where it came from. Clusters that fail are
+// logged and skipped so one broken cluster doesn't hide the rest, but when every
+// cluster fails there is nothing to show and the first failure is returned.
+func fetchAllClusterInstances(states aws.InstanceStates) ([]aws.InstanceData, error) {
+	clusters, err := cachedClusterNames()
+	if err != nil {
+		return nil, fmt.Errorf("error listing clusters: %v", err)
+	}
+
+	var instances []aws.InstanceData
+	var failed int
+	var firstErr error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+	for i := 0; i < clusterWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for cluster := range jobs {
+				clusterInstances, err := aws.FetchEC2InstanceDataInStates(cluster, awsProfile, states)
+				if err != nil && !aws.IsWarning(err) {
+					log.Printf("Error fetching instances for cluster %s: %v", cluster, err)
+					mu.Lock()
+					if failed++; firstErr == nil {
+						firstErr = fmt.Errorf("cluster %s: %v", cluster, err)
+					}
+					mu.Unlock()
+					continue
+				}
+				logVPCWarning(err)
+				mu.Lock()
+				instances = append(instances, clusterInstances...)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, cluster := range clusters {
+		jobs <- cluster
+	}
+	close(jobs)
+	wg.Wait()
+	if len(clusters) > 0 && failed == len(clusters) {
+		return nil, fmt.Errorf("fetching instances failed for all %d clusters, e.g. %v", failed, firstErr)
+	}
+
+	sort.SliceStable(instances, func(i, j int) bool {
+		if instances[i].Cluster != instances[j].Cluster {
+			return instances[i].Cluster < instances[j].Cluster
+		}
+		return instances[i].Name < instances[j].Name
+	})
+	return instances, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"enum/aws"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// setUpAllClusters caches clusters as the account's cluster list and answers instance
+// fetches like stubCluster, except for the clusters in broken, whose calls fail.
+func setUpAllClusters(t *testing.T, clusters []string, broken map[string]bool) {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("AWS_REGION", "us-west-2")
+	if err := writeCache(clusterCacheName(), "", clusters); err != nil {
+		t.Fatal(err)
+	}
+	aws.StubAPI(func(r *request.Request) {
+		if input, ok := r.Params.(*ecs.ListContainerInstancesInput); ok && broken[awssdk.StringValue(input.Cluster)] {
+			r.Error = fmt.Errorf("AccessDeniedException: not authorized on %s", awssdk.StringValue(input.Cluster))
+			return
+		}
+		stubCluster(r)
+	})
+	t.Cleanup(func() { aws.StubAPI(nil) })
+}
+
+func TestFetchAllClusterInstances(t *testing.T) {
+	tests := []struct {
+		name         string
+		clusters     []string
+		broken       map[string]bool
+		wantClusters []string
+		wantErr      string
+	}{
+		{name: "all fine", clusters: []string{"prod", "staging"}, wantClusters: []string{"prod", "staging"}},
+		{name: "one broken", clusters: []string{"prod", "staging"}, broken: map[string]bool{"prod": true}, wantClusters: []string{"staging"}},
+		{name: "all broken", clusters: []string{"prod", "staging"}, broken: map[string]bool{"prod": true, "staging": true}, wantErr: "failed for all 2 clusters"},
+		{name: "no clusters", clusters: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpAllClusters(t, tt.clusters, tt.broken)
+
+			instances, err := fetchAllClusterInstances(aws.RunningStates)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "AccessDenied") {
+					t.Fatalf("err = %v, want containing %q and the cause", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, instance := range instances {
+				got = append(got, instance.Cluster)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantClusters, ",") {
+				t.Errorf("instances from clusters %v, want %v", got, tt.wantClusters)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"enum/aws"
+)
+
+// amiRolloutInterval is how often --watch refreshes the rollout.
+const amiRolloutInterval = 30 * time.Second
+
+// amiGroup summarises the instances running one AMI.
+type amiGroup struct {
+	ImageID      string    `json:"image_id"`
+	Count        int       `json:"count"`
+	OldestLaunch time.Time `json:"oldest_launch"`
+	NewestLaunch time.Time `json:"newest_launch"`
+}
+
+// pendingInstance is an instance still on an old AMI, with the tasks draining it would move.
+type pendingInstance struct {
+	InstanceID   string `json:"instance_id"`
+	Name         string `json:"name"`
+	ImageID      string `json:"image_id"`
+	RunningTasks int    `json:"running_tasks"`
+}
+
+// amiRollout is the rollout state of the cluster, as printed or encoded for pipelines.
+type amiRollout struct {
+	Target          string            `json:"target,omitempty"`
+	Total           int               `json:"total"`
+	OnTarget        int               `json:"on_target"`
+	PercentComplete float64           `json:"percent_complete"`
+	Complete        bool              `json:"complete"`
+	AMIs            []amiGroup        `json:"amis"`
+	Pending         []pendingInstance `json:"pending,omitempty"`
+}
+
+// amiRolloutReport prints the cluster's AMI rollout, refreshing with watch until it completes.
+func amiRolloutReport(target, output string, watch bool) error {
+	if err := oneOf("table", "json")(output); err != nil {
+		return fmt.Errorf("unsupported output format %q: %v", output, err)
+	}
+	if watch && target == "" {
+		return fmt.Errorf("--watch needs a --target AMI to wait for")
+	}
+
+	for {
+		instances, err := fetchInstances(aws.RunningStates)
+		if err != nil {
+			return fmt.Errorf("error fetching EC2 instance data: %v", err)
+		}
+		rollout := summariseAMIs(instances, target)
+
+		if output == "json" {
+			if err := printJSON(rollout); err != nil {
+				return err
+			}
+		} else {
+			printAMIRollout(rollout)
+		}
+
+		if !watch || rollout.Complete {
+			return nil
+		}
+		time.Sleep(amiRolloutInterval)
+		if output == "table" {
+			fmt.Println()
+		}
+	}
+}
+
+// summariseAMIs groups instances by AMI and measures progress towards target.
+func summariseAMIs(instances []aws.InstanceData, target string) amiRollout {
+	rollout := amiRollout{Target: target, Total: len(instances), AMIs: []amiGroup{}}
+	groups := make(map[string]*amiGroup)
+	for _, instance := range instances {
+		group, ok := groups[instance.ImageID]
+		if !ok {
+			group = &amiGroup{ImageID: instance.ImageID, OldestLaunch: instance.LaunchTime, NewestLaunch: instance.LaunchTime}
+			groups[instance.ImageID] = group
+		}
+		group.Count++
+		if instance.LaunchTime.Before(group.OldestLaunch) {
+			group.OldestLaunch = instance.LaunchTime
+		}
+		if instance.LaunchTime.After(group.NewestLaunch) {
+			group.NewestLaunch = instance.LaunchTime
+		}
+
+		if target == "" {
+			continue
+		}
+		if instance.ImageID == target {
+			rollout.OnTarget++
+		} else {
+			rollout.Pending = append(rollout.Pending, pendingInstance{
+				InstanceID:   instance.InstanceID,
+				Name:         instance.Name,
+				ImageID:      instance.ImageID,
+				RunningTasks: instance.RunningTasksCount,
+			})
+		}
+	}
+
+	for _, group := range groups {
+		rollout.AMIs = append(rollout.AMIs, *group)
+	}
+	// Newest AMI generation first.
+	sort.Slice(rollout.AMIs, func(i, j int) bool {
+		return rollout.AMIs[i].NewestLaunch.After(rollout.AMIs[j].NewestLaunch)
+	})
+
+	if target != "" {
+		if rollout.Total > 0 {
+			rollout.PercentComplete = float64(rollout.OnTarget) / float64(rollout.Total) * 100
+		}
+		rollout.Complete = rollout.OnTarget == rollout.Total
+	}
+	return rollout
+}
+
+// printAMIRollout renders a rollout as tables.
+func printAMIRollout(rollout amiRollout) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "AMI\tInstances\tOldest Launch\tNewest Launch")
+	for _, group := range rollout.AMIs {
+		marker := ""
+		if group.ImageID == rollout.Target {
+			marker = " (target)"
+		}
+		fmt.Fprintf(w, "%s%s\t%d\t%s\t%s\n",
+			group.ImageID, marker, group.Count,
+			group.OldestLaunch.Local().Format(time.RFC3339),
+			group.NewestLaunch.Local().Format(time.RFC3339))
+	}
+	w.Flush()
+
+	if rollout.Target == "" {
+		return
+	}
+	fmt.Printf("\n%d of %d instances on %s (%.0f%% complete)\n", rollout.OnTarget, rollout.Total, rollout.Target, rollout.PercentComplete)
+	if len(rollout.Pending) == 0 {
+		return
+	}
+
+	fmt.Println("\nStill on an old AMI:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Instance ID\tName\tAMI\tRunning Tasks")
+	for _, instance := range rollout.Pending {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", instance.InstanceID, instance.Name, instance.ImageID, instance.RunningTasks)
+	}
+	w.Flush()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// apiSchemaVersion is bumped whenever the api-describe document changes shape.
+const apiSchemaVersion = 1
+
+// outputFormatsAnnotation is the cobra annotation listing a command's --output formats, comma separated.
+const outputFormatsAnnotation = "enum/output-formats"
+
+type apiDescription struct {
+	SchemaVersion int          `json:"schema_version"`
+	Version       string       `json:"version"`
+	Commands      []apiCommand `json:"commands"`
+}
+
+type apiCommand struct {
+	Path          string    `json:"path"`
+	Short         string    `json:"short"`
+	Args          []apiArg  `json:"args"`
+	Flags         []apiFlag `json:"flags"`
+	OutputFormats []string  `json:"output_formats"`
+}
+
+type apiArg struct {
+	Name     string `json:"name"`
+	Variadic bool   `json:"variadic"`
+}
+
+type apiFlag struct {
+	Name       string `json:"name"`
+	Shorthand  string `json:"shorthand,omitempty"`
+	Type       string `json:"type"`
+	Default    string `json:"default"`
+	Usage      string `json:"usage"`
+	Persistent bool   `json:"persistent"`
+}
+
+// describeAPI walks the live command tree so the description never drifts from the real CLI.
+func describeAPI(root *cobra.Command) apiDescription {
+	description := apiDescription{
+		SchemaVersion: apiSchemaVersion,
+		Version:       version,
+		Commands:      []apiCommand{},
+	}
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		if cmd.Hidden || cmd.Name() == "help" || cmd.Name() == "completion" {
+			return
+		}
+		description.Commands = append(description.Commands, describeCommand(cmd))
+		for _, child := range cmd.Commands() {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	sort.Slice(description.Commands, func(i, j int) bool {
+		return description.Commands[i].Path < description.Commands[j].Path
+	})
+	return description
+}
+
+func describeCommand(cmd *cobra.Command) apiCommand {
+	command := apiCommand{
+		Path:          cmd.CommandPath(),
+		Short:         cmd.Short,
+		Args:          []apiArg{},
+		Flags:         []apiFlag{},
+		OutputFormats: []string{},
+	}
+
+	// Positional arguments are only declared in the Use line, e.g. "shell [container-id] [args...]".
+	for _, field := range strings.Fields(cmd.Use)[1:] {
+		name := strings.Trim(field, "[]<>")
+		command.Args = append(command.Args, apiArg{
+			Name:     strings.TrimSuffix(name, "..."),
+			Variadic: strings.HasSuffix(name, "..."),
+		})
+	}
+
+	persistent := cmd.PersistentFlags()
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden || flag.Name == "help" {
+			return // cobra only adds --help to commands it has run, so it would make the output unstable
+		}
+		command.Flags = append(command.Flags, apiFlag{
+			Name:       flag.Name,
+			Shorthand:  flag.Shorthand,
+			Type:       flag.Value.Type(),
+			Default:    flag.DefValue,
+			Usage:      flag.Usage,
+			Persistent: persistent.Lookup(flag.Name) != nil,
+		})
+	})
+
+	if formats := cmd.Annotations[outputFormatsAnnotation]; formats != "" {
+		command.OutputFormats = strings.Split(formats, ",")
+	}
+	return command
+}
+
+func apiDescribe(root *cobra.Command, output string) error {
+	if output != "json" {
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+	return printJSON(describeAPI(root))
+}
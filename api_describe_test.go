@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDescribeAPIJSON(t *testing.T) {
+	data, err := json.Marshal(describeAPI(newRootCmd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode with its own JSON tags so the test checks the emitted keys, not the Go field names.
+	var doc struct {
+		SchemaVersion *int `json:"schema_version"`
+		Commands      []struct {
+			Path  string `json:"path"`
+			Flags []struct {
+				Name      string `json:"name"`
+				Shorthand string `json:"shorthand"`
+			} `json:"flags"`
+		} `json:"commands"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.SchemaVersion == nil || *doc.SchemaVersion != apiSchemaVersion {
+		t.Errorf("schema_version missing or wrong in %s", data[:80])
+	}
+
+	hasFlag := func(path, name, shorthand string) bool {
+		for _, command := range doc.Commands {
+			if command.Path != path {
+				continue
+			}
+			for _, flag := range command.Flags {
+				if flag.Name == name && flag.Shorthand == shorthand {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	root := human_readable_comand_name
+	if !hasFlag(root+" find", "sort", "") {
+		t.Errorf("%s find --sort missing from the description", root)
+	}
+	if !hasFlag(root+" list-ec2", "output", "o") {
+		t.Errorf("%s list-ec2 -o missing from the description", root)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"enum/aws"
+
+	"golang.org/x/term"
+)
+
+// attributeRequirement is a --require flag: an attribute that must be present and, when
+// Value is set, have that value.
+type attributeRequirement struct {
+	Name  string
+	Value string
+}
+
+func (r attributeRequirement) String() string {
+	if r.Value == "" {
+		return r.Name
+	}
+	return r.Name + "=" + r.Value
+}
+
+// showAttributes prints the container instance attributes of the cluster, or of the one
+// instance matching instanceFilter by ID or name, alongside the services' placement
+// constraints. It returns the number of instances missing a required attribute.
+func showAttributes(instanceFilter string, required []string) (int, error) {
+	var requirements []attributeRequirement
+	for _, r := range required {
+		name, value, _ := strings.Cut(r, "=")
+		if name == "" {
+			return 0, fmt.Errorf("invalid --require %q, want key or key=value", r)
+		}
+		requirements = append(requirements, attributeRequirement{Name: name, Value: value})
+	}
+
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	if instanceFilter != "" {
+		var matched []aws.InstanceData
+		for _, instance := range instances {
+			if instance.InstanceID == instanceFilter || instance.Name == instanceFilter {
+				matched = append(matched, instance)
+			}
+		}
+		if len(matched) == 0 {
+			return 0, fmt.Errorf("no running instance %s in the cluster", instanceFilter)
+		}
+		instances = matched
+	}
+
+	attributes, err := aws.FetchContainerInstanceAttributes(ActiveConfig.ClusterName, awsProfile)
+	if err != nil {
+		return 0, err
+	}
+	placements, err := aws.FetchServicePlacements(ActiveConfig.ClusterName, awsProfile)
+	if err != nil {
+		return 0, err
+	}
+
+	highlight := func(s string) string { return s }
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		highlight = func(s string) string { return "\033[31m" + s + "\033[0m" }
+	}
+
+	missingCount := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Instance ID\tName\tAttribute\tValue")
+	for _, instance := range instances {
+		instanceAttributes := attributes[instance.InstanceID]
+		names := make([]string, 0, len(instanceAttributes))
+		for name := range instanceAttributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		id, name := instance.InstanceID, instance.Name
+		for _, attribute := range names {
+			value := instanceAttributes[attribute]
+			if value == "" {
+				value = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", id, name, attribute, value)
+			id, name = "", "" // Only on the instance's first row
+		}
+
+		var missing []string
+		for _, requirement := range requirements {
+			value, ok := instanceAttributes[requirement.Name]
+			if !ok || requirement.Value != "" && value != requirement.Value {
+				missing = append(missing, requirement.String())
+			}
+		}
+		if len(missing) > 0 {
+			missingCount++
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", id, name, highlight("MISSING"), highlight(strings.Join(missing, ", ")))
+		}
+	}
+	w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Service\tPlacement Constraints")
+	for _, placement := range placements {
+		constraints := strings.Join(placement.Constraints, "; ")
+		if constraints == "" {
+			constraints = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", placement.Service, constraints)
+	}
+	w.Flush()
+
+	if missingCount > 0 {
+		fmt.Printf("\n%d of %d instances are missing a required attribute.\n", missingCount, len(instances))
+	}
+	return missingCount, nil
+}
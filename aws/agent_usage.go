@@ -0,0 +1,53 @@
+package aws
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"enum/ssh"
+)
+
+// agentUsageCommand prints the ECS agent's CPU percentage and resident memory in MB.
+// The agent runs as /agent inside the ecs-agent container on the ECS-optimized AMIs and
+// as amazon-ecs-agent elsewhere; ps truncates command names to 15 characters.
+const agentUsageCommand = `ps -eo pcpu=,rss=,comm= | awk '$3 == "agent" || $3 ~ /^amazon-ecs-agen/ {cpu += $1; rss += $2; found = 1} END {if (found) print cpu, rss / 1024}'`
+
+// PopulateAgentUsage sets AgentCPU and AgentMemMB on each instance from the ECS agent's
+// process over SSH. Like PopulateSSMStatus it is left to callers, as it contacts every host.
+// Hosts that can't be read are logged and left at zero.
+func PopulateAgentUsage(instances []InstanceData, opts ssh.SSHOptions) {
+	opts.Scheduler.Run(len(instances), func(i int) {
+		instance := &instances[i]
+		if instance.PrivateIP == "" {
+			return
+		}
+		output, err := ssh.SSHCommand(instance.PrivateIP, agentUsageCommand, opts.Verbose)
+		if err != nil {
+			log.Printf("Error reading ECS agent usage on instance %s: %v", instance.Name, err)
+			return
+		}
+		cpu, mem, err := parseAgentUsage(output)
+		if err != nil {
+			log.Printf("Error reading ECS agent usage on instance %s: %v", instance.Name, err)
+			return
+		}
+		instance.AgentCPU, instance.AgentMemMB = cpu, mem
+	})
+}
+
+// parseAgentUsage parses the "cpu mem" line printed by agentUsageCommand.
+func parseAgentUsage(output string) (cpu, mem float64, err error) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("ECS agent process not found")
+	}
+	if cpu, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		return 0, 0, fmt.Errorf("unexpected CPU usage %q", fields[0])
+	}
+	if mem, err = strconv.ParseFloat(fields[1], 64); err != nil {
+		return 0, 0, fmt.Errorf("unexpected memory usage %q", fields[1])
+	}
+	return cpu, mem, nil
+}
@@ -0,0 +1,44 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// GenerateAnsibleInventory writes an Ansible dynamic inventory with every
+// instance in groupName, keyed by private IP.
+func GenerateAnsibleInventory(instances []InstanceData, groupName string, w io.Writer) error {
+	hosts := []string{}
+	hostvars := make(map[string]map[string]string)
+	for _, instance := range instances {
+		if instance.PrivateIP == "" {
+			continue // Ansible can't reach an instance without an address
+		}
+		hosts = append(hosts, instance.PrivateIP)
+		hostvars[instance.PrivateIP] = map[string]string{
+			"instance_id":       instance.InstanceID,
+			"name":              instance.Name,
+			"state":             instance.State,
+			"instance_type":     instance.Type,
+			"cluster":           instance.Cluster,
+			"availability_zone": instance.AvailabilityZone,
+		}
+	}
+
+	inventory := map[string]interface{}{
+		groupName: map[string]interface{}{
+			"hosts": hosts,
+		},
+		"_meta": map[string]interface{}{
+			"hostvars": hostvars,
+		},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(inventory); err != nil {
+		return fmt.Errorf("failed to write inventory: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"enum/trace"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// apiCalls counts the AWS API requests sent during the run by "service:Operation",
+// retries included, since every attempt counts against the account's rate limits.
+var (
+	apiCallsMu sync.Mutex
+	apiCalls   = make(map[string]int)
+)
+
+// apiStub, when set by StubAPI, answers requests in place of AWS.
+var apiStub func(r *request.Request)
+
+// StubAPI makes sessions created afterwards answer every request with stub instead of
+// calling AWS, for tests. stub fills in r.Data or sets r.Error. Requests are neither
+// signed nor retried, and are still counted. A nil stub restores the real API.
+func StubAPI(stub func(r *request.Request)) {
+	apiStub = stub
+}
+
+// stubHandlers makes the session's requests go to apiStub. Clients add their own signing
+// and unmarshalling handlers on top of the session's, so each request is rewired as it
+// is validated, after they are in place.
+func stubHandlers(handlers *request.Handlers) {
+	handlers.Validate.PushBackNamed(request.NamedHandler{Name: "enum.stubAPI", Fn: func(r *request.Request) {
+		r.Handlers.Sign.Clear()
+		r.Handlers.Send.Clear()
+		r.Handlers.Send.PushBackNamed(request.NamedHandler{Name: "enum.countAPICall", Fn: countAPICall})
+		r.Handlers.Send.PushBack(apiStub)
+		r.Handlers.UnmarshalMeta.Clear()
+		r.Handlers.ValidateResponse.Clear()
+		r.Handlers.Unmarshal.Clear()
+		r.Handlers.UnmarshalError.Clear()
+		r.Handlers.Retry.Clear()
+		r.Handlers.AfterRetry.Clear()
+	}})
+}
+
+// countAPICall is a Send handler, added by newSession, that records each request
+// attempt and adds it to the trace.
+func countAPICall(r *request.Request) {
+	name := r.ClientInfo.ServiceName + ":" + r.Operation.Name
+
+	apiCallsMu.Lock()
+	apiCalls[name]++
+	apiCallsMu.Unlock()
+
+	span := trace.StartAt(name, "aws api", r.AttemptTime).Set("service", r.ClientInfo.ServiceName).Set("operation", r.Operation.Name)
+	if r.HTTPResponse != nil {
+		span.Set("status", r.HTTPResponse.StatusCode)
+	}
+	span.End()
+}
+
+// APICallCounts returns the number of AWS API requests sent so far by "service:Operation".
+func APICallCounts() map[string]int {
+	apiCallsMu.Lock()
+	defer apiCallsMu.Unlock()
+	counts := make(map[string]int, len(apiCalls))
+	for name, count := range apiCalls {
+		counts[name] = count
+	}
+	return counts
+}
+
+// APICallSummary describes the AWS API requests sent so far, busiest first, such as
+// "ecs:DescribeContainerInstances ×3, ec2:DescribeInstances ×1". It is empty when none were sent.
+func APICallSummary() string {
+	counts := APICallCounts()
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s ×%d", name, counts[name])
+	}
+	return strings.Join(parts, ", ")
+}
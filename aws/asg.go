@@ -0,0 +1,89 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// ASGActivityEvent is an Auto Scaling activity that mentions an instance.
+type ASGActivityEvent struct {
+	Description string
+	Cause       string
+	StartTime   time.Time
+	StatusCode  string
+}
+
+// FindAutoScalingGroup returns the name of the Auto Scaling group an instance belongs to.
+func FindAutoScalingGroup(instanceID, region, awsProfile string) (string, error) {
+	sess, err := newSession(awsProfile, region)
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %v", err)
+	}
+	output, err := autoscaling.New(sess).DescribeAutoScalingInstances(&autoscaling.DescribeAutoScalingInstancesInput{
+		InstanceIds: []*string{aws.String(instanceID)},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error describing auto scaling instance %s: %v", instanceID, err)
+	}
+	if len(output.AutoScalingInstances) == 0 {
+		return "", fmt.Errorf("instance %s is not part of an auto scaling group", instanceID)
+	}
+	return aws.StringValue(output.AutoScalingInstances[0].AutoScalingGroupName), nil
+}
+
+// FetchASGReplacementHistory returns the scaling activities of asgName that mention
+// instanceID, newest first. Terminated instances have left the group, so the caller
+// supplies the group name.
+func FetchASGReplacementHistory(instanceID, asgName, region, awsProfile string) ([]ASGActivityEvent, error) {
+	sess, err := newSession(awsProfile, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := autoscaling.New(sess)
+
+	// DescribeScalingActivities can't filter by instance, so match the ID in the
+	// activity text, which is where AWS records the instances launched or terminated.
+	var events []ASGActivityEvent
+	err = svc.DescribeScalingActivitiesPages(&autoscaling.DescribeScalingActivitiesInput{
+		AutoScalingGroupName: aws.String(asgName),
+	}, func(page *autoscaling.DescribeScalingActivitiesOutput, lastPage bool) bool {
+		for _, activity := range page.Activities {
+			description := aws.StringValue(activity.Description)
+			cause := aws.StringValue(activity.Cause)
+			if !strings.Contains(description, instanceID) && !strings.Contains(cause, instanceID) {
+				continue
+			}
+			events = append(events, ASGActivityEvent{
+				Description: description,
+				Cause:       cause,
+				StartTime:   aws.TimeValue(activity.StartTime),
+				StatusCode:  aws.StringValue(activity.StatusCode),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing scaling activities for %s: %v", asgName, err)
+	}
+
+	return events, nil
+}
+
+// DisplayASGActivityEvents prints Auto Scaling activities in a table format.
+func DisplayASGActivityEvents(events []ASGActivityEvent) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.Debug)
+	fmt.Fprintln(writer, "Start Time\tStatus\tDescription")
+	for _, event := range events {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n",
+			event.StartTime.Local().Format(time.RFC3339),
+			event.StatusCode,
+			event.Description)
+	}
+	writer.Flush()
+}
@@ -0,0 +1,118 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// ServicePlacement is the placement constraints of one ECS service.
+type ServicePlacement struct {
+	Service     string
+	Constraints []string // "type: expression", e.g. "memberOf: attribute:stack == blue"
+}
+
+// FetchContainerInstanceAttributes returns every attribute of each container instance in the
+// cluster, built-in ecs.* attributes included, keyed by EC2 instance ID. Attributes without
+// a value, such as capabilities, map to "".
+func FetchContainerInstanceAttributes(clusterName, awsProfile string) (map[string]map[string]string, error) {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ecs.New(sess)
+
+	var arns []*string
+	err = svc.ListContainerInstancesPages(&ecs.ListContainerInstancesInput{
+		Cluster: aws.String(clusterName),
+	}, func(page *ecs.ListContainerInstancesOutput, lastPage bool) bool {
+		arns = append(arns, page.ContainerInstanceArns...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing container instances for cluster %s: %v", clusterName, err)
+	}
+
+	attributes := make(map[string]map[string]string)
+	// DescribeContainerInstances accepts at most 100 container instances per call.
+	for start := 0; start < len(arns); start += 100 {
+		resp, err := svc.DescribeContainerInstances(&ecs.DescribeContainerInstancesInput{
+			Cluster:            aws.String(clusterName),
+			ContainerInstances: arns[start:min(start+100, len(arns))],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error describing container instances: %v", err)
+		}
+		for _, containerInstance := range resp.ContainerInstances {
+			instanceAttributes := make(map[string]string)
+			for _, attribute := range containerInstance.Attributes {
+				targetType := aws.StringValue(attribute.TargetType)
+				if targetType != "" && targetType != ecs.TargetTypeContainerInstance {
+					continue
+				}
+				instanceAttributes[aws.StringValue(attribute.Name)] = aws.StringValue(attribute.Value)
+			}
+			attributes[aws.StringValue(containerInstance.Ec2InstanceId)] = instanceAttributes
+		}
+	}
+	return attributes, nil
+}
+
+// FetchServicePlacements returns the placement constraints of every service in the cluster, by service name.
+func FetchServicePlacements(clusterName, awsProfile string) ([]ServicePlacement, error) {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ecs.New(sess)
+
+	var arns []*string
+	err = svc.ListServicesPages(&ecs.ListServicesInput{
+		Cluster: aws.String(clusterName),
+	}, func(page *ecs.ListServicesOutput, lastPage bool) bool {
+		arns = append(arns, page.ServiceArns...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing services for cluster %s: %v", clusterName, err)
+	}
+
+	var placements []ServicePlacement
+	// DescribeServices accepts at most 10 services per call.
+	for start := 0; start < len(arns); start += 10 {
+		resp, err := svc.DescribeServices(&ecs.DescribeServicesInput{
+			Cluster:  aws.String(clusterName),
+			Services: arns[start:min(start+10, len(arns))],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error describing services: %v", err)
+		}
+		for _, service := range resp.Services {
+			placements = append(placements, ServicePlacement{
+				Service:     aws.StringValue(service.ServiceName),
+				Constraints: formatPlacementConstraints(service.PlacementConstraints),
+			})
+		}
+	}
+
+	sort.Slice(placements, func(i, j int) bool {
+		return placements[i].Service < placements[j].Service
+	})
+	return placements, nil
+}
+
+// formatPlacementConstraints renders each constraint as "type: expression", or just the
+// type for constraints without an expression such as distinctInstance.
+func formatPlacementConstraints(constraints []*ecs.PlacementConstraint) []string {
+	var formatted []string
+	for _, constraint := range constraints {
+		expression := aws.StringValue(constraint.Type)
+		if aws.StringValue(constraint.Expression) != "" {
+			expression += ": " + aws.StringValue(constraint.Expression)
+		}
+		formatted = append(formatted, expression)
+	}
+	return formatted
+}
@@ -0,0 +1,140 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
+)
+
+// AutoScalingConfig is the Application Auto Scaling setup of an ECS service's desired count.
+type AutoScalingConfig struct {
+	ResourceID  string // "service/<cluster>/<service>"
+	MinCapacity int64
+	MaxCapacity int64
+	Suspended   []string // Scaling activities that are suspended, e.g. "scale-in"
+	Policies    []ScalingPolicyInfo
+}
+
+// ScalingPolicyInfo is one scaling policy of a service.
+type ScalingPolicyInfo struct {
+	Name   string
+	Type   string // "TargetTrackingScaling" or "StepScaling"
+	Detail string // The tracked metric and target, or the step adjustments
+}
+
+// FetchServiceAutoScaling returns the service's scalable target and scaling policies, or nil
+// when the service isn't registered with Application Auto Scaling. An empty region uses the
+// default region.
+func FetchServiceAutoScaling(clusterName, serviceName, region, awsProfile string) (*AutoScalingConfig, error) {
+	sess, err := newSession(awsProfile, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := applicationautoscaling.New(sess)
+	resourceID := fmt.Sprintf("service/%s/%s", clusterName, serviceName)
+
+	targets, err := svc.DescribeScalableTargets(&applicationautoscaling.DescribeScalableTargetsInput{
+		ServiceNamespace:  aws.String(applicationautoscaling.ServiceNamespaceEcs),
+		ResourceIds:       []*string{aws.String(resourceID)},
+		ScalableDimension: aws.String(applicationautoscaling.ScalableDimensionEcsServiceDesiredCount),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing scalable target %s: %v", resourceID, err)
+	}
+	if len(targets.ScalableTargets) == 0 {
+		return nil, nil
+	}
+	target := targets.ScalableTargets[0]
+	config := &AutoScalingConfig{
+		ResourceID:  resourceID,
+		MinCapacity: aws.Int64Value(target.MinCapacity),
+		MaxCapacity: aws.Int64Value(target.MaxCapacity),
+	}
+	if suspended := target.SuspendedState; suspended != nil {
+		if aws.BoolValue(suspended.DynamicScalingInSuspended) {
+			config.Suspended = append(config.Suspended, "scale-in")
+		}
+		if aws.BoolValue(suspended.DynamicScalingOutSuspended) {
+			config.Suspended = append(config.Suspended, "scale-out")
+		}
+		if aws.BoolValue(suspended.ScheduledScalingSuspended) {
+			config.Suspended = append(config.Suspended, "scheduled")
+		}
+	}
+
+	err = svc.DescribeScalingPoliciesPages(&applicationautoscaling.DescribeScalingPoliciesInput{
+		ServiceNamespace:  aws.String(applicationautoscaling.ServiceNamespaceEcs),
+		ResourceId:        aws.String(resourceID),
+		ScalableDimension: aws.String(applicationautoscaling.ScalableDimensionEcsServiceDesiredCount),
+	}, func(page *applicationautoscaling.DescribeScalingPoliciesOutput, lastPage bool) bool {
+		for _, policy := range page.ScalingPolicies {
+			config.Policies = append(config.Policies, ScalingPolicyInfo{
+				Name:   aws.StringValue(policy.PolicyName),
+				Type:   aws.StringValue(policy.PolicyType),
+				Detail: scalingPolicyDetail(policy),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing scaling policies of %s: %v", resourceID, err)
+	}
+	return config, nil
+}
+
+// scalingPolicyDetail summarizes what a policy reacts to, e.g. "ECSServiceAverageCPUUtilization at 60".
+func scalingPolicyDetail(policy *applicationautoscaling.ScalingPolicy) string {
+	if tracking := policy.TargetTrackingScalingPolicyConfiguration; tracking != nil {
+		metric := "custom metric"
+		if predefined := tracking.PredefinedMetricSpecification; predefined != nil {
+			metric = aws.StringValue(predefined.PredefinedMetricType)
+		} else if custom := tracking.CustomizedMetricSpecification; custom != nil && custom.MetricName != nil {
+			metric = aws.StringValue(custom.Namespace) + "/" + aws.StringValue(custom.MetricName)
+		}
+		detail := fmt.Sprintf("%s at %g", metric, aws.Float64Value(tracking.TargetValue))
+		if aws.BoolValue(tracking.DisableScaleIn) {
+			detail += ", scale-in disabled"
+		}
+		return detail
+	}
+	if step := policy.StepScalingPolicyConfiguration; step != nil {
+		var steps []string
+		for _, adjustment := range step.StepAdjustments {
+			lower, upper := "-inf", "+inf"
+			if adjustment.MetricIntervalLowerBound != nil {
+				lower = fmt.Sprintf("%g", aws.Float64Value(adjustment.MetricIntervalLowerBound))
+			}
+			if adjustment.MetricIntervalUpperBound != nil {
+				upper = fmt.Sprintf("%g", aws.Float64Value(adjustment.MetricIntervalUpperBound))
+			}
+			steps = append(steps, fmt.Sprintf("[%s, %s): %+d", lower, upper, aws.Int64Value(adjustment.ScalingAdjustment)))
+		}
+		return fmt.Sprintf("%s %s", aws.StringValue(step.AdjustmentType), strings.Join(steps, "; "))
+	}
+	return ""
+}
+
+// DisplayServiceAutoScaling prints a service's auto scaling configuration in a table format.
+func DisplayServiceAutoScaling(config *AutoScalingConfig) {
+	fmt.Printf("Scalable target: %s\n", config.ResourceID)
+	fmt.Printf("Capacity: min %d, max %d\n", config.MinCapacity, config.MaxCapacity)
+	if len(config.Suspended) > 0 {
+		fmt.Printf("Suspended: %s\n", strings.Join(config.Suspended, ", "))
+	}
+	if len(config.Policies) == 0 {
+		fmt.Println("\nNo scaling policies.")
+		return
+	}
+
+	fmt.Println()
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "Policy\tType\tDetail")
+	for _, policy := range config.Policies {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", policy.Name, policy.Type, policy.Detail)
+	}
+	writer.Flush()
+}
@@ -1,56 +1,166 @@
 package aws
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 
 	"text/tabwriter"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
 )
 
 type InstanceData struct {
-	InstanceID string
-	Name       string
-	State      string
-	Type       string
-	PrivateIP  string
+	InstanceID             string
+	Name                   string
+	Tags                   map[string]string // Every tag on the EC2 instance, including Name
+	State                  string
+	Type                   string
+	Architecture           string // "x86_64" or "arm64"
+	ImageID                string
+	PrivateIP              string
+	VPCID                  string
+	SecurityGroupIDs       []string
+	Cluster                string
+	Clusters               []string // Every ECS cluster the instance is registered with; only set by PopulateClusterMemberships
+	AvailabilityZone       string
+	CPUCount               int // Registered CPU in ECS CPU units (1024 per vCPU)
+	MemoryMiB              int // Registered memory in MiB
+	CPUReserved            int // CPU units reserved by running tasks
+	MemoryReserved         int // Memory in MiB reserved by running tasks
+	RunningTasksCount      int
+	CustomAttributes       map[string]string // Container instance attributes outside the ecs. namespace
+	DrainingReason         string            // Why the container instance is DRAINING, if it is
+	AgentUpdateStatus      string
+	AgentVersion           string  // ECS agent version reported at registration
+	AgentCPU               float64 // ECS agent CPU usage in percent; only set by PopulateAgentUsage
+	AgentMemMB             float64 // ECS agent resident memory in MB; only set by PopulateAgentUsage
+	UpdateStuck            bool    // Agent update has been PENDING for longer than agentUpdateStuckAfter
+	LaunchTime             time.Time
+	ENIAttachmentTime      time.Time // When the primary network interface attached
+	ECSRegisteredAt        time.Time // When the container instance registered with ECS
+	SystemStatusCheck      string    // EC2 system reachability check; only set by PopulateStatusChecks
+	InstanceStatusCheck    string    // EC2 instance reachability check; only set by PopulateStatusChecks
+	SSMAgentActive         bool      // Only set by PopulateSSMStatus
+	StorageDriver          string    // docker's storage driver, e.g. overlay2; only set by PopulateStorageDriver
+	StorageDriverOptions   []string  // The driver's status as key=value pairs; only set by PopulateStorageDriver
+	ContainerRuntime       string    // "docker" or "containerd"; only set once enum has connected to the host
+	CloudWatchAgentRunning bool      // Only set by PopulateCloudWatchAgentStatus
+	CloudWatchAgentConfig  string    // The agent's configstatus, e.g. "configured"; only set by PopulateCloudWatchAgentStatus
+	RegistrationErrors     []string  // Recent registration failures in the ECS agent log; only set by PopulateRegistrationErrors
+	CgroupVersion          int       // 1 or 2; only set by PopulateCgroupVersion
+	ZombieProcessCount     int       // Processes in the Z state; only set by PopulateZombieProcesses
+	ZombiesChecked         bool      // ZombieProcessCount was read from the host
+	ManagedDraining        string    // ManagedDrainingEnabled or ManagedDrainingPending; empty when the capacity provider doesn't manage draining
 }
 
-// listECSClusters lists all ECS clusters and outputs them in a table format.
-func ListECSClusters(awsProfile string) error {
+// RegistrationDelay is how long after launch the instance registered with ECS, or 0
+// when it hasn't registered.
+func (i InstanceData) RegistrationDelay() time.Duration {
+	if i.ECSRegisteredAt.IsZero() {
+		return 0
+	}
+	return i.ECSRegisteredAt.Sub(i.LaunchTime)
+}
+
+// DisplayOptions controls which optional columns DisplayEC2Instances prints.
+type DisplayOptions struct {
+	ShowResources       bool
+	ShowAttributes      bool
+	ShowDrainReason     bool
+	ShowCluster         bool
+	ShowTiming          bool
+	ShowManagedDraining bool
+	ShowTags            bool
+	ShowAgentUsage      bool
+	ShowCloudWatchAgent bool
+	ShowClusters        bool // Every cluster the instance is registered with
+	ShowStorageDriver   bool
+	ShowStatusChecks    bool
+	ShowZombies         bool
+	ShowRegistration    bool // Registration errors from the ECS agent log
+	ShowCgroupVersion   bool
+	TagColumns          []string // Tag keys to show as columns of their own
+	Color               bool     // Colorize states other than running
+}
+
+// agentUpdateStuckAfter is how long an agent update may stay PENDING on an instance
+// registered at least that long ago before it is reported as stuck.
+const agentUpdateStuckAfter = 30 * time.Minute
+
+// ManagedDraining values for instances whose capacity provider has managed draining enabled.
+const (
+	ManagedDrainingEnabled = "ENABLED"
+	ManagedDrainingPending = "PENDING" // DRAINING, ECS is still moving tasks off before termination
+)
+
+// newSession creates an AWS session for the given profile and region, using Region() when region is empty.
+func newSession(awsProfile, region string) (*session.Session, error) {
+	if region == "" {
+		region = Region()
+	}
 	sess, err := session.NewSessionWithOptions(session.Options{
-		Profile: awsProfile, // Specify the profile name here
+		Profile: awsProfile,
 		Config: aws.Config{
-			Region: aws.String("us-west-2"), // Set your AWS region here
+			Region: aws.String(region),
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create session: %v", err)
+		return nil, err
 	}
+	sess.Handlers.Send.PushBackNamed(request.NamedHandler{Name: "enum.countAPICall", Fn: countAPICall})
+	if apiStub != nil {
+		stubHandlers(&sess.Handlers)
+	}
+	return sess, nil
+}
 
-	svc := ecs.New(sess)
-	input := &ecs.ListClustersInput{}
-	result, err := svc.ListClusters(input)
+// FetchECSClusterNames returns the names of all ECS clusters svc can see, sorted alphabetically.
+func FetchECSClusterNames(svc ecsiface.ECSAPI) ([]string, error) {
+	// ListClusters returns at most 100 clusters per page.
+	var clusterArns []*string
+	err := svc.ListClustersPages(&ecs.ListClustersInput{}, func(page *ecs.ListClustersOutput, lastPage bool) bool {
+		clusterArns = append(clusterArns, page.ClusterArns...)
+		return true
+	})
 	if err != nil {
-		return fmt.Errorf("failed to list clusters: %v", err)
+		return nil, fmt.Errorf("failed to list clusters: %v", err)
 	}
 
 	// Extract and sort cluster names from ARNs
 	var clusterNames []string
-	for _, arn := range result.ClusterArns {
+	for _, arn := range clusterArns {
 		splitARN := strings.Split(*arn, "/")
 		name := splitARN[len(splitARN)-1] // Assumes the cluster name is the last segment of the ARN
 		clusterNames = append(clusterNames, name)
 	}
 	sort.Strings(clusterNames) // Sort the cluster names alphabetically
 
+	return clusterNames, nil
+}
+
+// listECSClusters lists all ECS clusters and outputs them in a table format.
+func ListECSClusters(awsProfile string) error {
+	clients, err := NewClients(awsProfile)
+	if err != nil {
+		return err
+	}
+	clusterNames, err := FetchECSClusterNames(clients.ECS)
+	if err != nil {
+		return err
+	}
+
 	// Output the cluster names in a table format
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "Cluster Name\t")
@@ -63,15 +173,22 @@ func ListECSClusters(awsProfile string) error {
 	return nil
 }
 
+// FetchEC2InstanceData fetches the cluster's container instances, optionally only the running ones.
+//
+// Deprecated: use FetchEC2InstanceDataInStates, which can include states other than running.
 func FetchEC2InstanceData(clusterName string, awsProfile string, onlyRunning bool) ([]InstanceData, error) {
+	if onlyRunning {
+		return FetchEC2InstanceDataInStates(clusterName, awsProfile, RunningStates)
+	}
+	return FetchEC2InstanceDataInStates(clusterName, awsProfile, AllStates)
+}
+
+// FetchEC2InstanceDataInStates fetches the cluster's container instances whose EC2 state is in states.
+// Terminated instances are only included while they are still registered with the cluster.
+func FetchEC2InstanceDataInStates(clusterName string, awsProfile string, states InstanceStates) ([]InstanceData, error) {
 	var instances []InstanceData
 
-	sess, err := session.NewSessionWithOptions(session.Options{
-		Profile: awsProfile,
-		Config: aws.Config{
-			Region: aws.String("us-west-2"), // Set your AWS region here
-		},
-	})
+	sess, err := newSession(awsProfile, "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %v", err)
 	}
@@ -102,8 +219,15 @@ func FetchEC2InstanceData(clusterName string, awsProfile string, onlyRunning boo
 	}
 
 	var instanceIds []*string
+	containerInstances := make(map[string]*ecs.ContainerInstance)
 	for _, instance := range describeResp.ContainerInstances {
 		instanceIds = append(instanceIds, instance.Ec2InstanceId)
+		containerInstances[aws.StringValue(instance.Ec2InstanceId)] = instance
+	}
+
+	managedDraining, err := managedDrainingProviders(ecsSvc, describeResp.ContainerInstances)
+	if err != nil {
+		return nil, err
 	}
 
 	ec2Params := &ec2.DescribeInstancesInput{
@@ -117,22 +241,62 @@ func FetchEC2InstanceData(clusterName string, awsProfile string, onlyRunning boo
 	for _, reservation := range ec2Resp.Reservations {
 		for _, instance := range reservation.Instances {
 			instanceName := "Unnamed"
+			tags := make(map[string]string, len(instance.Tags))
 			for _, tag := range instance.Tags {
+				tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
 				if *tag.Key == "Name" {
 					instanceName = *tag.Value
-					break
 				}
 			}
-			if onlyRunning && *instance.State.Name != "running" {
+			if !states.Includes(aws.StringValue(instance.State.Name)) {
 				continue
 			}
-			instances = append(instances, InstanceData{
-				InstanceID: aws.StringValue(instance.InstanceId),
-				Name:       instanceName,
-				State:      aws.StringValue(instance.State.Name),
-				Type:       aws.StringValue(instance.InstanceType),
-				PrivateIP:  aws.StringValue(instance.PrivateIpAddress),
-			})
+			data := InstanceData{
+				InstanceID:       aws.StringValue(instance.InstanceId),
+				Name:             instanceName,
+				Tags:             tags,
+				State:            aws.StringValue(instance.State.Name),
+				Type:             aws.StringValue(instance.InstanceType),
+				Architecture:     aws.StringValue(instance.Architecture),
+				ImageID:          aws.StringValue(instance.ImageId),
+				PrivateIP:        aws.StringValue(instance.PrivateIpAddress),
+				VPCID:            aws.StringValue(instance.VpcId),
+				Cluster:          clusterName,
+				AvailabilityZone: aws.StringValue(instance.Placement.AvailabilityZone),
+				LaunchTime:       aws.TimeValue(instance.LaunchTime),
+			}
+			for _, group := range instance.SecurityGroups {
+				data.SecurityGroupIDs = append(data.SecurityGroupIDs, aws.StringValue(group.GroupId))
+			}
+			for _, eni := range instance.NetworkInterfaces {
+				if eni.Attachment != nil && aws.Int64Value(eni.Attachment.DeviceIndex) == 0 {
+					data.ENIAttachmentTime = aws.TimeValue(eni.Attachment.AttachTime)
+				}
+			}
+			if containerInstance, ok := containerInstances[data.InstanceID]; ok {
+				data.CPUCount = integerResource(containerInstance.RegisteredResources, "CPU")
+				data.MemoryMiB = integerResource(containerInstance.RegisteredResources, "MEMORY")
+				data.CPUReserved = data.CPUCount - integerResource(containerInstance.RemainingResources, "CPU")
+				data.MemoryReserved = data.MemoryMiB - integerResource(containerInstance.RemainingResources, "MEMORY")
+				data.RunningTasksCount = int(aws.Int64Value(containerInstance.RunningTasksCount))
+				data.CustomAttributes = customAttributes(containerInstance.Attributes)
+				if aws.StringValue(containerInstance.Status) == "DRAINING" {
+					data.DrainingReason = aws.StringValue(containerInstance.StatusReason)
+				}
+				data.AgentUpdateStatus = aws.StringValue(containerInstance.AgentUpdateStatus)
+				if containerInstance.VersionInfo != nil {
+					data.AgentVersion = aws.StringValue(containerInstance.VersionInfo.AgentVersion)
+				}
+				data.UpdateStuck = updateStuck(containerInstance, time.Now())
+				data.ECSRegisteredAt = aws.TimeValue(containerInstance.RegisteredAt)
+				if managedDraining[aws.StringValue(containerInstance.CapacityProviderName)] {
+					data.ManagedDraining = ManagedDrainingEnabled
+					if aws.StringValue(containerInstance.Status) == "DRAINING" {
+						data.ManagedDraining = ManagedDrainingPending
+					}
+				}
+			}
+			instances = append(instances, data)
 		}
 	}
 
@@ -141,19 +305,258 @@ func FetchEC2InstanceData(clusterName string, awsProfile string, onlyRunning boo
 		return instances[i].Name < instances[j].Name
 	})
 
-	return instances, nil
+	var warnings []error
+	if duplicates := findDuplicateIPs(instances); len(duplicates) > 0 {
+		err := &DuplicateIPError{Duplicates: duplicates}
+		log.Printf("Warning: %v", err)
+		warnings = append(warnings, err)
+	}
+	// Left to the caller to log, since some clusters span VPCs on purpose.
+	if vpcs := findVPCs(instances); vpcs != nil {
+		warnings = append(warnings, &MultipleVPCError{VPCs: vpcs})
+	}
+
+	return instances, errors.Join(warnings...)
+}
+
+// updateStuck reports whether the agent update is PENDING on an instance registered
+// long enough ago that the update should have progressed.
+func updateStuck(containerInstance *ecs.ContainerInstance, now time.Time) bool {
+	return aws.StringValue(containerInstance.AgentUpdateStatus) == ecs.AgentUpdateStatusPending &&
+		now.Sub(aws.TimeValue(containerInstance.RegisteredAt)) > agentUpdateStuckAfter
+}
+
+// managedDrainingProviders returns the capacity providers of the container instances that
+// have managed draining enabled. The container instance health status doesn't report
+// managed draining, so it is read from the Auto Scaling group provider settings instead.
+func managedDrainingProviders(ecsSvc *ecs.ECS, containerInstances []*ecs.ContainerInstance) (map[string]bool, error) {
+	var names []*string
+	seen := make(map[string]bool)
+	for _, containerInstance := range containerInstances {
+		name := aws.StringValue(containerInstance.CapacityProviderName)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, aws.String(name))
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	resp, err := ecsSvc.DescribeCapacityProviders(&ecs.DescribeCapacityProvidersInput{
+		CapacityProviders: names,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing capacity providers: %v", err)
+	}
+	enabled := make(map[string]bool)
+	for _, provider := range resp.CapacityProviders {
+		if asg := provider.AutoScalingGroupProvider; asg != nil && aws.StringValue(asg.ManagedDraining) == ecs.ManagedDrainingEnabled {
+			enabled[aws.StringValue(provider.Name)] = true
+		}
+	}
+	return enabled, nil
+}
+
+// integerResource returns the value of the named INTEGER resource, or 0 if it is absent.
+func integerResource(resources []*ecs.Resource, name string) int {
+	for _, resource := range resources {
+		if aws.StringValue(resource.Name) == name && aws.StringValue(resource.Type) == "INTEGER" {
+			return int(aws.Int64Value(resource.IntegerValue))
+		}
+	}
+	return 0
 }
 
-func DisplayEC2Instances(instances []InstanceData) {
+// customAttributes returns the user-defined attributes targeting the container instance itself.
+func customAttributes(attributes []*ecs.Attribute) map[string]string {
+	custom := make(map[string]string)
+	for _, attribute := range attributes {
+		targetType := aws.StringValue(attribute.TargetType)
+		if targetType != "" && targetType != ecs.TargetTypeContainerInstance {
+			continue
+		}
+		name := aws.StringValue(attribute.Name)
+		if strings.HasPrefix(name, "ecs.") {
+			continue // Built-in attributes set by the ECS agent
+		}
+		custom[name] = aws.StringValue(attribute.Value)
+	}
+	return custom
+}
+
+func DisplayEC2Instances(instances []InstanceData, opts DisplayOptions) {
 	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.Debug)
-	fmt.Fprintln(writer, "Instance ID\tName\tState\tType\tPrivate IP") // Print header
+	header := "Instance ID\tName\tState\tType\tArch\tPrivate IP"
+	if opts.ShowCluster {
+		header = "Cluster\t" + header
+	}
+	if opts.ShowResources {
+		header += "\tvCPUs\tMemory (GiB)"
+	}
+	if opts.ShowAttributes {
+		header += "\tAttributes"
+	}
+	if opts.ShowDrainReason {
+		header += "\tDraining Reason"
+	}
+	if opts.ShowTiming {
+		header += "\tLaunch Time\tENI Attached\tENI Delay\tECS Registered\tRegistration Delay"
+	}
+	if opts.ShowManagedDraining {
+		header += "\tManaged Draining"
+	}
+	if opts.ShowAgentUsage {
+		header += "\tAgent CPU %\tAgent Mem (MB)"
+	}
+	if opts.ShowCloudWatchAgent {
+		header += "\tCW Agent\tCW Config"
+	}
+	if opts.ShowClusters {
+		header += "\tClusters"
+	}
+	if opts.ShowStorageDriver {
+		header += "\tStorage Driver\tDriver Options"
+	}
+	if opts.ShowStatusChecks {
+		header += "\tSystem Check\tInstance Check"
+	}
+	if opts.ShowZombies {
+		header += "\tZombies"
+	}
+	if opts.ShowRegistration {
+		header += "\tLast Registration Error"
+	}
+	if opts.ShowCgroupVersion {
+		header += "\tCgroup"
+	}
+	if opts.ShowTags {
+		header += "\tTags"
+	}
+	for _, key := range opts.TagColumns {
+		header += "\t" + key
+	}
+	fmt.Fprintln(writer, header) // Print header
 	for _, instance := range instances {
-		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n",
+		state := instance.State
+		if opts.Color {
+			state = colorState(state)
+		}
+		if opts.ShowCluster {
+			fmt.Fprintf(writer, "%s\t", instance.Cluster)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s",
 			instance.InstanceID,
 			instance.Name,
-			instance.State,
+			state,
 			instance.Type,
+			instance.Architecture,
 			instance.PrivateIP)
+		if opts.ShowResources {
+			fmt.Fprintf(writer, "\t%.2f\t%.2f",
+				float64(instance.CPUCount)/1024,
+				float64(instance.MemoryMiB)/1024)
+		}
+		if opts.ShowAttributes {
+			attributes, _ := json.Marshal(instance.CustomAttributes)
+			fmt.Fprintf(writer, "\t%s", attributes)
+		}
+		if opts.ShowDrainReason {
+			fmt.Fprintf(writer, "\t%s", instance.DrainingReason)
+		}
+		if opts.ShowTiming {
+			attached, delay := "-", "-"
+			if !instance.ENIAttachmentTime.IsZero() {
+				attached = instance.ENIAttachmentTime.Local().Format(time.RFC3339)
+				delay = instance.ENIAttachmentTime.Sub(instance.LaunchTime).String()
+			}
+			registered, registrationDelay := "-", "-"
+			if !instance.ECSRegisteredAt.IsZero() {
+				registered = instance.ECSRegisteredAt.Local().Format(time.RFC3339)
+				registrationDelay = instance.RegistrationDelay().String()
+			}
+			fmt.Fprintf(writer, "\t%s\t%s\t%s\t%s\t%s", instance.LaunchTime.Local().Format(time.RFC3339), attached, delay, registered, registrationDelay)
+		}
+		if opts.ShowManagedDraining {
+			managedDraining := instance.ManagedDraining
+			if managedDraining == "" {
+				managedDraining = "-"
+			}
+			fmt.Fprintf(writer, "\t%s", managedDraining)
+		}
+		if opts.ShowAgentUsage {
+			fmt.Fprintf(writer, "\t%.1f\t%.0f", instance.AgentCPU, instance.AgentMemMB)
+		}
+		if opts.ShowCloudWatchAgent {
+			running := "stopped"
+			if instance.CloudWatchAgentRunning {
+				running = "running"
+			}
+			config := instance.CloudWatchAgentConfig
+			if config == "" {
+				config = "-"
+			}
+			fmt.Fprintf(writer, "\t%s\t%s", running, config)
+		}
+		if opts.ShowClusters {
+			fmt.Fprintf(writer, "\t%s", strings.Join(instance.Clusters, ","))
+		}
+		if opts.ShowStorageDriver {
+			fmt.Fprintf(writer, "\t%s\t%s", instance.StorageDriver, strings.Join(instance.StorageDriverOptions, ", "))
+		}
+		if opts.ShowStatusChecks {
+			system, check := instance.SystemStatusCheck, instance.InstanceStatusCheck
+			if system == "" {
+				system = "-"
+			}
+			if check == "" {
+				check = "-"
+			}
+			fmt.Fprintf(writer, "\t%s\t%s", system, check)
+		}
+		if opts.ShowZombies {
+			zombies := "-"
+			if instance.ZombiesChecked {
+				zombies = strconv.Itoa(instance.ZombieProcessCount)
+			}
+			fmt.Fprintf(writer, "\t%s", zombies)
+		}
+		if opts.ShowRegistration {
+			lastError := "-"
+			if count := len(instance.RegistrationErrors); count > 0 {
+				lastError = fmt.Sprintf("(%d) %s", count, instance.RegistrationErrors[count-1])
+			}
+			fmt.Fprintf(writer, "\t%s", lastError)
+		}
+		if opts.ShowCgroupVersion {
+			cgroup := "-"
+			if instance.CgroupVersion != 0 {
+				cgroup = fmt.Sprintf("v%d", instance.CgroupVersion)
+			}
+			fmt.Fprintf(writer, "\t%s", cgroup)
+		}
+		if opts.ShowTags {
+			fmt.Fprintf(writer, "\t%s", formatTags(instance.Tags))
+		}
+		for _, key := range opts.TagColumns {
+			value, ok := instance.Tags[key]
+			if !ok {
+				value = "-"
+			}
+			fmt.Fprintf(writer, "\t%s", value)
+		}
+		fmt.Fprintln(writer)
 	}
 	writer.Flush() // Ensure all buffered operations are applied to the writer
 }
+
+// formatTags renders tags as key=value pairs sorted by key.
+func formatTags(tags map[string]string) string {
+	var pairs []string
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,51 @@
+package aws
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+// fakeClusterPages answers ListClustersPages with one page per element of pages, or with err.
+type fakeClusterPages struct {
+	ecsiface.ECSAPI
+	pages [][]string
+	err   error
+}
+
+func (f *fakeClusterPages) ListClustersPages(input *ecs.ListClustersInput, fn func(*ecs.ListClustersOutput, bool) bool) error {
+	if f.err != nil {
+		return f.err
+	}
+	for i, page := range f.pages {
+		if !fn(&ecs.ListClustersOutput{ClusterArns: aws.StringSlice(page)}, i == len(f.pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestFetchECSClusterNames(t *testing.T) {
+	svc := &fakeClusterPages{pages: [][]string{
+		{"arn:aws:ecs:us-east-1:123456789012:cluster/staging", "arn:aws:ecs:us-east-1:123456789012:cluster/prod"},
+		{"arn:aws:ecs:us-east-1:123456789012:cluster/batch"},
+	}}
+	got, err := FetchECSClusterNames(svc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"batch", "prod", "staging"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFetchECSClusterNamesError(t *testing.T) {
+	_, err := FetchECSClusterNames(&fakeClusterPages{err: errors.New("AccessDenied")})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,192 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// CapacityProviderMetric compares a capacity provider's latest reservation with its managed scaling target.
+type CapacityProviderMetric struct {
+	CapacityProvider string
+	Reservation      float64 // percent, only meaningful when HasReservation is set
+	HasReservation   bool
+	TargetCapacity   int64 // percent, 0 when managed scaling is disabled
+	ManagedScaling   string
+	Timestamp        time.Time
+}
+
+// CapacityProviderDetail is the managed scaling configuration of a capacity provider attached to a cluster.
+type CapacityProviderDetail struct {
+	Name                         string
+	AutoScalingGroup             string // ASG name, empty for Fargate providers
+	ManagedScalingStatus         string // ENABLED, DISABLED or n/a for Fargate providers
+	TargetCapacity               int64  // percent, 0 when managed scaling is disabled
+	MinimumScalingStepSize       int64
+	MaximumScalingStepSize       int64
+	InstanceWarmupPeriod         int64 // seconds
+	ManagedTerminationProtection string
+}
+
+// FetchCapacityProviders returns the capacity providers attached to the cluster, with their managed scaling settings.
+func FetchCapacityProviders(clusterName, awsProfile string) ([]CapacityProviderDetail, error) {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	ecsSvc := ecs.New(sess)
+
+	clusters, err := ecsSvc.DescribeClusters(&ecs.DescribeClustersInput{
+		Clusters: []*string{aws.String(clusterName)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing cluster %s: %v", clusterName, err)
+	}
+	if len(clusters.Clusters) == 0 {
+		return nil, fmt.Errorf("cluster %s not found", clusterName)
+	}
+	providers := clusters.Clusters[0].CapacityProviders
+	if len(providers) == 0 {
+		return nil, nil
+	}
+
+	described, err := ecsSvc.DescribeCapacityProviders(&ecs.DescribeCapacityProvidersInput{
+		CapacityProviders: providers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing capacity providers: %v", err)
+	}
+
+	var details []CapacityProviderDetail
+	for _, provider := range described.CapacityProviders {
+		detail := CapacityProviderDetail{
+			Name:                 aws.StringValue(provider.Name),
+			ManagedScalingStatus: "n/a", // Fargate providers have no managed scaling
+		}
+		if asg := provider.AutoScalingGroupProvider; asg != nil {
+			_, detail.AutoScalingGroup, _ = strings.Cut(aws.StringValue(asg.AutoScalingGroupArn), "autoScalingGroupName/")
+			detail.ManagedTerminationProtection = aws.StringValue(asg.ManagedTerminationProtection)
+			if scaling := asg.ManagedScaling; scaling != nil {
+				detail.ManagedScalingStatus = aws.StringValue(scaling.Status)
+				detail.TargetCapacity = aws.Int64Value(scaling.TargetCapacity)
+				detail.MinimumScalingStepSize = aws.Int64Value(scaling.MinimumScalingStepSize)
+				detail.MaximumScalingStepSize = aws.Int64Value(scaling.MaximumScalingStepSize)
+				detail.InstanceWarmupPeriod = aws.Int64Value(scaling.InstanceWarmupPeriod)
+			}
+		}
+		details = append(details, detail)
+	}
+
+	return details, nil
+}
+
+// FetchCapacityProviderMetrics returns the latest CapacityProviderReservation datapoint and
+// the managed scaling target of each capacity provider attached to the cluster.
+func FetchCapacityProviderMetrics(clusterName, awsProfile string) ([]CapacityProviderMetric, error) {
+	providers, err := FetchCapacityProviders(clusterName, awsProfile)
+	if err != nil {
+		return nil, err
+	}
+	if len(providers) == 0 {
+		return nil, nil
+	}
+
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	cwSvc := cloudwatch.New(sess)
+
+	var metrics []CapacityProviderMetric
+	for _, provider := range providers {
+		metric := CapacityProviderMetric{
+			CapacityProvider: provider.Name,
+			ManagedScaling:   provider.ManagedScalingStatus,
+			TargetCapacity:   provider.TargetCapacity,
+		}
+
+		// ECS publishes the metric every minute; look back a little further to find the latest point.
+		stats, err := cwSvc.GetMetricStatistics(&cloudwatch.GetMetricStatisticsInput{
+			Namespace:  aws.String("AWS/ECS/ManagedScaling"),
+			MetricName: aws.String("CapacityProviderReservation"),
+			Dimensions: []*cloudwatch.Dimension{
+				{Name: aws.String("ClusterName"), Value: aws.String(clusterName)},
+				{Name: aws.String("CapacityProviderName"), Value: aws.String(provider.Name)},
+			},
+			StartTime:  aws.Time(time.Now().Add(-15 * time.Minute)),
+			EndTime:    aws.Time(time.Now()),
+			Period:     aws.Int64(60),
+			Statistics: []*string{aws.String(cloudwatch.StatisticAverage)},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error fetching reservation for %s: %v", metric.CapacityProvider, err)
+		}
+		for _, point := range stats.Datapoints {
+			if timestamp := aws.TimeValue(point.Timestamp); timestamp.After(metric.Timestamp) {
+				metric.Timestamp = timestamp
+				metric.Reservation = aws.Float64Value(point.Average)
+				metric.HasReservation = true
+			}
+		}
+		metrics = append(metrics, metric)
+	}
+
+	return metrics, nil
+}
+
+// DisplayCapacityProviderMetrics prints capacity provider reservations in a table format.
+func DisplayCapacityProviderMetrics(metrics []CapacityProviderMetric) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.Debug)
+	fmt.Fprintln(writer, "Capacity Provider\tManaged Scaling\tReservation\tTarget\tAs Of")
+	for _, metric := range metrics {
+		reservation, asOf := "-", "-"
+		if metric.HasReservation {
+			reservation = fmt.Sprintf("%.1f%%", metric.Reservation)
+			asOf = metric.Timestamp.Local().Format(time.RFC3339)
+		}
+		target := "-"
+		if metric.TargetCapacity > 0 {
+			target = fmt.Sprintf("%d%%", metric.TargetCapacity)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n",
+			metric.CapacityProvider,
+			metric.ManagedScaling,
+			reservation,
+			target,
+			asOf)
+	}
+	writer.Flush()
+}
+
+// DisplayCapacityProviders prints capacity providers' managed scaling settings in a table format.
+func DisplayCapacityProviders(providers []CapacityProviderDetail) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.Debug)
+	fmt.Fprintln(writer, "Capacity Provider\tAuto Scaling Group\tManaged Scaling\tTarget\tStep Size\tWarmup\tTermination Protection")
+	for _, provider := range providers {
+		group, protection := provider.AutoScalingGroup, provider.ManagedTerminationProtection
+		if group == "" {
+			group, protection = "-", "-"
+		}
+		target, steps, warmup := "-", "-", "-"
+		if provider.ManagedScalingStatus == ecs.ManagedScalingStatusEnabled {
+			target = fmt.Sprintf("%d%%", provider.TargetCapacity)
+			steps = fmt.Sprintf("%d-%d", provider.MinimumScalingStepSize, provider.MaximumScalingStepSize)
+			warmup = fmt.Sprintf("%ds", provider.InstanceWarmupPeriod)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			provider.Name,
+			group,
+			provider.ManagedScalingStatus,
+			target,
+			steps,
+			warmup,
+			protection)
+	}
+	writer.Flush()
+}
@@ -0,0 +1,48 @@
+package aws
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"enum/ssh"
+)
+
+// cgroupCommand prints the filesystem type of the cgroup mount: cgroup2fs on a unified
+// (v2) hierarchy, tmpfs on the legacy v1 layout.
+const cgroupCommand = `stat -fc %T /sys/fs/cgroup/`
+
+// PopulateCgroupVersion sets CgroupVersion on each instance over SSH. Like
+// PopulateStorageDriver it is left to callers, as it contacts every host. Hosts that
+// can't be read are logged and left at 0.
+func PopulateCgroupVersion(instances []InstanceData, opts ssh.SSHOptions) {
+	opts.Scheduler.Run(len(instances), func(i int) {
+		instance := &instances[i]
+		if instance.PrivateIP == "" {
+			return
+		}
+		output, err := ssh.SSHCommand(instance.PrivateIP, cgroupCommand, opts.Verbose)
+		if err != nil {
+			log.Printf("Error reading cgroup version on instance %s: %v", instance.Name, err)
+			return
+		}
+		version, err := parseCgroupVersion(output)
+		if err != nil {
+			log.Printf("Error reading cgroup version on instance %s: %v", instance.Name, err)
+			return
+		}
+		instance.CgroupVersion = version
+	})
+}
+
+// parseCgroupVersion maps the output of cgroupCommand to 1 or 2.
+func parseCgroupVersion(output string) (int, error) {
+	switch fsType := strings.TrimSpace(output); fsType {
+	case "cgroup2fs":
+		return 2, nil
+	case "tmpfs":
+		return 1, nil
+	default:
+		return 0, fmt.Errorf("unexpected cgroup filesystem %q", fsType)
+	}
+}
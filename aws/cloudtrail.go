@@ -0,0 +1,86 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+)
+
+// CloudTrailEvent is an API call recorded by CloudTrail.
+type CloudTrailEvent struct {
+	EventID     string
+	EventName   string
+	EventSource string
+	EventTime   time.Time
+	Username    string
+}
+
+// FetchRecentAPIEvents returns the CloudTrail events of the last hours that reference an EC2 instance, newest first.
+func FetchRecentAPIEvents(instanceID, region, awsProfile string, hours int) ([]CloudTrailEvent, error) {
+	sess, err := newSession(awsProfile, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := cloudtrail.New(sess)
+
+	// LookupEvents accepts a single lookup attribute, so filter on the resource
+	// name and check the resource type on each event.
+	input := &cloudtrail.LookupEventsInput{
+		LookupAttributes: []*cloudtrail.LookupAttribute{{
+			AttributeKey:   aws.String(cloudtrail.LookupAttributeKeyResourceName),
+			AttributeValue: aws.String(instanceID),
+		}},
+		StartTime: aws.Time(time.Now().Add(-time.Duration(hours) * time.Hour)),
+		EndTime:   aws.Time(time.Now()),
+	}
+
+	var events []CloudTrailEvent
+	err = svc.LookupEventsPages(input, func(page *cloudtrail.LookupEventsOutput, lastPage bool) bool {
+		for _, event := range page.Events {
+			if !referencesInstance(event, instanceID) {
+				continue
+			}
+			events = append(events, CloudTrailEvent{
+				EventID:     aws.StringValue(event.EventId),
+				EventName:   aws.StringValue(event.EventName),
+				EventSource: aws.StringValue(event.EventSource),
+				EventTime:   aws.TimeValue(event.EventTime),
+				Username:    aws.StringValue(event.Username),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error looking up CloudTrail events for %s: %v", instanceID, err)
+	}
+
+	return events, nil
+}
+
+// referencesInstance reports whether event lists instanceID as an AWS::EC2::Instance resource.
+func referencesInstance(event *cloudtrail.Event, instanceID string) bool {
+	for _, resource := range event.Resources {
+		if aws.StringValue(resource.ResourceType) == "AWS::EC2::Instance" && aws.StringValue(resource.ResourceName) == instanceID {
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayCloudTrailEvents prints CloudTrail events in a table format.
+func DisplayCloudTrailEvents(events []CloudTrailEvent) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.Debug)
+	fmt.Fprintln(writer, "Time\tEvent\tSource\tUser")
+	for _, event := range events {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n",
+			event.EventTime.Local().Format(time.RFC3339),
+			event.EventName,
+			event.EventSource,
+			event.Username)
+	}
+	writer.Flush()
+}
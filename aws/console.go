@@ -0,0 +1,55 @@
+package aws
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// FetchConsoleHostKeys returns the SSH host public keys cloud-init printed to the
+// instance console at boot, in authorized_keys format. It returns no keys when the
+// console output has rotated past them or cloud-init didn't print any.
+func FetchConsoleHostKeys(instanceID, awsProfile string) ([]string, error) {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	resp, err := ec2.New(sess).GetConsoleOutput(&ec2.GetConsoleOutputInput{
+		InstanceId: aws.String(instanceID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching console output for %s: %v", instanceID, err)
+	}
+	output, err := base64.StdEncoding.DecodeString(aws.StringValue(resp.Output))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding console output for %s: %v", instanceID, err)
+	}
+	return parseConsoleHostKeys(string(output)), nil
+}
+
+// parseConsoleHostKeys extracts the keys between cloud-init's BEGIN/END SSH HOST KEY KEYS markers.
+func parseConsoleHostKeys(output string) []string {
+	var keys []string
+	inBlock := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		switch {
+		case strings.Contains(line, "-----BEGIN SSH HOST KEY KEYS-----"):
+			inBlock = true
+		case strings.Contains(line, "-----END SSH HOST KEY KEYS-----"):
+			inBlock = false
+		case inBlock && line != "":
+			// Console lines can carry a kernel timestamp prefix; keep from the key type on.
+			if i := strings.Index(line, "ssh-"); i != -1 {
+				line = line[i:]
+			} else if i := strings.Index(line, "ecdsa-"); i != -1 {
+				line = line[i:]
+			}
+			keys = append(keys, line)
+		}
+	}
+	return keys
+}
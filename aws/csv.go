@@ -0,0 +1,62 @@
+package aws
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+// WriteCSV writes a header row naming every InstanceData field followed by one row per instance.
+// Map and slice fields are encoded as JSON and times as RFC 3339.
+func WriteCSV(instances []InstanceData, w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	fields := reflect.VisibleFields(reflect.TypeOf(InstanceData{}))
+	var header []string
+	for _, field := range fields {
+		header = append(header, field.Name)
+	}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %v", err)
+	}
+
+	for _, instance := range instances {
+		value := reflect.ValueOf(instance)
+		var row []string
+		for _, field := range fields {
+			row = append(row, csvValue(value.FieldByIndex(field.Index)))
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %v", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvValue formats a single field for a CSV cell. Times are RFC 3339, empty when unset.
+func csvValue(v reflect.Value) string {
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return ""
+		}
+		encoded, err := json.Marshal(v.Interface())
+		if err != nil {
+			return ""
+		}
+		return string(encoded)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,56 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"enum/ssh"
+)
+
+// cwAgentCtl is the control script the CloudWatch agent package installs.
+const cwAgentCtl = "/opt/aws/amazon-cloudwatch-agent/bin/amazon-cloudwatch-agent-ctl"
+
+// cwAgentCommand prints the systemd state of the CloudWatch agent, a separator line and
+// the agent's own status JSON. Neither part fails the command when the agent is missing.
+const cwAgentCommand = "systemctl is-active amazon-cloudwatch-agent 2>/dev/null; echo ---; " +
+	"sudo -n " + cwAgentCtl + " -m ec2 -a status 2>/dev/null || true"
+
+// PopulateCloudWatchAgentStatus sets CloudWatchAgentRunning and CloudWatchAgentConfig on each
+// instance over SSH. Like PopulateAgentUsage it is left to callers, as it contacts every host.
+// Hosts that can't be read are logged and left unset.
+func PopulateCloudWatchAgentStatus(instances []InstanceData, opts ssh.SSHOptions) {
+	opts.Scheduler.Run(len(instances), func(i int) {
+		instance := &instances[i]
+		if instance.PrivateIP == "" {
+			return
+		}
+		output, err := ssh.SSHCommand(instance.PrivateIP, cwAgentCommand, opts.Verbose)
+		if err != nil {
+			log.Printf("Error reading CloudWatch agent status on instance %s: %v", instance.Name, err)
+			return
+		}
+		instance.CloudWatchAgentRunning, instance.CloudWatchAgentConfig = parseCloudWatchAgentStatus(output)
+	})
+}
+
+// parseCloudWatchAgentStatus parses the output of cwAgentCommand. The config status is
+// "not installed" when the control script printed nothing.
+func parseCloudWatchAgentStatus(output string) (running bool, config string) {
+	active, status, _ := strings.Cut(output, "---")
+	running = strings.TrimSpace(active) == "active"
+
+	status = strings.TrimSpace(status)
+	if status == "" {
+		return running, "not installed"
+	}
+	var ctl struct {
+		Status       string `json:"status"`
+		ConfigStatus string `json:"configstatus"`
+	}
+	if err := json.Unmarshal([]byte(status), &ctl); err != nil {
+		return running, fmt.Sprintf("unknown (%s)", strings.SplitN(status, "\n", 2)[0])
+	}
+	return running || ctl.Status == "running", ctl.ConfigStatus
+}
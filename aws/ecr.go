@@ -0,0 +1,111 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecr"
+)
+
+// ECRImage identifies an image reference hosted in an ECR registry.
+type ECRImage struct {
+	RegistryID string
+	Region     string
+	Repository string
+	Tag        string // Empty when the reference is only a digest
+	Digest     string // The sha256:... digest the reference is pinned to, if any
+}
+
+// ECRImageDetail describes a single image manifest stored in ECR.
+type ECRImageDetail struct {
+	Digest   string
+	PushedAt time.Time
+}
+
+var ecrImagePattern = regexp.MustCompile(`^(\d{12})\.dkr\.ecr\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?/([^:]+)(?::(.+))?$`)
+
+// ParseECRImage parses a docker image reference such as repo:tag, repo@sha256:... or
+// repo:tag@sha256:..., reporting false for images not hosted in ECR. A reference with
+// neither tag nor digest means the latest tag.
+func ParseECRImage(image string) (ECRImage, bool) {
+	// The digest holds a colon of its own, so it comes off before the tag is looked for.
+	ref, digest, _ := strings.Cut(image, "@")
+	match := ecrImagePattern.FindStringSubmatch(ref)
+	if match == nil {
+		return ECRImage{}, false
+	}
+
+	tag := match[4]
+	if tag == "" && digest == "" {
+		tag = "latest"
+	}
+	return ECRImage{
+		RegistryID: match[1],
+		Region:     match[2],
+		Repository: match[3],
+		Tag:        tag,
+		Digest:     digest,
+	}, true
+}
+
+// String returns the image reference in the form it was parsed from.
+func (i ECRImage) String() string {
+	domain := "amazonaws.com"
+	if strings.HasPrefix(i.Region, "cn-") {
+		domain += ".cn"
+	}
+	s := fmt.Sprintf("%s.dkr.ecr.%s.%s/%s", i.RegistryID, i.Region, domain, i.Repository)
+	if i.Tag != "" {
+		s += ":" + i.Tag
+	}
+	if i.Digest != "" {
+		s += "@" + i.Digest
+	}
+	return s
+}
+
+// FetchECRImageDetail looks up an image in ECR by digest, or by the image's tag when digest is empty.
+func FetchECRImageDetail(image ECRImage, digest string, awsProfile string) (ECRImageDetail, error) {
+	if digest == "" && image.Tag == "" {
+		return ECRImageDetail{}, fmt.Errorf("image %s has no tag to look up", image)
+	}
+	sess, err := newSession(awsProfile, image.Region)
+	if err != nil {
+		return ECRImageDetail{}, fmt.Errorf("failed to create session: %v", err)
+	}
+
+	imageID := &ecr.ImageIdentifier{ImageTag: aws.String(image.Tag)}
+	if digest != "" {
+		imageID = &ecr.ImageIdentifier{ImageDigest: aws.String(digest)}
+	}
+
+	svc := ecr.New(sess)
+	result, err := svc.DescribeImages(&ecr.DescribeImagesInput{
+		RegistryId:     aws.String(image.RegistryID),
+		RepositoryName: aws.String(image.Repository),
+		ImageIds:       []*ecr.ImageIdentifier{imageID},
+	})
+	if err != nil {
+		return ECRImageDetail{}, fmt.Errorf("failed to describe image %s: %v", image, err)
+	}
+	if len(result.ImageDetails) == 0 {
+		return ECRImageDetail{}, fmt.Errorf("image %s not found", image)
+	}
+
+	detail := result.ImageDetails[0]
+	return ECRImageDetail{
+		Digest:   aws.StringValue(detail.ImageDigest),
+		PushedAt: aws.TimeValue(detail.ImagePushedAt),
+	}, nil
+}
+
+// RepoDigest extracts the sha256 digest from a docker RepoDigests entry such as repo@sha256:abc.
+func RepoDigest(repoDigest string) string {
+	if i := strings.LastIndex(repoDigest, "@"); i != -1 {
+		return repoDigest[i+1:]
+	}
+	return ""
+}
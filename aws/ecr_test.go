@@ -0,0 +1,48 @@
+package aws
+
+import "testing"
+
+func TestParseECRImage(t *testing.T) {
+	const (
+		repo   = "123456789012.dkr.ecr.us-west-2.amazonaws.com/team/web"
+		digest = "sha256:4f1c2d8e9a0b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d"
+	)
+	tests := []struct {
+		in     string
+		want   ECRImage
+		wantOK bool
+	}{
+		{in: repo + ":v1.2", want: ECRImage{RegistryID: "123456789012", Region: "us-west-2", Repository: "team/web", Tag: "v1.2"}, wantOK: true},
+		{in: repo, want: ECRImage{RegistryID: "123456789012", Region: "us-west-2", Repository: "team/web", Tag: "latest"}, wantOK: true},
+		{in: repo + "@" + digest, want: ECRImage{RegistryID: "123456789012", Region: "us-west-2", Repository: "team/web", Digest: digest}, wantOK: true},
+		{in: repo + ":v1.2@" + digest, want: ECRImage{RegistryID: "123456789012", Region: "us-west-2", Repository: "team/web", Tag: "v1.2", Digest: digest}, wantOK: true},
+		{in: "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn/web:prod", want: ECRImage{RegistryID: "123456789012", Region: "cn-north-1", Repository: "web", Tag: "prod"}, wantOK: true},
+		{in: "nginx:1.25"},
+		{in: "docker.io/library/nginx@" + digest},
+		{in: "public.ecr.aws/nginx/nginx:latest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := ParseECRImage(tt.in)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("ParseECRImage(%q) = %+v, %v; want %+v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+			}
+			if ok && tt.want.Tag != "latest" && got.String() != tt.in {
+				t.Errorf("String() = %q, want %q", got.String(), tt.in)
+			}
+		})
+	}
+}
+
+func TestRepoDigest(t *testing.T) {
+	tests := map[string]string{
+		"123456789012.dkr.ecr.us-west-2.amazonaws.com/web@sha256:abc": "sha256:abc",
+		"nginx@sha256:def": "sha256:def",
+		"nginx":            "",
+	}
+	for in, want := range tests {
+		if got := RepoDigest(in); got != want {
+			t.Errorf("RepoDigest(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+// ELBHealthStatus is the health of an instance in one target group, for one port.
+type ELBHealthStatus struct {
+	TargetGroupARN  string
+	TargetGroupName string
+	Port            int64
+	State           string // healthy, unhealthy, draining, initial, unused or unavailable
+	Reason          string
+	Description     string
+}
+
+// FetchELBHealthStatus returns the health of the instance in every instance-type target group
+// it is registered with. An empty region uses the default region.
+func FetchELBHealthStatus(instanceID, region, awsProfile string) ([]ELBHealthStatus, error) {
+	sess, err := newSession(awsProfile, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := elbv2.New(sess)
+
+	var targetGroups []*elbv2.TargetGroup
+	err = svc.DescribeTargetGroupsPages(&elbv2.DescribeTargetGroupsInput{}, func(page *elbv2.DescribeTargetGroupsOutput, lastPage bool) bool {
+		for _, group := range page.TargetGroups {
+			// ip target groups hold awsvpc task ENIs rather than instances.
+			if aws.StringValue(group.TargetType) == elbv2.TargetTypeEnumInstance {
+				targetGroups = append(targetGroups, group)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing target groups: %v", err)
+	}
+
+	var statuses []ELBHealthStatus
+	for _, group := range targetGroups {
+		resp, err := svc.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{
+			TargetGroupArn: group.TargetGroupArn,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error describing target health of %s: %v", aws.StringValue(group.TargetGroupName), err)
+		}
+		for _, description := range resp.TargetHealthDescriptions {
+			if aws.StringValue(description.Target.Id) != instanceID {
+				continue
+			}
+			status := ELBHealthStatus{
+				TargetGroupARN:  aws.StringValue(group.TargetGroupArn),
+				TargetGroupName: aws.StringValue(group.TargetGroupName),
+				Port:            aws.Int64Value(description.Target.Port),
+			}
+			if health := description.TargetHealth; health != nil {
+				status.State = aws.StringValue(health.State)
+				status.Reason = aws.StringValue(health.Reason)
+				status.Description = aws.StringValue(health.Description)
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+// DisplayELBHealthStatus prints target health in a table format.
+func DisplayELBHealthStatus(statuses []ELBHealthStatus) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "Target Group\tPort\tState\tReason\tTarget Group ARN")
+	for _, status := range statuses {
+		reason := status.Reason
+		if status.Description != "" {
+			reason += ": " + status.Description
+		}
+		if reason == "" {
+			reason = "-"
+		}
+		fmt.Fprintf(writer, "%s\t%d\t%s\t%s\t%s\n", status.TargetGroupName, status.Port, status.State, reason, status.TargetGroupARN)
+	}
+	writer.Flush()
+}
@@ -0,0 +1,116 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// Kinds of EnvVarDiff.
+const (
+	EnvVarAdded   = "added"
+	EnvVarRemoved = "removed"
+	EnvVarChanged = "changed"
+)
+
+// EnvVarDiff is an environment variable of a container that differs between two
+// revisions of a task definition.
+type EnvVarDiff struct {
+	Container string `json:"container"`
+	Name      string `json:"name"`
+	Change    string `json:"change"` // EnvVarAdded, EnvVarRemoved or EnvVarChanged
+	Old       string `json:"old,omitempty"`
+	New       string `json:"new,omitempty"`
+}
+
+// DiffTaskDefEnvVars compares the environment of each container between revisions rev1
+// and rev2 of family. A container present in only one revision has all its variables
+// reported as added or removed. Results are sorted by container, then variable.
+func DiffTaskDefEnvVars(family string, rev1, rev2 int, awsProfile string) ([]EnvVarDiff, error) {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ecs.New(sess)
+
+	before, err := taskDefinitionEnv(svc, family, rev1)
+	if err != nil {
+		return nil, err
+	}
+	after, err := taskDefinitionEnv(svc, family, rev2)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []EnvVarDiff
+	for container, oldEnv := range before {
+		newEnv := after[container]
+		for name, value := range oldEnv {
+			newValue, ok := newEnv[name]
+			switch {
+			case !ok:
+				diffs = append(diffs, EnvVarDiff{Container: container, Name: name, Change: EnvVarRemoved, Old: value})
+			case newValue != value:
+				diffs = append(diffs, EnvVarDiff{Container: container, Name: name, Change: EnvVarChanged, Old: value, New: newValue})
+			}
+		}
+	}
+	for container, newEnv := range after {
+		oldEnv := before[container]
+		for name, value := range newEnv {
+			if _, ok := oldEnv[name]; !ok {
+				diffs = append(diffs, EnvVarDiff{Container: container, Name: name, Change: EnvVarAdded, New: value})
+			}
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Container != diffs[j].Container {
+			return diffs[i].Container < diffs[j].Container
+		}
+		return diffs[i].Name < diffs[j].Name
+	})
+	return diffs, nil
+}
+
+// taskDefinitionEnv maps each container of family:revision to its environment variables.
+func taskDefinitionEnv(svc *ecs.ECS, family string, revision int) (map[string]map[string]string, error) {
+	definition := fmt.Sprintf("%s:%d", family, revision)
+	resp, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(definition),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing task definition %s: %v", definition, err)
+	}
+
+	containers := make(map[string]map[string]string)
+	for _, container := range resp.TaskDefinition.ContainerDefinitions {
+		env := make(map[string]string)
+		for _, pair := range container.Environment {
+			env[aws.StringValue(pair.Name)] = aws.StringValue(pair.Value)
+		}
+		containers[aws.StringValue(container.Name)] = env
+	}
+	return containers, nil
+}
+
+// DisplayEnvVarDiffs prints environment variable differences in a table format.
+func DisplayEnvVarDiffs(diffs []EnvVarDiff) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "Container\tVariable\tChange\tOld\tNew")
+	for _, diff := range diffs {
+		oldValue, newValue := diff.Old, diff.New
+		if diff.Change == EnvVarAdded {
+			oldValue = "-"
+		}
+		if diff.Change == EnvVarRemoved {
+			newValue = "-"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", diff.Container, diff.Name, diff.Change, oldValue, newValue)
+	}
+	writer.Flush()
+}
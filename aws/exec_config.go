@@ -0,0 +1,84 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// FetchECSExecConfiguration returns the cluster's ECS Exec configuration, or nil when the
+// cluster has none, in which case ECS Exec uses the DEFAULT logging behaviour.
+func FetchECSExecConfiguration(clusterName, awsProfile string) (*ecs.ExecuteCommandConfiguration, error) {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ecs.New(sess)
+
+	resp, err := svc.DescribeClusters(&ecs.DescribeClustersInput{
+		Clusters: []*string{aws.String(clusterName)},
+		Include:  []*string{aws.String(ecs.ClusterFieldConfigurations)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing cluster %s: %v", clusterName, err)
+	}
+	if len(resp.Clusters) == 0 {
+		return nil, fmt.Errorf("cluster %s not found", clusterName)
+	}
+	if resp.Clusters[0].Configuration == nil {
+		return nil, nil
+	}
+	return resp.Clusters[0].Configuration.ExecuteCommandConfiguration, nil
+}
+
+// DisplayECSExecConfiguration prints whether ECS Exec sessions are logged and where to.
+func DisplayECSExecConfiguration(config *ecs.ExecuteCommandConfiguration) {
+	logging := ecs.ExecuteCommandLoggingDefault
+	var logConfig ecs.ExecuteCommandLogConfiguration
+	kmsKey := "-"
+	if config != nil {
+		if aws.StringValue(config.Logging) != "" {
+			logging = aws.StringValue(config.Logging)
+		}
+		if config.LogConfiguration != nil {
+			logConfig = *config.LogConfiguration
+		}
+		if aws.StringValue(config.KmsKeyId) != "" {
+			kmsKey = aws.StringValue(config.KmsKeyId)
+		}
+	}
+
+	enabled := "yes"
+	switch logging {
+	case ecs.ExecuteCommandLoggingNone:
+		enabled = "no"
+	case ecs.ExecuteCommandLoggingDefault:
+		enabled = "yes, to the awslogs configuration of each task definition"
+	}
+
+	valueOr := func(s *string) string {
+		if aws.StringValue(s) == "" {
+			return "-"
+		}
+		return aws.StringValue(s)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "Logging\t%s\n", logging)
+	fmt.Fprintf(writer, "Session logging enabled\t%s\n", enabled)
+	fmt.Fprintf(writer, "Session encryption KMS key\t%s\n", kmsKey)
+	if logging == ecs.ExecuteCommandLoggingOverride {
+		fmt.Fprintf(writer, "CloudWatch log group\t%s\n", valueOr(logConfig.CloudWatchLogGroupName))
+		fmt.Fprintf(writer, "CloudWatch encryption\t%t\n", aws.BoolValue(logConfig.CloudWatchEncryptionEnabled))
+		s3 := valueOr(logConfig.S3BucketName)
+		if prefix := aws.StringValue(logConfig.S3KeyPrefix); prefix != "" && s3 != "-" {
+			s3 = "s3://" + s3 + "/" + prefix
+		}
+		fmt.Fprintf(writer, "S3 bucket\t%s\n", s3)
+		fmt.Fprintf(writer, "S3 encryption\t%t\n", aws.BoolValue(logConfig.S3EncryptionEnabled))
+	}
+	writer.Flush()
+}
@@ -0,0 +1,46 @@
+package aws
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GenerateHostsFile writes an /etc/hosts fragment mapping each instance's private IP
+// to its Name. Instances sharing a Name get their instance ID appended, as in GenerateSSHConfig.
+func GenerateHostsFile(instances []InstanceData, w io.Writer) error {
+	names := make(map[string]int)
+	clusterSet := make(map[string]bool)
+	for _, instance := range instances {
+		names[instance.Name]++
+		clusterSet[instance.Cluster] = true
+	}
+	var clusters []string
+	for cluster := range clusterSet {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+
+	if _, err := fmt.Fprintf(w, "# ECS cluster %s, generated by enum at %s\n",
+		strings.Join(clusters, ", "), time.Now().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("failed to write hosts file: %v", err)
+	}
+
+	for _, instance := range instances {
+		if instance.PrivateIP == "" {
+			continue
+		}
+
+		name := strings.Join(strings.Fields(instance.Name), "-")
+		if names[instance.Name] > 1 {
+			name += "-" + instance.InstanceID
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", instance.PrivateIP, name); err != nil {
+			return fmt.Errorf("failed to write hosts file: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package aws
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// CallerIdentity is the AWS account and principal the current credentials resolve to.
+type CallerIdentity struct {
+	Account string
+	Arn     string
+}
+
+var (
+	identityMu    sync.Mutex
+	identityCache = make(map[string]CallerIdentity)
+)
+
+// FetchCallerIdentity calls sts.GetCallerIdentity for the profile. The result is
+// cached for the life of the process.
+func FetchCallerIdentity(awsProfile string) (CallerIdentity, error) {
+	identityMu.Lock()
+	defer identityMu.Unlock()
+	if identity, ok := identityCache[awsProfile]; ok {
+		return identity, nil
+	}
+
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return CallerIdentity{}, fmt.Errorf("failed to create session: %v", err)
+	}
+	output, err := sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return CallerIdentity{}, fmt.Errorf("error checking AWS identity: %v", err)
+	}
+
+	identity := CallerIdentity{
+		Account: aws.StringValue(output.Account),
+		Arn:     aws.StringValue(output.Arn),
+	}
+	identityCache[awsProfile] = identity
+	return identity, nil
+}
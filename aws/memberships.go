@@ -0,0 +1,61 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// PopulateClusterMemberships sets Clusters on each instance to every ECS cluster in the
+// account that has it registered as a container instance. An instance normally belongs to
+// one cluster; more than one points at a registration bug. It lists the container
+// instances of every cluster, so it is left to callers that need it.
+func PopulateClusterMemberships(instances []InstanceData, awsProfile string) error {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ecs.New(sess)
+	clusterNames, err := FetchECSClusterNames(svc)
+	if err != nil {
+		return err
+	}
+
+	memberships := make(map[string][]string) // EC2 instance ID -> cluster names
+	for _, clusterName := range clusterNames {
+		var arns []*string
+		err = svc.ListContainerInstancesPages(&ecs.ListContainerInstancesInput{
+			Cluster: aws.String(clusterName),
+		}, func(page *ecs.ListContainerInstancesOutput, lastPage bool) bool {
+			arns = append(arns, page.ContainerInstanceArns...)
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("error listing container instances for cluster %s: %v", clusterName, err)
+		}
+
+		// DescribeContainerInstances accepts at most 100 container instances per call.
+		for start := 0; start < len(arns); start += 100 {
+			resp, err := svc.DescribeContainerInstances(&ecs.DescribeContainerInstancesInput{
+				Cluster:            aws.String(clusterName),
+				ContainerInstances: arns[start:min(start+100, len(arns))],
+			})
+			if err != nil {
+				return fmt.Errorf("error describing container instances for cluster %s: %v", clusterName, err)
+			}
+			for _, containerInstance := range resp.ContainerInstances {
+				id := aws.StringValue(containerInstance.Ec2InstanceId)
+				memberships[id] = append(memberships[id], clusterName)
+			}
+		}
+	}
+
+	for i := range instances {
+		clusters := memberships[instances[i].InstanceID]
+		sort.Strings(clusters)
+		instances[i].Clusters = clusters
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MemorySummary totals the memory ECS has registered and reserved across a cluster's container instances.
+type MemorySummary struct {
+	Instances    int // Container instances that registered memory with ECS
+	TotalMiB     int
+	AllocatedMiB int
+}
+
+// FreeMiB is the registered memory not yet reserved by tasks.
+func (s MemorySummary) FreeMiB() int {
+	return s.TotalMiB - s.AllocatedMiB
+}
+
+// Ratio is the allocated memory as a percentage of the registered memory.
+func (s MemorySummary) Ratio() float64 {
+	if s.TotalMiB == 0 {
+		return 0
+	}
+	return float64(s.AllocatedMiB) / float64(s.TotalMiB) * 100
+}
+
+// SummarizeMemory sums the registered and reserved memory of every container instance.
+func SummarizeMemory(instances []InstanceData) MemorySummary {
+	var summary MemorySummary
+	for _, instance := range instances {
+		if instance.MemoryMiB == 0 {
+			continue
+		}
+		summary.Instances++
+		summary.TotalMiB += instance.MemoryMiB
+		summary.AllocatedMiB += instance.MemoryReserved
+	}
+	return summary
+}
+
+// DisplayMemorySummary prints the cluster's memory totals with a progress bar of the allocated share.
+func DisplayMemorySummary(summary MemorySummary) {
+	const width = 40
+	filled := min(width, int(summary.Ratio()/100*width+0.5))
+
+	fmt.Printf("Container instances: %d\n", summary.Instances)
+	fmt.Printf("Total memory:        %d MiB\n", summary.TotalMiB)
+	fmt.Printf("Allocated:           %d MiB\n", summary.AllocatedMiB)
+	fmt.Printf("Free:                %d MiB\n", summary.FreeMiB())
+	fmt.Printf("Overcommit ratio:    [%s%s] %.1f%%\n", strings.Repeat("#", filled), strings.Repeat(".", width-filled), summary.Ratio())
+}
@@ -0,0 +1,71 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// NATGatewayInfo is a NAT gateway in a VPC.
+type NATGatewayInfo struct {
+	ID               string
+	State            string
+	ConnectivityType string   // "public" or "private"
+	ElasticIPs       []string // Public IPs of the gateway's addresses
+	SubnetID         string
+}
+
+// FetchNATGateways returns the NAT gateways of a VPC. An empty region uses the default region.
+func FetchNATGateways(vpcID, region, awsProfile string) ([]NATGatewayInfo, error) {
+	sess, err := newSession(awsProfile, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ec2.New(sess)
+
+	var gateways []NATGatewayInfo
+	err = svc.DescribeNatGatewaysPages(&ec2.DescribeNatGatewaysInput{
+		Filter: []*ec2.Filter{{
+			Name:   aws.String("vpc-id"),
+			Values: []*string{aws.String(vpcID)},
+		}},
+	}, func(page *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+		for _, gateway := range page.NatGateways {
+			info := NATGatewayInfo{
+				ID:               aws.StringValue(gateway.NatGatewayId),
+				State:            aws.StringValue(gateway.State),
+				ConnectivityType: aws.StringValue(gateway.ConnectivityType),
+				SubnetID:         aws.StringValue(gateway.SubnetId),
+			}
+			for _, address := range gateway.NatGatewayAddresses {
+				if ip := aws.StringValue(address.PublicIp); ip != "" {
+					info.ElasticIPs = append(info.ElasticIPs, ip)
+				}
+			}
+			gateways = append(gateways, info)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing NAT gateways for VPC %s: %v", vpcID, err)
+	}
+	return gateways, nil
+}
+
+// DisplayNATGateways prints NAT gateways in a table format.
+func DisplayNATGateways(gateways []NATGatewayInfo) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAT Gateway ID\tState\tType\tElastic IP\tSubnet")
+	for _, gateway := range gateways {
+		ips := strings.Join(gateway.ElasticIPs, ", ")
+		if ips == "" {
+			ips = "-"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", gateway.ID, gateway.State, gateway.ConnectivityType, ips, gateway.SubnetID)
+	}
+	writer.Flush()
+}
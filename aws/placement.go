@@ -0,0 +1,112 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// PlacementFailure is a distinct reason ECS gave for being unable to place a service's tasks.
+type PlacementFailure struct {
+	Reason     string
+	Constraint string // The service's placement constraint expressions, when the reason blames a constraint
+	Count      int
+	LastSeen   time.Time
+}
+
+// closestMatch matches the container instance named in a placement failure, so events
+// differing only in which instance came closest are counted together.
+var closestMatch = regexp.MustCompile(`\(container-instance [^)]*\)`)
+
+// FetchPlacementFailures groups the "unable to place a task" events ECS retains for a
+// service by reason, most frequent first.
+func FetchPlacementFailures(clusterName, serviceName, awsProfile string) ([]PlacementFailure, error) {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ecs.New(sess)
+
+	resp, err := svc.DescribeServices(&ecs.DescribeServicesInput{
+		Cluster:  aws.String(clusterName),
+		Services: []*string{aws.String(serviceName)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing service %s: %v", serviceName, err)
+	}
+	if len(resp.Services) == 0 {
+		return nil, fmt.Errorf("service %s not found in cluster %s", serviceName, clusterName)
+	}
+	service := resp.Services[0]
+
+	constraints := formatPlacementConstraints(service.PlacementConstraints)
+
+	byReason := make(map[string]*PlacementFailure)
+	for _, event := range service.Events {
+		reason, ok := placementFailureReason(aws.StringValue(event.Message))
+		if !ok {
+			continue
+		}
+		failure, seen := byReason[reason]
+		if !seen {
+			failure = &PlacementFailure{Reason: reason}
+			if strings.Contains(strings.ToLower(reason), "constraint") {
+				failure.Constraint = strings.Join(constraints, "; ")
+			}
+			byReason[reason] = failure
+		}
+		failure.Count++
+		if createdAt := aws.TimeValue(event.CreatedAt); createdAt.After(failure.LastSeen) {
+			failure.LastSeen = createdAt
+		}
+	}
+
+	var failures []PlacementFailure
+	for _, failure := range byReason {
+		failures = append(failures, *failure)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].Count != failures[j].Count {
+			return failures[i].Count > failures[j].Count
+		}
+		return failures[i].LastSeen.After(failures[j].LastSeen)
+	})
+	return failures, nil
+}
+
+// placementFailureReason extracts why a task couldn't be placed from a service event such as
+// "(service web) was unable to place a task because no container instance met all of its
+// requirements. The closest matching (container-instance 0a1b) has insufficient memory available."
+func placementFailureReason(message string) (string, bool) {
+	if !strings.Contains(message, "unable to place a task") && !strings.Contains(message, "TaskPlacementConstraintViolation") {
+		return "", false
+	}
+	reason := message
+	if _, after, found := strings.Cut(message, " because "); found {
+		reason = after
+	}
+	reason, _, _ = strings.Cut(reason, " For more information")
+	reason = closestMatch.ReplaceAllString(reason, "(container-instance)")
+	return strings.TrimSpace(reason), true
+}
+
+// DisplayPlacementFailures prints placement failures in a table format.
+func DisplayPlacementFailures(failures []PlacementFailure) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Count\tLast Seen\tReason\tConstraint")
+	for _, failure := range failures {
+		constraint := failure.Constraint
+		if constraint == "" {
+			constraint = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", failure.Count, failure.LastSeen.Local().Format(time.RFC3339), failure.Reason, constraint)
+	}
+	w.Flush()
+}
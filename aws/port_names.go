@@ -0,0 +1,37 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// FetchNamedContainerPort returns the container port of the port mapping named portName on
+// containerName in the task definition, given as family:revision or an ARN.
+func FetchNamedContainerPort(taskDefinition, containerName, portName, awsProfile string) (int64, error) {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ecs.New(sess)
+
+	resp, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(taskDefinition),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error describing task definition %s: %v", taskDefinition, err)
+	}
+	for _, container := range resp.TaskDefinition.ContainerDefinitions {
+		if aws.StringValue(container.Name) != containerName {
+			continue
+		}
+		for _, mapping := range container.PortMappings {
+			if aws.StringValue(mapping.Name) == portName {
+				return aws.Int64Value(mapping.ContainerPort), nil
+			}
+		}
+		return 0, fmt.Errorf("container %s in %s has no port mapping named %s", containerName, taskDefinition, portName)
+	}
+	return 0, fmt.Errorf("task definition %s has no container %s", taskDefinition, containerName)
+}
@@ -0,0 +1,45 @@
+package aws
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WritePrometheusMetrics writes instance gauges in the Prometheus text exposition format.
+func WritePrometheusMetrics(instances []InstanceData, w io.Writer) error {
+	gauges := []struct {
+		name  string
+		help  string
+		value func(InstanceData) int
+	}{
+		{"enum_instance_running_tasks", "Number of tasks running on the container instance.", func(i InstanceData) int { return i.RunningTasksCount }},
+		{"enum_instance_cpu_reserved", "CPU units reserved by tasks on the container instance.", func(i InstanceData) int { return i.CPUReserved }},
+		{"enum_instance_memory_reserved", "Memory in MiB reserved by tasks on the container instance.", func(i InstanceData) int { return i.MemoryReserved }},
+	}
+
+	for _, gauge := range gauges {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", gauge.name, gauge.help, gauge.name); err != nil {
+			return fmt.Errorf("failed to write metric header: %v", err)
+		}
+		for _, instance := range instances {
+			_, err := fmt.Fprintf(w, "%s{cluster=\"%s\",instance_id=\"%s\",name=\"%s\",az=\"%s\"} %d\n",
+				gauge.name,
+				escapeLabelValue(instance.Cluster),
+				escapeLabelValue(instance.InstanceID),
+				escapeLabelValue(instance.Name),
+				escapeLabelValue(instance.AvailabilityZone),
+				gauge.value(instance))
+			if err != nil {
+				return fmt.Errorf("failed to write metric: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// escapeLabelValue escapes a label value as required by the exposition format.
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,45 @@
+package aws
+
+import (
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// fallbackRegion is used when neither --region, the environment nor the profile names a region.
+const fallbackRegion = "us-west-2"
+
+// regionFlag is the --region flag, set by SetRegion.
+var regionFlag string
+
+// profileRegion is the region of $AWS_PROFILE in ~/.aws/config, read once.
+var profileRegion = sync.OnceValue(func() string {
+	sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return ""
+	}
+	return aws.StringValue(sess.Config.Region)
+})
+
+// SetRegion sets the region enum's AWS calls go to, for the life of the process.
+// An empty region leaves the choice to Region's fallbacks.
+func SetRegion(name string) {
+	regionFlag = name
+}
+
+// Region returns the region AWS calls go to: the one given to SetRegion, else
+// $AWS_REGION, else $AWS_DEFAULT_REGION, else the profile's configured region,
+// else us-west-2.
+func Region() string {
+	for _, name := range []string{regionFlag, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")} {
+		if name != "" {
+			return name
+		}
+	}
+	if name := profileRegion(); name != "" {
+		return name
+	}
+	return fallbackRegion
+}
@@ -0,0 +1,35 @@
+package aws
+
+import (
+	"log"
+	"strings"
+
+	"enum/ssh"
+)
+
+// registrationErrorsCommand prints the last few registration failures in the ECS agent log.
+// tail keeps the exit status at 0 when grep finds nothing.
+const registrationErrorsCommand = `sudo grep -i -e 'failed to register' -e 'registration failed' /var/log/ecs/ecs-agent.log | tail -5`
+
+// PopulateRegistrationErrors sets RegistrationErrors on each instance to the last few
+// registration failures its ECS agent logged, over SSH. Like PopulateStorageDriver it is
+// left to callers, as it contacts every host. Hosts that can't be read are logged and
+// left unset.
+func PopulateRegistrationErrors(instances []InstanceData, opts ssh.SSHOptions) {
+	opts.Scheduler.Run(len(instances), func(i int) {
+		instance := &instances[i]
+		if instance.PrivateIP == "" {
+			return
+		}
+		output, err := ssh.SSHCommand(instance.PrivateIP, registrationErrorsCommand, opts.Verbose)
+		if err != nil {
+			log.Printf("Error reading the ECS agent log on instance %s: %v", instance.Name, err)
+			return
+		}
+		for _, line := range strings.Split(output, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				instance.RegistrationErrors = append(instance.RegistrationErrors, line)
+			}
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// PutS3Object uploads body to s3://bucket/key. A kmsKeyID enables SSE-KMS with that key.
+func PutS3Object(bucket, key string, body []byte, contentType, kmsKeyID, awsProfile string) error {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := s3.New(sess)
+
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(contentType),
+	}
+	if kmsKeyID != "" {
+		input.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+		input.SSEKMSKeyId = aws.String(kmsKeyID)
+	}
+	if _, err := svc.PutObject(input); err != nil {
+		return fmt.Errorf("error uploading to s3://%s/%s: %v", bucket, key, err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// sshPort is the port enum connects to instances on.
+const sshPort = 22
+
+// SGRule is a single inbound source allowed by a security group rule.
+type SGRule struct {
+	GroupID         string
+	GroupName       string
+	Protocol        string // "-1" means all protocols
+	FromPort        int64
+	ToPort          int64
+	CidrIpv4        string
+	CidrIpv6        string
+	ReferencedGroup string
+	PrefixListID    string
+	Description     string
+}
+
+// FetchSSHSecurityGroupRules returns the inbound rules of the instance's security
+// groups that allow the SSH port, including all-traffic rules and port ranges covering it.
+func FetchSSHSecurityGroupRules(instanceID, region, awsProfile string) ([]SGRule, error) {
+	sess, err := newSession(awsProfile, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ec2.New(sess)
+
+	instanceResp, err := svc.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(instanceID)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing instance %s: %v", instanceID, err)
+	}
+	var groupIDs []*string
+	for _, reservation := range instanceResp.Reservations {
+		for _, instance := range reservation.Instances {
+			for _, group := range instance.SecurityGroups {
+				groupIDs = append(groupIDs, group.GroupId)
+			}
+		}
+	}
+	if len(groupIDs) == 0 {
+		return nil, fmt.Errorf("no security groups found for instance %s", instanceID)
+	}
+
+	groupResp, err := svc.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		GroupIds: groupIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing security groups: %v", err)
+	}
+
+	var rules []SGRule
+	for _, group := range groupResp.SecurityGroups {
+		for _, permission := range group.IpPermissions {
+			protocol := aws.StringValue(permission.IpProtocol)
+			fromPort, toPort := aws.Int64Value(permission.FromPort), aws.Int64Value(permission.ToPort)
+			allTraffic := protocol == "-1"
+			if !allTraffic && (protocol != "tcp" && protocol != "6" || fromPort > sshPort || toPort < sshPort) {
+				continue
+			}
+
+			rule := SGRule{
+				GroupID:   aws.StringValue(group.GroupId),
+				GroupName: aws.StringValue(group.GroupName),
+				Protocol:  protocol,
+				FromPort:  fromPort,
+				ToPort:    toPort,
+			}
+			for _, ipRange := range permission.IpRanges {
+				r := rule
+				r.CidrIpv4 = aws.StringValue(ipRange.CidrIp)
+				r.Description = aws.StringValue(ipRange.Description)
+				rules = append(rules, r)
+			}
+			for _, ipRange := range permission.Ipv6Ranges {
+				r := rule
+				r.CidrIpv6 = aws.StringValue(ipRange.CidrIpv6)
+				r.Description = aws.StringValue(ipRange.Description)
+				rules = append(rules, r)
+			}
+			for _, pair := range permission.UserIdGroupPairs {
+				r := rule
+				r.ReferencedGroup = aws.StringValue(pair.GroupId)
+				r.Description = aws.StringValue(pair.Description)
+				rules = append(rules, r)
+			}
+			for _, prefixList := range permission.PrefixListIds {
+				r := rule
+				r.PrefixListID = aws.StringValue(prefixList.PrefixListId)
+				r.Description = aws.StringValue(prefixList.Description)
+				rules = append(rules, r)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+// DisplaySGRules prints security group rules in a table format.
+func DisplaySGRules(rules []SGRule) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.Debug)
+	fmt.Fprintln(writer, "Group ID\tGroup Name\tProtocol\tPorts\tSource\tDescription")
+	for _, rule := range rules {
+		protocol, ports := rule.Protocol, fmt.Sprintf("%d-%d", rule.FromPort, rule.ToPort)
+		if protocol == "-1" {
+			protocol, ports = "all", "all"
+		} else if rule.FromPort == rule.ToPort {
+			ports = fmt.Sprintf("%d", rule.FromPort)
+		}
+
+		source := rule.CidrIpv4
+		switch {
+		case rule.CidrIpv6 != "":
+			source = rule.CidrIpv6
+		case rule.ReferencedGroup != "":
+			source = rule.ReferencedGroup
+		case rule.PrefixListID != "":
+			source = rule.PrefixListID
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			rule.GroupID,
+			rule.GroupName,
+			protocol,
+			ports,
+			source,
+			rule.Description)
+	}
+	writer.Flush()
+}
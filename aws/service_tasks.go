@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// RunningTaskInfo is a running task of a service and where its containers run.
+type RunningTaskInfo struct {
+	TaskArn    string
+	StartedAt  time.Time
+	InstanceID string // EC2 instance the task runs on; empty for Fargate
+	Containers []TaskContainer
+}
+
+// TaskContainer is a container of a running task.
+type TaskContainer struct {
+	Name      string
+	RuntimeID string // The docker container ID on the instance
+}
+
+// ListRunningServiceTasks returns the service's running tasks, oldest first.
+func ListRunningServiceTasks(clusterName, serviceName, awsProfile string) ([]RunningTaskInfo, error) {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ecs.New(sess)
+
+	var taskArns []*string
+	err = svc.ListTasksPages(&ecs.ListTasksInput{
+		Cluster:       aws.String(clusterName),
+		ServiceName:   aws.String(serviceName),
+		DesiredStatus: aws.String(ecs.DesiredStatusRunning),
+	}, func(page *ecs.ListTasksOutput, lastPage bool) bool {
+		taskArns = append(taskArns, page.TaskArns...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing tasks of service %s: %v", serviceName, err)
+	}
+
+	var tasks []RunningTaskInfo
+	containerInstances := make(map[string]string) // container instance ARN -> EC2 instance ID
+	// DescribeTasks accepts at most 100 tasks per call.
+	for start := 0; start < len(taskArns); start += 100 {
+		resp, err := svc.DescribeTasks(&ecs.DescribeTasksInput{
+			Cluster: aws.String(clusterName),
+			Tasks:   taskArns[start:min(start+100, len(taskArns))],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error describing tasks: %v", err)
+		}
+		for _, task := range resp.Tasks {
+			if aws.StringValue(task.LastStatus) != ecs.DesiredStatusRunning {
+				continue
+			}
+			info := RunningTaskInfo{
+				TaskArn:    aws.StringValue(task.TaskArn),
+				StartedAt:  aws.TimeValue(task.StartedAt),
+				InstanceID: aws.StringValue(task.ContainerInstanceArn), // Resolved to an EC2 ID below
+			}
+			if arn := aws.StringValue(task.ContainerInstanceArn); arn != "" {
+				containerInstances[arn] = ""
+			}
+			for _, container := range task.Containers {
+				info.Containers = append(info.Containers, TaskContainer{
+					Name:      aws.StringValue(container.Name),
+					RuntimeID: aws.StringValue(container.RuntimeId),
+				})
+			}
+			tasks = append(tasks, info)
+		}
+	}
+
+	if err := resolveContainerInstances(svc, clusterName, containerInstances); err != nil {
+		return nil, err
+	}
+	for i := range tasks {
+		tasks[i].InstanceID = containerInstances[tasks[i].InstanceID]
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].StartedAt.Before(tasks[j].StartedAt)
+	})
+	return tasks, nil
+}
@@ -0,0 +1,86 @@
+package aws
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GenerateSSHConfig writes an ssh_config Host block for every instance, using
+// the instance Name as the alias. Instances sharing a Name get their instance ID
+// appended so every alias stays unique.
+func GenerateSSHConfig(instances []InstanceData, identityFile, bastion string, w io.Writer) error {
+	names := make(map[string]int)
+	for _, instance := range instances {
+		names[instance.Name]++
+	}
+
+	for _, instance := range instances {
+		if instance.PrivateIP == "" {
+			continue
+		}
+
+		alias := strings.Join(strings.Fields(instance.Name), "-")
+		if names[instance.Name] > 1 {
+			alias += "-" + instance.InstanceID
+		}
+
+		if err := writeSSHHost(w, alias, instance.PrivateIP, "", identityFile, bastion); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GenerateClusterSSHConfig writes an ssh_config Host block for every instance,
+// aliased "<cluster>-<name>-<last octet>" so several clusters can share one file.
+func GenerateClusterSSHConfig(instances []InstanceData, cluster, user, identityFile, bastion string, w io.Writer) error {
+	for _, instance := range instances {
+		if instance.PrivateIP == "" {
+			continue
+		}
+
+		octets := strings.Split(instance.PrivateIP, ".")
+		parts := []string{cluster, strings.Join(strings.Fields(instance.Name), "-"), octets[len(octets)-1]}
+		alias := strings.Join(nonEmpty(parts), "-")
+
+		if err := writeSSHHost(w, alias, instance.PrivateIP, user, identityFile, bastion); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nonEmpty drops empty alias parts, such as the name of an unnamed instance.
+func nonEmpty(parts []string) []string {
+	kept := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return kept
+}
+
+func writeSSHHost(w io.Writer, alias, hostName, user, identityFile, bastion string) error {
+	lines := []string{
+		"Host " + alias,
+		"    HostName " + hostName,
+	}
+	if user != "" {
+		lines = append(lines, "    User "+user)
+	}
+	if identityFile != "" {
+		lines = append(lines, "    IdentityFile "+identityFile)
+	}
+	if bastion != "" {
+		lines = append(lines, "    ProxyJump "+bastion)
+	}
+
+	if _, err := fmt.Fprintf(w, "%s\n\n", strings.Join(lines, "\n")); err != nil {
+		return fmt.Errorf("failed to write ssh config: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// PopulateSSMStatus sets SSMAgentActive on each instance whose SSM agent is registered
+// and reporting in. It costs extra API calls, so FetchEC2InstanceData leaves it to callers.
+func PopulateSSMStatus(instances []InstanceData, awsProfile string) error {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ssm.New(sess)
+
+	online := make(map[string]bool)
+	// The InstanceIds filter accepts at most 50 values.
+	for start := 0; start < len(instances); start += 50 {
+		var ids []*string
+		for _, instance := range instances[start:min(start+50, len(instances))] {
+			ids = append(ids, aws.String(instance.InstanceID))
+		}
+		err := svc.DescribeInstanceInformationPages(&ssm.DescribeInstanceInformationInput{
+			Filters: []*ssm.InstanceInformationStringFilter{{
+				Key:    aws.String("InstanceIds"),
+				Values: ids,
+			}},
+		}, func(page *ssm.DescribeInstanceInformationOutput, lastPage bool) bool {
+			for _, info := range page.InstanceInformationList {
+				if aws.StringValue(info.PingStatus) == ssm.PingStatusOnline {
+					online[aws.StringValue(info.InstanceId)] = true
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("error describing SSM instance information: %v", err)
+		}
+	}
+
+	for i := range instances {
+		instances[i].SSMAgentActive = online[instances[i].InstanceID]
+	}
+	return nil
+}
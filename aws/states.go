@@ -0,0 +1,70 @@
+package aws
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// InstanceStates is a set of EC2 instance state names to include. An empty set includes every state.
+type InstanceStates []string
+
+var (
+	// AllStates includes instances in any state.
+	AllStates InstanceStates
+	// RunningStates includes only running instances.
+	RunningStates = InstanceStates{ec2.InstanceStateNameRunning}
+	// ReachableStates includes instances whose containers may still answer over SSH.
+	ReachableStates = InstanceStates{ec2.InstanceStateNameRunning, ec2.InstanceStateNameStopping}
+)
+
+// Includes reports whether state is in the set.
+func (s InstanceStates) Includes(state string) bool {
+	return len(s) == 0 || slices.Contains(s, state)
+}
+
+// String renders the set as accepted by ParseInstanceStates.
+func (s InstanceStates) String() string {
+	if len(s) == 0 {
+		return "all"
+	}
+	return strings.Join(s, ",")
+}
+
+// ParseInstanceStates parses "all" or a comma separated list of EC2 state names such as "running,stopping".
+func ParseInstanceStates(value string) (InstanceStates, error) {
+	if value == "all" {
+		return AllStates, nil
+	}
+	var states InstanceStates
+	for _, state := range strings.Split(value, ",") {
+		state = strings.TrimSpace(state)
+		if !slices.Contains(ec2.InstanceStateName_Values(), state) {
+			return nil, fmt.Errorf("unknown instance state %q (want all or a list of %s)", state, strings.Join(ec2.InstanceStateName_Values(), ", "))
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// stateColors are the ANSI colors used for instance states that need attention.
+var stateColors = map[string]string{
+	ec2.InstanceStateNamePending:      "\033[36m",
+	ec2.InstanceStateNameStopping:     "\033[33m",
+	ec2.InstanceStateNameStopped:      "\033[33m",
+	ec2.InstanceStateNameShuttingDown: "\033[31m",
+	ec2.InstanceStateNameTerminated:   "\033[31m",
+}
+
+// colorState wraps state in an ANSI color. Running states get the default color
+// sequence so every cell carries the same number of escape bytes and tabwriter
+// columns stay aligned.
+func colorState(state string) string {
+	color, ok := stateColors[state]
+	if !ok {
+		color = "\033[39m"
+	}
+	return color + state + "\033[0m"
+}
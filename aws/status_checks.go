@@ -0,0 +1,64 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// StatusCheckOK is the status of a passing EC2 status check.
+const StatusCheckOK = ec2.SummaryStatusOk
+
+// PopulateStatusChecks sets SystemStatusCheck and InstanceStatusCheck on each instance from
+// EC2's status checks, e.g. "ok", "impaired" or "initializing". EC2 only checks running
+// instances; others are left empty.
+func PopulateStatusChecks(instances []InstanceData, awsProfile string) error {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ec2.New(sess)
+
+	type checks struct{ system, instance string }
+	statuses := make(map[string]checks)
+	// DescribeInstanceStatus accepts at most 100 instance IDs per call.
+	for start := 0; start < len(instances); start += 100 {
+		var ids []*string
+		for _, instance := range instances[start:min(start+100, len(instances))] {
+			ids = append(ids, aws.String(instance.InstanceID))
+		}
+		err := svc.DescribeInstanceStatusPages(&ec2.DescribeInstanceStatusInput{
+			InstanceIds: ids,
+		}, func(page *ec2.DescribeInstanceStatusOutput, lastPage bool) bool {
+			for _, status := range page.InstanceStatuses {
+				var c checks
+				if status.SystemStatus != nil {
+					c.system = aws.StringValue(status.SystemStatus.Status)
+				}
+				if status.InstanceStatus != nil {
+					c.instance = aws.StringValue(status.InstanceStatus.Status)
+				}
+				statuses[aws.StringValue(status.InstanceId)] = c
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("error describing instance status: %v", err)
+		}
+	}
+
+	for i := range instances {
+		c := statuses[instances[i].InstanceID]
+		instances[i].SystemStatusCheck, instances[i].InstanceStatusCheck = c.system, c.instance
+	}
+	return nil
+}
+
+// StatusCheckFailed reports whether either status check of a checked instance isn't ok.
+func (i InstanceData) StatusCheckFailed() bool {
+	if i.SystemStatusCheck == "" && i.InstanceStatusCheck == "" {
+		return false
+	}
+	return i.SystemStatusCheck != StatusCheckOK || i.InstanceStatusCheck != StatusCheckOK
+}
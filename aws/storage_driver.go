@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"enum/ssh"
+)
+
+// storageDriverCommand prints docker's storage driver and its status pairs as JSON, e.g.
+// overlay2	[["Backing Filesystem","xfs"],["Supports d_type","true"]].
+const storageDriverCommand = `sudo docker info --format '{{.Driver}}	{{json .DriverStatus}}'`
+
+// PopulateStorageDriver sets StorageDriver and StorageDriverOptions on each instance from
+// docker info over SSH. Like PopulateAgentUsage it is left to callers, as it contacts every
+// host. Hosts that can't be read are logged and left unset.
+func PopulateStorageDriver(instances []InstanceData, opts ssh.SSHOptions) {
+	opts.Scheduler.Run(len(instances), func(i int) {
+		instance := &instances[i]
+		if instance.PrivateIP == "" {
+			return
+		}
+		output, err := ssh.SSHCommand(instance.PrivateIP, storageDriverCommand, opts.Verbose)
+		if err != nil {
+			log.Printf("Error reading storage driver on instance %s: %v", instance.Name, err)
+			return
+		}
+		driver, options, err := parseStorageDriver(output)
+		if err != nil {
+			log.Printf("Error reading storage driver on instance %s: %v", instance.Name, err)
+			return
+		}
+		instance.StorageDriver, instance.StorageDriverOptions = driver, options
+	})
+}
+
+// parseStorageDriver parses the output of storageDriverCommand, returning the status
+// pairs as "key=value" options.
+func parseStorageDriver(output string) (string, []string, error) {
+	driver, status, found := strings.Cut(strings.TrimSpace(output), "\t")
+	if !found || driver == "" {
+		return "", nil, fmt.Errorf("unexpected docker info output %q", output)
+	}
+	var pairs [][]string
+	if err := json.Unmarshal([]byte(status), &pairs); err != nil {
+		return "", nil, fmt.Errorf("unexpected driver status %q: %v", status, err)
+	}
+	var options []string
+	for _, pair := range pairs {
+		if len(pair) == 2 {
+			options = append(options, pair[0]+"="+pair[1])
+		}
+	}
+	return driver, options, nil
+}
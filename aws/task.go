@@ -0,0 +1,71 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// TaskDetail is the control plane view of a single ECS task.
+type TaskDetail struct {
+	TaskArn        string
+	TaskDefinition string
+	Group          string // "service:<name>" for service tasks
+	LastStatus     string
+	DesiredStatus  string
+	StartedBy      string
+	StartedAt      time.Time
+}
+
+// DescribeTask fetches a single task from the cluster.
+func DescribeTask(clusterName, taskArn, awsProfile string) (TaskDetail, error) {
+	sess, err := newSession(awsProfile, taskRegion(taskArn))
+	if err != nil {
+		return TaskDetail{}, fmt.Errorf("failed to create session: %v", err)
+	}
+	resp, err := ecs.New(sess).DescribeTasks(&ecs.DescribeTasksInput{
+		Cluster: aws.String(clusterName),
+		Tasks:   []*string{aws.String(taskArn)},
+	})
+	if err != nil {
+		return TaskDetail{}, fmt.Errorf("error describing task %s: %v", taskArn, err)
+	}
+	if len(resp.Tasks) == 0 {
+		return TaskDetail{}, fmt.Errorf("task %s not found in cluster %s", taskArn, clusterName)
+	}
+
+	task := resp.Tasks[0]
+	return TaskDetail{
+		TaskArn:        aws.StringValue(task.TaskArn),
+		TaskDefinition: aws.StringValue(task.TaskDefinitionArn),
+		Group:          aws.StringValue(task.Group),
+		LastStatus:     aws.StringValue(task.LastStatus),
+		DesiredStatus:  aws.StringValue(task.DesiredStatus),
+		StartedBy:      aws.StringValue(task.StartedBy),
+		StartedAt:      aws.TimeValue(task.StartedAt),
+	}, nil
+}
+
+// TaskConsoleURL returns the ECS console page for a task.
+func TaskConsoleURL(clusterName, taskArn string) string {
+	region := taskRegion(taskArn)
+	if region == "" {
+		region = Region()
+	}
+	taskID := taskArn[strings.LastIndex(taskArn, "/")+1:]
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/ecs/v2/clusters/%s/tasks/%s/configuration?region=%s",
+		region, clusterName, taskID, region)
+}
+
+// taskRegion returns the region embedded in a task ARN, or "" if it can't be parsed.
+func taskRegion(taskArn string) string {
+	parsed, err := arn.Parse(taskArn)
+	if err != nil {
+		return ""
+	}
+	return parsed.Region
+}
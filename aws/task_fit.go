@@ -0,0 +1,196 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// FitResult says whether one more copy of a task definition could be placed on a
+// container instance right now, and if not, why.
+type FitResult struct {
+	InstanceID      string
+	Status          string // Container instance status, e.g. ACTIVE or DRAINING
+	RemainingCPU    int
+	RemainingMemory int // MiB
+	Fits            bool
+	Reasons         []string // Why the task doesn't fit; empty when it does
+}
+
+// taskRequirements is what a task definition needs from a container instance.
+type taskRequirements struct {
+	CPU          int
+	Memory       int      // MiB
+	Architecture string   // "x86_64" or "arm64"; empty when the task doesn't say
+	Attributes   []string // "name" or "name=value" attributes the task requires
+	HostPorts    []string // Static host ports, as ECS lists them in remaining PORTS
+}
+
+// ValidateTaskFit checks the task definition's CPU, memory, architecture, required
+// attributes and static host ports against the remaining resources of each of the
+// cluster's container instances. taskDefARN may also be a family or family:revision.
+func ValidateTaskFit(clusterName, taskDefARN, awsProfile string) ([]FitResult, error) {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ecs.New(sess)
+
+	resp, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(taskDefARN),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing task definition %s: %v", taskDefARN, err)
+	}
+	needs := requirementsOf(resp.TaskDefinition)
+
+	var arns []*string
+	err = svc.ListContainerInstancesPages(&ecs.ListContainerInstancesInput{
+		Cluster: aws.String(clusterName),
+	}, func(page *ecs.ListContainerInstancesOutput, lastPage bool) bool {
+		arns = append(arns, page.ContainerInstanceArns...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing container instances for cluster %s: %v", clusterName, err)
+	}
+
+	var results []FitResult
+	// DescribeContainerInstances accepts at most 100 container instances per call.
+	for start := 0; start < len(arns); start += 100 {
+		described, err := svc.DescribeContainerInstances(&ecs.DescribeContainerInstancesInput{
+			Cluster:            aws.String(clusterName),
+			ContainerInstances: arns[start:min(start+100, len(arns))],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error describing container instances: %v", err)
+		}
+		for _, containerInstance := range described.ContainerInstances {
+			results = append(results, checkFit(containerInstance, needs))
+		}
+	}
+	return results, nil
+}
+
+// requirementsOf reads a task definition's needs. Task-level CPU and memory win; without
+// them the containers' values are summed, taking the soft memory reservation where no
+// hard limit is set, as the scheduler does.
+func requirementsOf(taskDef *ecs.TaskDefinition) taskRequirements {
+	var needs taskRequirements
+	needs.CPU, _ = strconv.Atoi(aws.StringValue(taskDef.Cpu))
+	needs.Memory, _ = strconv.Atoi(aws.StringValue(taskDef.Memory))
+
+	var cpu, memory int
+	for _, container := range taskDef.ContainerDefinitions {
+		cpu += int(aws.Int64Value(container.Cpu))
+		if container.Memory != nil {
+			memory += int(aws.Int64Value(container.Memory))
+		} else {
+			memory += int(aws.Int64Value(container.MemoryReservation))
+		}
+		for _, mapping := range container.PortMappings {
+			if port := aws.Int64Value(mapping.HostPort); port > 0 && aws.StringValue(taskDef.NetworkMode) != ecs.NetworkModeAwsvpc {
+				needs.HostPorts = append(needs.HostPorts, strconv.FormatInt(port, 10))
+			}
+		}
+	}
+	if needs.CPU == 0 {
+		needs.CPU = cpu
+	}
+	if needs.Memory == 0 {
+		needs.Memory = memory
+	}
+
+	if platform := taskDef.RuntimePlatform; platform != nil {
+		needs.Architecture = strings.ToLower(aws.StringValue(platform.CpuArchitecture))
+	}
+	for _, attribute := range taskDef.RequiresAttributes {
+		name := aws.StringValue(attribute.Name)
+		if value := aws.StringValue(attribute.Value); value != "" {
+			name += "=" + value
+		}
+		needs.Attributes = append(needs.Attributes, name)
+	}
+	return needs
+}
+
+// checkFit compares needs with what a container instance has left.
+func checkFit(containerInstance *ecs.ContainerInstance, needs taskRequirements) FitResult {
+	result := FitResult{
+		InstanceID:      aws.StringValue(containerInstance.Ec2InstanceId),
+		Status:          aws.StringValue(containerInstance.Status),
+		RemainingCPU:    integerResource(containerInstance.RemainingResources, "CPU"),
+		RemainingMemory: integerResource(containerInstance.RemainingResources, "MEMORY"),
+	}
+
+	if result.Status != ecs.ContainerInstanceStatusActive {
+		result.Reasons = append(result.Reasons, "instance is "+result.Status)
+	}
+	if !aws.BoolValue(containerInstance.AgentConnected) {
+		result.Reasons = append(result.Reasons, "ECS agent is disconnected")
+	}
+	if needs.CPU > result.RemainingCPU {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("needs %d CPU units, %d free", needs.CPU, result.RemainingCPU))
+	}
+	if needs.Memory > result.RemainingMemory {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("needs %d MiB memory, %d free", needs.Memory, result.RemainingMemory))
+	}
+
+	attributes := make(map[string]string)
+	for _, attribute := range containerInstance.Attributes {
+		attributes[aws.StringValue(attribute.Name)] = aws.StringValue(attribute.Value)
+	}
+	if needs.Architecture != "" {
+		if arch := attributes["ecs.cpu-architecture"]; arch != "" && arch != needs.Architecture {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("task needs %s, instance is %s", needs.Architecture, arch))
+		}
+	}
+	for _, required := range needs.Attributes {
+		name, value, hasValue := strings.Cut(required, "=")
+		if actual, ok := attributes[name]; !ok || hasValue && actual != value {
+			result.Reasons = append(result.Reasons, "missing attribute "+required)
+		}
+	}
+
+	var usedPorts []string
+	for _, resource := range containerInstance.RemainingResources {
+		if aws.StringValue(resource.Name) == "PORTS" {
+			usedPorts = aws.StringValueSlice(resource.StringSetValue)
+		}
+	}
+	for _, port := range needs.HostPorts {
+		for _, used := range usedPorts {
+			if port == used {
+				result.Reasons = append(result.Reasons, "host port "+port+" is taken")
+			}
+		}
+	}
+
+	result.Fits = len(result.Reasons) == 0
+	return result
+}
+
+// DisplayTaskFit prints which container instances can fit a task in a table format.
+func DisplayTaskFit(results []FitResult) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "Instance ID\tStatus\tFree CPU\tFree Memory (MiB)\tFits\tWhy Not")
+	for _, result := range results {
+		fits, why := "yes", "-"
+		if !result.Fits {
+			fits, why = "no", strings.Join(result.Reasons, "; ")
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%s\t%s\n",
+			result.InstanceID,
+			result.Status,
+			result.RemainingCPU,
+			result.RemainingMemory,
+			fits,
+			why)
+	}
+	writer.Flush()
+}
@@ -0,0 +1,43 @@
+package aws
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// maxTaskProtection is the longest scale-in protection ECS accepts in one request.
+const maxTaskProtection = 48 * time.Hour
+
+// SetTaskProtection turns ECS task scale-in protection on for window, rounded up to whole
+// minutes, or off when window is 0. Protected tasks aren't stopped by service scale-in or
+// deployments, so nothing replaces them while they are worked on by hand.
+func SetTaskProtection(clusterName, taskArn string, window time.Duration, awsProfile string) error {
+	if window > maxTaskProtection {
+		return fmt.Errorf("task protection can't last longer than %s", maxTaskProtection)
+	}
+	sess, err := newSession(awsProfile, taskRegion(taskArn))
+	if err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+
+	input := &ecs.UpdateTaskProtectionInput{
+		Cluster:           aws.String(clusterName),
+		Tasks:             []*string{aws.String(taskArn)},
+		ProtectionEnabled: aws.Bool(window > 0),
+	}
+	if window > 0 {
+		input.ExpiresInMinutes = aws.Int64(int64((window + time.Minute - 1) / time.Minute))
+	}
+	resp, err := ecs.New(sess).UpdateTaskProtection(input)
+	if err != nil {
+		return fmt.Errorf("error updating protection of task %s: %v", taskArn, err)
+	}
+	if len(resp.Failures) > 0 {
+		failure := resp.Failures[0]
+		return fmt.Errorf("error updating protection of task %s: %s %s", taskArn, aws.StringValue(failure.Reason), aws.StringValue(failure.Detail))
+	}
+	return nil
+}
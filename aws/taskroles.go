@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// FetchTaskRoles maps the ARN of every running task in the cluster to the IAM role its
+// containers assume. A role set in the task's overrides wins over the task definition's;
+// tasks without a role map to "".
+func FetchTaskRoles(clusterName, awsProfile string) (map[string]string, error) {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ecs.New(sess)
+
+	var taskArns []*string
+	err = svc.ListTasksPages(&ecs.ListTasksInput{
+		Cluster:       aws.String(clusterName),
+		DesiredStatus: aws.String(ecs.DesiredStatusRunning),
+	}, func(page *ecs.ListTasksOutput, lastPage bool) bool {
+		taskArns = append(taskArns, page.TaskArns...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing tasks for cluster %s: %v", clusterName, err)
+	}
+
+	roles := make(map[string]string)
+	definitionRoles := make(map[string]string) // Task definitions are shared, so describe each once
+	// DescribeTasks accepts at most 100 tasks per call.
+	for start := 0; start < len(taskArns); start += 100 {
+		end := min(start+100, len(taskArns))
+		resp, err := svc.DescribeTasks(&ecs.DescribeTasksInput{
+			Cluster: aws.String(clusterName),
+			Tasks:   taskArns[start:end],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error describing tasks: %v", err)
+		}
+
+		for _, task := range resp.Tasks {
+			taskArn := aws.StringValue(task.TaskArn)
+			if task.Overrides != nil && aws.StringValue(task.Overrides.TaskRoleArn) != "" {
+				roles[taskArn] = aws.StringValue(task.Overrides.TaskRoleArn)
+				continue
+			}
+
+			definition := aws.StringValue(task.TaskDefinitionArn)
+			role, ok := definitionRoles[definition]
+			if !ok {
+				resp, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+					TaskDefinition: aws.String(definition),
+				})
+				if err != nil {
+					return nil, fmt.Errorf("error describing task definition %s: %v", definition, err)
+				}
+				role = aws.StringValue(resp.TaskDefinition.TaskRoleArn)
+				definitionRoles[definition] = role
+			}
+			roles[taskArn] = role
+		}
+	}
+
+	return roles, nil
+}
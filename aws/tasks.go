@@ -0,0 +1,176 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// StoppedTaskInfo describes why an ECS task stopped.
+type StoppedTaskInfo struct {
+	TaskArn              string
+	TaskDefinition       string
+	Group                string
+	StoppedReason        string
+	StopCode             string
+	StoppedAt            time.Time
+	InstanceID           string // EC2 instance the task ran on; empty for Fargate or a deregistered instance
+	ContainerInstanceArn string // ECS container instance the task ran on; empty for Fargate
+	Containers           []ContainerExit
+}
+
+// ContainerExit is the final state of a container in a stopped task.
+type ContainerExit struct {
+	Name      string
+	RuntimeID string // The docker container ID; empty when the container never started
+	ExitCode  *int64 // nil when the container never started
+	Reason    string
+}
+
+// ListStoppedTasks returns up to maxResults recently stopped tasks, newest first.
+func ListStoppedTasks(clusterName, awsProfile string, maxResults int64) ([]StoppedTaskInfo, error) {
+	return ListStoppedServiceTasks(clusterName, "", awsProfile, maxResults)
+}
+
+// ListStoppedServiceTasks is ListStoppedTasks narrowed to one service when serviceName is set.
+// A maxResults of 0 returns every stopped task ECS still retains.
+func ListStoppedServiceTasks(clusterName, serviceName, awsProfile string, maxResults int64) ([]StoppedTaskInfo, error) {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+	svc := ecs.New(sess)
+
+	var taskArns []*string
+	input := &ecs.ListTasksInput{
+		Cluster:       aws.String(clusterName),
+		DesiredStatus: aws.String(ecs.DesiredStatusStopped),
+	}
+	if serviceName != "" {
+		input.ServiceName = aws.String(serviceName)
+	}
+	// ListTasks isn't ordered, so collect every stopped task before picking the newest.
+	err = svc.ListTasksPages(input, func(page *ecs.ListTasksOutput, lastPage bool) bool {
+		taskArns = append(taskArns, page.TaskArns...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing stopped tasks for cluster %s: %v", clusterName, err)
+	}
+
+	var stopped []StoppedTaskInfo
+	containerInstances := make(map[string]string) // container instance ARN -> EC2 instance ID
+	// DescribeTasks accepts at most 100 tasks per call.
+	for start := 0; start < len(taskArns); start += 100 {
+		end := start + 100
+		if end > len(taskArns) {
+			end = len(taskArns)
+		}
+		resp, err := svc.DescribeTasks(&ecs.DescribeTasksInput{
+			Cluster: aws.String(clusterName),
+			Tasks:   taskArns[start:end],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error describing stopped tasks: %v", err)
+		}
+
+		for _, task := range resp.Tasks {
+			info := StoppedTaskInfo{
+				TaskArn:              aws.StringValue(task.TaskArn),
+				TaskDefinition:       aws.StringValue(task.TaskDefinitionArn),
+				Group:                aws.StringValue(task.Group),
+				StoppedReason:        aws.StringValue(task.StoppedReason),
+				StopCode:             aws.StringValue(task.StopCode),
+				StoppedAt:            aws.TimeValue(task.StoppedAt),
+				ContainerInstanceArn: aws.StringValue(task.ContainerInstanceArn),
+			}
+			if info.ContainerInstanceArn != "" {
+				containerInstances[info.ContainerInstanceArn] = ""
+			}
+			for _, container := range task.Containers {
+				info.Containers = append(info.Containers, ContainerExit{
+					Name:      aws.StringValue(container.Name),
+					RuntimeID: aws.StringValue(container.RuntimeId),
+					ExitCode:  container.ExitCode,
+					Reason:    aws.StringValue(container.Reason),
+				})
+			}
+			stopped = append(stopped, info)
+		}
+	}
+
+	if err := resolveContainerInstances(svc, clusterName, containerInstances); err != nil {
+		return nil, err
+	}
+	for i := range stopped {
+		stopped[i].InstanceID = containerInstances[stopped[i].ContainerInstanceArn]
+	}
+
+	sort.Slice(stopped, func(i, j int) bool {
+		return stopped[i].StoppedAt.After(stopped[j].StoppedAt)
+	})
+	if maxResults > 0 && int64(len(stopped)) > maxResults {
+		stopped = stopped[:maxResults]
+	}
+
+	return stopped, nil
+}
+
+// resolveContainerInstances fills in the EC2 instance ID for each container instance ARN key.
+// Instances that have since been deregistered are left empty.
+func resolveContainerInstances(svc *ecs.ECS, clusterName string, arns map[string]string) error {
+	var keys []*string
+	for arn := range arns {
+		keys = append(keys, aws.String(arn))
+	}
+	// DescribeContainerInstances accepts at most 100 instances per call.
+	for start := 0; start < len(keys); start += 100 {
+		end := min(start+100, len(keys))
+		resp, err := svc.DescribeContainerInstances(&ecs.DescribeContainerInstancesInput{
+			Cluster:            aws.String(clusterName),
+			ContainerInstances: keys[start:end],
+		})
+		if err != nil {
+			return fmt.Errorf("error describing container instances: %v", err)
+		}
+		for _, instance := range resp.ContainerInstances {
+			arns[aws.StringValue(instance.ContainerInstanceArn)] = aws.StringValue(instance.Ec2InstanceId)
+		}
+	}
+	return nil
+}
+
+// DisplayStoppedTasks prints stopped tasks in a table format.
+func DisplayStoppedTasks(tasks []StoppedTaskInfo) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.Debug)
+	fmt.Fprintln(writer, "Task\tGroup\tInstance\tStopped At\tStop Code\tExit Codes\tStopped Reason")
+	for _, task := range tasks {
+		var exits []string
+		for _, container := range task.Containers {
+			code := "-"
+			if container.ExitCode != nil {
+				code = fmt.Sprint(*container.ExitCode)
+			}
+			exits = append(exits, container.Name+"="+code)
+		}
+		instance := task.InstanceID
+		if instance == "" {
+			instance = "-"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			task.TaskArn[strings.LastIndex(task.TaskArn, "/")+1:],
+			task.Group,
+			instance,
+			task.StoppedAt.Local().Format(time.RFC3339),
+			task.StopCode,
+			strings.Join(exits, " "),
+			task.StoppedReason)
+	}
+	writer.Flush()
+}
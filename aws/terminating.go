@@ -0,0 +1,66 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+// Clients holds the EC2 and ECS clients for one profile. Tests substitute fakes.
+type Clients struct {
+	EC2 ec2iface.EC2API
+	ECS ecsiface.ECSAPI
+}
+
+// NewClients creates EC2 and ECS clients for awsProfile in Region().
+func NewClients(awsProfile string) (Clients, error) {
+	sess, err := newSession(awsProfile, "")
+	if err != nil {
+		return Clients{}, fmt.Errorf("failed to create session: %v", err)
+	}
+	return Clients{EC2: ec2.New(sess), ECS: ecs.New(sess)}, nil
+}
+
+// InstanceGoingAway re-checks an instance that stopped answering mid-operation. It returns
+// why the instance is on its way out, such as "EC2 state shutting-down" or "container
+// instance DRAINING", or "" when it isn't and the failure needs looking into.
+func InstanceGoingAway(clients Clients, clusterName, instanceID string) (string, error) {
+	resp, err := clients.EC2.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(instanceID)},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error describing instance %s: %v", instanceID, err)
+	}
+	for _, reservation := range resp.Reservations {
+		for _, instance := range reservation.Instances {
+			if instance.State == nil {
+				continue
+			}
+			switch state := aws.StringValue(instance.State.Name); state {
+			case ec2.InstanceStateNameShuttingDown, ec2.InstanceStateNameTerminated,
+				ec2.InstanceStateNameStopping, ec2.InstanceStateNameStopped:
+				return "EC2 state " + state, nil
+			}
+		}
+	}
+
+	if clusterName == "" {
+		return "", nil
+	}
+	draining, err := clients.ECS.ListContainerInstances(&ecs.ListContainerInstancesInput{
+		Cluster: aws.String(clusterName),
+		Filter:  aws.String("ec2InstanceId == " + instanceID),
+		Status:  aws.String(ecs.ContainerInstanceStatusDraining),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error checking container instance status of %s: %v", instanceID, err)
+	}
+	if len(draining.ContainerInstanceArns) > 0 {
+		return "container instance DRAINING", nil
+	}
+	return "", nil
+}
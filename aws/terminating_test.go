@@ -0,0 +1,109 @@
+package aws
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+// fakeEC2 answers DescribeInstances with one instance in state, or with err.
+type fakeEC2 struct {
+	ec2iface.EC2API
+	state string
+	err   error
+	calls int
+}
+
+func (f *fakeEC2) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{
+		Instances: []*ec2.Instance{{
+			InstanceId: input.InstanceIds[0],
+			State:      &ec2.InstanceState{Name: aws.String(f.state)},
+		}},
+	}}}, nil
+}
+
+// fakeECS answers ListContainerInstances with arns, or with err, recording the last input.
+type fakeECS struct {
+	ecsiface.ECSAPI
+	arns  []string
+	err   error
+	input *ecs.ListContainerInstancesInput
+}
+
+func (f *fakeECS) ListContainerInstances(input *ecs.ListContainerInstancesInput) (*ecs.ListContainerInstancesOutput, error) {
+	f.input = input
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ecs.ListContainerInstancesOutput{ContainerInstanceArns: aws.StringSlice(f.arns)}, nil
+}
+
+func TestInstanceGoingAway(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    string
+		draining []string
+		cluster  string
+		want     string
+	}{
+		{name: "shutting down", state: ec2.InstanceStateNameShuttingDown, cluster: "prod", want: "EC2 state shutting-down"},
+		{name: "terminated", state: ec2.InstanceStateNameTerminated, cluster: "prod", want: "EC2 state terminated"},
+		{name: "stopping", state: ec2.InstanceStateNameStopping, cluster: "prod", want: "EC2 state stopping"},
+		{name: "draining", state: ec2.InstanceStateNameRunning, draining: []string{"arn:aws:ecs:us-west-2:1:container-instance/prod/abc"}, cluster: "prod", want: "container instance DRAINING"},
+		{name: "running and active", state: ec2.InstanceStateNameRunning, cluster: "prod", want: ""},
+		{name: "no cluster to check", state: ec2.InstanceStateNameRunning, draining: []string{"arn"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ecsFake := &fakeECS{arns: tt.draining}
+			got, err := InstanceGoingAway(Clients{EC2: &fakeEC2{state: tt.state}, ECS: ecsFake}, tt.cluster, "i-0abc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if ecsFake.input != nil {
+				if filter := aws.StringValue(ecsFake.input.Filter); filter != "ec2InstanceId == i-0abc" {
+					t.Errorf("filter = %q", filter)
+				}
+				if status := aws.StringValue(ecsFake.input.Status); status != ecs.ContainerInstanceStatusDraining {
+					t.Errorf("status = %q", status)
+				}
+			}
+		})
+	}
+}
+
+func TestInstanceGoingAwayTerminatingSkipsECS(t *testing.T) {
+	ecsFake := &fakeECS{err: errors.New("should not be called")}
+	got, err := InstanceGoingAway(Clients{EC2: &fakeEC2{state: ec2.InstanceStateNameShuttingDown}, ECS: ecsFake}, "prod", "i-0abc")
+	if err != nil || got == "" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if ecsFake.input != nil {
+		t.Error("ECS was asked although EC2 already said the instance is going away")
+	}
+}
+
+func TestInstanceGoingAwayErrors(t *testing.T) {
+	_, err := InstanceGoingAway(Clients{EC2: &fakeEC2{err: errors.New("throttled")}, ECS: &fakeECS{}}, "prod", "i-0abc")
+	if err == nil || !strings.Contains(err.Error(), "describing instance i-0abc") {
+		t.Errorf("EC2 error = %v", err)
+	}
+	_, err = InstanceGoingAway(Clients{EC2: &fakeEC2{state: ec2.InstanceStateNameRunning}, ECS: &fakeECS{err: errors.New("throttled")}}, "prod", "i-0abc")
+	if err == nil || !strings.Contains(err.Error(), "container instance status") {
+		t.Errorf("ECS error = %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package aws
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var invalidTerraformChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// GenerateTerraformImports writes a terraform import command for every instance.
+func GenerateTerraformImports(instances []InstanceData, w io.Writer) error {
+	names := terraformNames(instances)
+	for _, instance := range instances {
+		if _, err := fmt.Fprintf(w, "terraform import aws_instance.%s %s\n", names[instance.InstanceID], instance.InstanceID); err != nil {
+			return fmt.Errorf("failed to write import: %v", err)
+		}
+	}
+	return nil
+}
+
+// GenerateTerraformDataSources writes an aws_instance data source block for every instance.
+func GenerateTerraformDataSources(instances []InstanceData, w io.Writer) error {
+	names := terraformNames(instances)
+	for _, instance := range instances {
+		if _, err := fmt.Fprintf(w, "data \"aws_instance\" \"%s\" {\n  instance_id = \"%s\"\n}\n\n", names[instance.InstanceID], instance.InstanceID); err != nil {
+			return fmt.Errorf("failed to write data source: %v", err)
+		}
+	}
+	return nil
+}
+
+// terraformNames derives a unique, valid Terraform resource name for each instance, keyed by instance ID.
+func terraformNames(instances []InstanceData) map[string]string {
+	counts := make(map[string]int)
+	base := make(map[string]string)
+	for _, instance := range instances {
+		name := invalidTerraformChars.ReplaceAllString(strings.ToLower(instance.Name), "_")
+		if name == "" || !strings.ContainsAny(name[:1], "abcdefghijklmnopqrstuvwxyz_") {
+			name = "node_" + name // Names must start with a letter or underscore
+		}
+		base[instance.InstanceID] = name
+		counts[name]++
+	}
+
+	names := make(map[string]string)
+	for id, name := range base {
+		if counts[name] > 1 {
+			name += "_" + strings.ReplaceAll(id, "-", "_")
+		}
+		names[id] = name
+	}
+	return names
+}
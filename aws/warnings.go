@@ -0,0 +1,96 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DuplicateIPError reports private IPs shared by more than one instance. It is
+// returned alongside valid results, so callers can treat it as a warning.
+type DuplicateIPError struct {
+	Duplicates map[string][]string // private IP -> instance IDs
+}
+
+func (e *DuplicateIPError) Error() string {
+	var ips []string
+	for ip := range e.Duplicates {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	var parts []string
+	for _, ip := range ips {
+		parts = append(parts, fmt.Sprintf("%s (%s)", ip, strings.Join(e.Duplicates[ip], ", ")))
+	}
+	return "duplicate private IPs: " + strings.Join(parts, "; ")
+}
+
+// MultipleVPCError reports a cluster whose instances span more than one VPC. It is
+// returned alongside valid results, so callers can treat it as a warning.
+type MultipleVPCError struct {
+	VPCs map[string][]string // VPC ID -> instance IDs
+}
+
+func (e *MultipleVPCError) Error() string {
+	var vpcs []string
+	for vpc := range e.VPCs {
+		vpcs = append(vpcs, vpc)
+	}
+	sort.Strings(vpcs)
+
+	var parts []string
+	for _, vpc := range vpcs {
+		parts = append(parts, fmt.Sprintf("%s (%d instances)", vpc, len(e.VPCs[vpc])))
+	}
+	return "instances span multiple VPCs: " + strings.Join(parts, "; ")
+}
+
+// IsWarning reports whether err only carries warnings that accompany valid results.
+// Joined errors are warnings only if every one of them is.
+func IsWarning(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if !IsWarning(e) {
+				return false
+			}
+		}
+		return true
+	}
+	var duplicateIP *DuplicateIPError
+	var multipleVPC *MultipleVPCError
+	return errors.As(err, &duplicateIP) || errors.As(err, &multipleVPC)
+}
+
+// findDuplicateIPs returns the private IPs used by more than one instance.
+func findDuplicateIPs(instances []InstanceData) map[string][]string {
+	byIP := make(map[string][]string)
+	for _, instance := range instances {
+		if instance.PrivateIP != "" {
+			byIP[instance.PrivateIP] = append(byIP[instance.PrivateIP], instance.InstanceID)
+		}
+	}
+
+	duplicates := make(map[string][]string)
+	for ip, ids := range byIP {
+		if len(ids) > 1 {
+			duplicates[ip] = ids
+		}
+	}
+	return duplicates
+}
+
+// findVPCs groups instance IDs by VPC, returning nil when they all share one.
+func findVPCs(instances []InstanceData) map[string][]string {
+	byVPC := make(map[string][]string)
+	for _, instance := range instances {
+		if instance.VPCID != "" {
+			byVPC[instance.VPCID] = append(byVPC[instance.VPCID], instance.InstanceID)
+		}
+	}
+	if len(byVPC) < 2 {
+		return nil
+	}
+	return byVPC
+}
@@ -0,0 +1,38 @@
+package aws
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"enum/ssh"
+)
+
+// zombieCommand counts the host's processes in the Z (defunct) state.
+const zombieCommand = `ps -eo stat= | awk '$1 ~ /^Z/ {n++} END {print n+0}'`
+
+// PopulateZombieProcesses sets ZombieProcessCount on each instance over SSH. A growing
+// count usually means the container runtime isn't reaping exited children and needs a
+// restart. Like PopulateStorageDriver it is left to callers, as it contacts every host.
+// Hosts that can't be read are logged and left unset.
+func PopulateZombieProcesses(instances []InstanceData, opts ssh.SSHOptions) {
+	opts.Scheduler.Run(len(instances), func(i int) {
+		instance := &instances[i]
+		if instance.PrivateIP == "" {
+			return
+		}
+		output, err := ssh.SSHCommand(instance.PrivateIP, zombieCommand, opts.Verbose)
+		if err != nil {
+			log.Printf("Error counting zombie processes on instance %s: %v", instance.Name, err)
+			return
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(output))
+		if err != nil {
+			log.Printf("Error counting zombie processes on instance %s: %v", instance.Name, fmt.Errorf("unexpected output %q", output))
+			return
+		}
+		instance.ZombieProcessCount = count
+		instance.ZombiesChecked = true
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+
+	"enum/aws"
+)
+
+// Some AMIs have sudo or PAM print a banner on stdout ahead of a command's own output,
+// which pushes the runtime line down, leaves stray rows in docker ps output and puts a
+// prefix in front of JSON. The helpers here find where the real output starts.
+
+// bannerWarned holds the hosts already warned about a banner, so each is reported once per run.
+var (
+	bannerMu     sync.Mutex
+	bannerWarned = make(map[string]bool)
+)
+
+// stripBanner drops the lines before the first line that valid accepts, returning the rest
+// of the output and the dropped prefix. Output without any valid line is returned as is.
+func stripBanner(output string, valid func(line string) bool) (rest, banner string) {
+	offset := 0
+	for _, line := range strings.SplitAfter(output, "\n") {
+		if valid(strings.TrimRight(line, "\r\n")) {
+			return output[offset:], output[:offset]
+		}
+		offset += len(line)
+	}
+	return output, ""
+}
+
+// resyncJSON returns output from the first line that starts a valid JSON document, along
+// with the prefix before it. Output that is already valid JSON, or that holds no valid
+// document, is returned as is.
+func resyncJSON(output string) (rest, banner string) {
+	if trimmed := strings.TrimSpace(output); trimmed == "" || json.Valid([]byte(trimmed)) {
+		return output, ""
+	}
+	offset := 0
+	for _, line := range strings.SplitAfter(output, "\n") {
+		if start := strings.TrimSpace(line); strings.HasPrefix(start, "[") || strings.HasPrefix(start, "{") {
+			if json.Valid([]byte(strings.TrimSpace(output[offset:]))) {
+				return output[offset:], output[:offset]
+			}
+		}
+		offset += len(line)
+	}
+	return output, ""
+}
+
+// isRuntimeLine reports whether line is the runtime name printed by a runtimeCommand.
+func isRuntimeLine(line string) bool {
+	line = strings.TrimSpace(line)
+	return line == runtimeDocker || line == runtimeContainerd
+}
+
+// isContainerLine reports whether line has the fields of containerFormat.
+func isContainerLine(line string) bool {
+	return strings.Count(line, "\t") == strings.Count(containerFormat, "\t")
+}
+
+// warnBanner logs, once per host, that a banner was stripped from the host's output.
+func warnBanner(instance aws.InstanceData, banner string) {
+	if strings.TrimSpace(banner) == "" {
+		return
+	}
+	bannerMu.Lock()
+	seen := bannerWarned[instance.InstanceID]
+	bannerWarned[instance.InstanceID] = true
+	bannerMu.Unlock()
+	if seen {
+		return
+	}
+	log.Printf("Warning: %s (%s) printed %q before the command output, which enum ignored. Turn off the sudo lecture or PAM/MOTD banner for non-interactive sessions on the host.",
+		instance.Name, instance.InstanceID, firstLine(banner, ""))
+}
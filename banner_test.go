@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// sudoLecture is what sudo prints on the first use by a user when lecture is on.
+const sudoLecture = `
+We trust you have received the usual lecture from the local System
+Administrator. It usually boils down to these three things:
+
+    #1) Respect the privacy of others.
+    #2) Think before you type.
+    #3) With great power comes great responsibility.
+
+`
+
+// motd is an Amazon Linux 2 login banner printed by a PAM motd module.
+const motd = `       __|  __|_  )
+       _|  (     /   Amazon Linux 2 AMI
+      ___|\___|___|
+
+https://aws.amazon.com/amazon-linux-2/
+3 package(s) needed for security, out of 12 available
+Run "sudo yum update" to apply all updates.
+`
+
+func TestStripBannerRuntimeLine(t *testing.T) {
+	containers := "abc123\tweb\tnginx:1.25\tUp 2 hours\t2 hours ago\n"
+	tests := []struct {
+		name       string
+		output     string
+		wantRest   string
+		wantBanner string
+	}{
+		{name: "no banner", output: "docker\n" + containers, wantRest: "docker\n" + containers},
+		{name: "sudo lecture", output: sudoLecture + "docker\n" + containers, wantRest: "docker\n" + containers, wantBanner: sudoLecture},
+		{name: "motd", output: motd + "containerd\n" + containers, wantRest: "containerd\n" + containers, wantBanner: motd},
+		{name: "CRLF banner", output: "Authorized use only\r\ndocker\r\n", wantRest: "docker\r\n", wantBanner: "Authorized use only\r\n"},
+		{name: "runtime line with spaces", output: "banner\n  docker  \n", wantRest: "  docker  \n", wantBanner: "banner\n"},
+		{name: "no runtime line", output: motd, wantRest: motd},
+		{name: "empty", output: "", wantRest: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rest, banner := stripBanner(tt.output, isRuntimeLine)
+			if rest != tt.wantRest {
+				t.Errorf("rest = %q, want %q", rest, tt.wantRest)
+			}
+			if banner != tt.wantBanner {
+				t.Errorf("banner = %q, want %q", banner, tt.wantBanner)
+			}
+		})
+	}
+}
+
+func TestStripBannerContainerRows(t *testing.T) {
+	rows := "abc123\tweb\tnginx:1.25\tUp 2 hours\t2 hours ago\ndef456\tapi\tapi:7\tUp 1 minute\t1 minute ago\n"
+
+	// A banner printed by sudo after the runtime line lands among the ps rows.
+	runtime, rest := splitRuntimeOutput(sudoLecture + "docker\n" + motd + rows)
+	if runtime != "docker" {
+		t.Fatalf("runtime = %q, want docker", runtime)
+	}
+	rest, banner := stripBanner(rest, isContainerLine)
+	if rest != rows {
+		t.Errorf("rows = %q, want %q", rest, rows)
+	}
+	if banner != motd {
+		t.Errorf("banner = %q, want the motd", banner)
+	}
+}
+
+func TestIsContainerLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"abc123\tweb\tnginx:1.25\tUp 2 hours\t2 hours ago", true},
+		{"abc123\tweb\tnginx:1.25\tUp 2 hours\t", true},
+		{"abc123\tweb\tnginx", false},
+		{"    #1) Respect the privacy of others.", false},
+		{"", false},
+		{"a\tb\tc\td\te\tf", false},
+	}
+	for _, tt := range tests {
+		if got := isContainerLine(tt.line); got != tt.want {
+			t.Errorf("isContainerLine(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestResyncJSON(t *testing.T) {
+	inspect := `[
+    {
+        "Id": "abc123",
+        "State": {"Status": "running"}
+    }
+]
+`
+	tests := []struct {
+		name       string
+		output     string
+		wantRest   string
+		wantBanner string
+	}{
+		{name: "valid JSON untouched", output: inspect, wantRest: inspect},
+		{name: "sudo lecture prefix", output: sudoLecture + inspect, wantRest: inspect, wantBanner: sudoLecture},
+		{name: "motd prefix", output: motd + inspect, wantRest: inspect, wantBanner: motd},
+		{
+			name:       "banner line with brackets",
+			output:     "[WARN] this host is scheduled for retirement\n" + inspect,
+			wantRest:   inspect,
+			wantBanner: "[WARN] this host is scheduled for retirement\n",
+		},
+		{name: "object document", output: "Last login: Mon Jun  3 10:00:00 2024\n{\"a\": 1}\n", wantRest: "{\"a\": 1}\n", wantBanner: "Last login: Mon Jun  3 10:00:00 2024\n"},
+		{name: "no JSON at all", output: motd, wantRest: motd},
+		{name: "truncated JSON", output: motd + "[{\"Id\": ", wantRest: motd + "[{\"Id\": "},
+		{name: "empty", output: "", wantRest: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rest, banner := resyncJSON(tt.output)
+			if rest != tt.wantRest {
+				t.Errorf("rest = %q, want %q", rest, tt.wantRest)
+			}
+			if banner != tt.wantBanner {
+				t.Errorf("banner = %q, want %q", banner, tt.wantBanner)
+			}
+			if tt.wantBanner != "" && !json.Valid([]byte(rest)) {
+				t.Errorf("rest is not valid JSON: %q", rest)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"enum/aws"
+)
+
+// clusterCacheTTL is how long the cached cluster list is trusted.
+const clusterCacheTTL = time.Hour
+
+// cacheEntry is the on-disk envelope of a cached value.
+type cacheEntry struct {
+	StoredAt time.Time       `json:"stored_at"`
+	Cluster  string          `json:"cluster,omitempty"` // Empty for entries not tied to one cluster
+	Data     json.RawMessage `json:"data"`
+}
+
+// cacheDir returns the directory enum keeps cached lookups in.
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "enum"), nil
+}
+
+// clusterCacheName is the cache entry holding the cluster list of the AWS profile and region in use.
+func clusterCacheName() string {
+	return "clusters-" + awsProfile + "-" + aws.Region()
+}
+
+// readCache loads a cached value into v, reporting false when it is missing or older than maxAge.
+func readCache(name string, maxAge time.Duration, v any) bool {
+	dir, err := cacheDir()
+	if err != nil {
+		return false
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil || time.Since(entry.StoredAt) > maxAge {
+		return false
+	}
+	return json.Unmarshal(entry.Data, v) == nil
+}
+
+// writeCache stores v under name, replacing any previous value. cluster records which
+// cluster the value describes so --refresh can drop it; pass "" for global values.
+func writeCache(name, cluster string, v any) error {
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(cacheEntry{StoredAt: time.Now(), Cluster: cluster, Data: data})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name+".json"), raw, 0o600)
+}
+
+// cacheFile is a cache entry found on disk.
+type cacheFile struct {
+	Name  string
+	Path  string
+	Entry cacheEntry
+}
+
+// listCache returns every readable cache entry, sorted by name.
+func listCache() ([]cacheFile, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var files []cacheFile
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var entry cacheEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			continue
+		}
+		files = append(files, cacheFile{
+			Name:  strings.TrimSuffix(filepath.Base(path), ".json"),
+			Path:  path,
+			Entry: entry,
+		})
+	}
+	return files, nil
+}
+
+// invalidateCache removes the entries describing cluster along with the cluster
+// list used for completion and the palette, returning how many were removed.
+func invalidateCache(cluster string) (int, error) {
+	files, err := listCache()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, file := range files {
+		if file.Name != clusterCacheName() && (cluster == "" || file.Entry.Cluster != cluster) {
+			continue
+		}
+		if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("unable to remove cache entry %s: %v", file.Name, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// cacheStatus prints each cache entry with the cluster it belongs to and its age.
+func cacheStatus(w io.Writer) error {
+	files, err := listCache()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(w, "Nothing is cached.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Entry\tCluster\tAge")
+	for _, file := range files {
+		cluster := file.Entry.Cluster
+		if cluster == "" {
+			cluster = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", file.Name, cluster, time.Since(file.Entry.StoredAt).Round(time.Second))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestInvalidateCache(t *testing.T) {
+	tests := []struct {
+		name        string
+		cluster     string
+		wantRemoved int
+		wantKept    []string
+	}{
+		{name: "cluster", cluster: "prod", wantRemoved: 3, wantKept: []string{"containers-default-us-west-2-staging"}},
+		{name: "no cluster", cluster: "", wantRemoved: 1, wantKept: []string{"containers-default-us-west-2-prod", "containers-default-us-west-2-staging", "replica-prod-web"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			t.Setenv("AWS_REGION", "us-west-2")
+			previousProfile := awsProfile
+			t.Cleanup(func() { awsProfile = previousProfile })
+			awsProfile = "default"
+
+			entries := map[string]string{
+				clusterCacheName():                     "",
+				"containers-default-us-west-2-prod":    "prod",
+				"containers-default-us-west-2-staging": "staging",
+				"replica-prod-web":                     "prod",
+			}
+			for name, cluster := range entries {
+				if err := writeCache(name, cluster, "value"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			removed, err := invalidateCache(tt.cluster)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if removed != tt.wantRemoved {
+				t.Errorf("removed %d entries, want %d", removed, tt.wantRemoved)
+			}
+			files, err := listCache()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var kept []string
+			for _, file := range files {
+				kept = append(kept, file.Name)
+			}
+			sort.Strings(kept)
+			if len(kept) != len(tt.wantKept) {
+				t.Fatalf("kept %v, want %v", kept, tt.wantKept)
+			}
+			for i := range kept {
+				if kept[i] != tt.wantKept[i] {
+					t.Fatalf("kept %v, want %v", kept, tt.wantKept)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"enum/aws"
+	"enum/ssh"
+)
+
+// binarySniffLen is how much of a file is checked for binary content, as git does.
+const binarySniffLen = 8000
+
+// containerFile is a file read from one container by catFiles.
+type containerFile struct {
+	Record  containerRecord
+	Content []byte
+	Err     string // Why the file couldn't be read, e.g. it doesn't exist in this container
+}
+
+// catFiles reads path from every running container matching searchTerm and prints it
+// under a header per container. With grep only matching lines are printed; with sum only
+// each file's SHA-256, flagging containers whose file differs from the most common one.
+func catFiles(searchTerm, path, grep string, sum bool) error {
+	var pattern *regexp.Regexp
+	if grep != "" {
+		var err error
+		if pattern, err = regexp.Compile(grep); err != nil {
+			return fmt.Errorf("invalid --grep pattern: %v", err)
+		}
+	}
+
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	byHost := make(map[string][]containerRecord)
+	var hosts []aws.InstanceData
+	for _, record := range scanContainers(instances, false) {
+		if len(matchingTerms(record, []string{searchTerm})) == 0 {
+			continue
+		}
+		id := record.Instance.InstanceID
+		if _, ok := byHost[id]; !ok {
+			hosts = append(hosts, record.Instance)
+		}
+		byHost[id] = append(byHost[id], record)
+	}
+	if len(hosts) == 0 {
+		return fmt.Errorf("no running containers match %q", searchTerm)
+	}
+
+	perHost := make([][]containerFile, len(hosts))
+	hostScheduler.Run(len(hosts), func(h int) {
+		instance := hosts[h]
+		files, err := catHost(instance, byHost[instance.InstanceID], path)
+		if err != nil {
+			log.Printf("Error reading %s on instance %s: %v", path, instance.Name, err)
+		}
+		perHost[h] = files
+	})
+	var files []containerFile
+	for _, hostFiles := range perHost {
+		files = append(files, hostFiles...)
+	}
+
+	if sum {
+		printFileSums(files)
+		return nil
+	}
+
+	w, err := cappedStdout()
+	if err != nil {
+		return err
+	}
+	for i, file := range files {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "==> %s on %s <==\n", file.Record.Name, file.Record.Instance.Name)
+		switch {
+		case file.Err != "":
+			fmt.Fprintf(w, "(%s)\n", file.Err)
+		case isBinary(file.Content):
+			fmt.Fprintf(w, "(binary file, %d bytes, not shown; use --sha256 to compare)\n", len(file.Content))
+		case pattern != nil:
+			for _, line := range strings.Split(string(file.Content), "\n") {
+				if pattern.MatchString(line) {
+					fmt.Fprintln(w, line)
+				}
+			}
+		default:
+			w.Write(file.Content)
+			if len(file.Content) > 0 && !bytes.HasSuffix(file.Content, []byte("\n")) {
+				fmt.Fprintln(w)
+			}
+		}
+	}
+	return w.Close()
+}
+
+// catHost reads path from each of the records' containers over one SSH connection.
+// The exec runs without a TTY, so the file's bytes come through unchanged.
+func catHost(instance aws.InstanceData, records []containerRecord, path string) ([]containerFile, error) {
+	conn, err := ssh.Connect(instance.PrivateIP, verbose)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var files []containerFile
+	for _, record := range records {
+		result, err := conn.Run(fmt.Sprintf("sudo %s exec %s cat -- %s", containerCLI(instance), record.ID, ssh.ShellQuote(path)))
+		if err != nil {
+			return files, err
+		}
+		file := containerFile{Record: record, Content: []byte(result.Stdout)}
+		if result.ExitCode != 0 {
+			file.Err = firstLine(result.Stderr, fmt.Sprintf("exit status %d", result.ExitCode))
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// printFileSums prints each container's SHA-256 of the file, marking those that differ
+// from the hash most containers have.
+func printFileSums(files []containerFile) {
+	counts := make(map[string]int)
+	sums := make([]string, len(files))
+	for i, file := range files {
+		if file.Err != "" {
+			continue
+		}
+		digest := sha256.Sum256(file.Content)
+		sums[i] = hex.EncodeToString(digest[:])
+		counts[sums[i]]++
+	}
+	common := ""
+	for sum, count := range counts {
+		if count > counts[common] || count == counts[common] && sum < common {
+			common = sum
+		}
+	}
+
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return sums[order[a]] < sums[order[b]] })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Container\tInstance\tSHA-256\t")
+	for _, i := range order {
+		sum, flag := sums[i], ""
+		if files[i].Err != "" {
+			sum = files[i].Err
+		}
+		if sums[i] != common {
+			flag = "!"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", files[i].Record.Name, files[i].Record.Instance.Name, sum, flag)
+	}
+	w.Flush()
+	if len(counts) > 1 {
+		fmt.Printf("\n! %d different versions of the file\n", len(counts))
+	}
+}
+
+// isBinary reports whether content looks like a binary file: a NUL byte or invalid
+// UTF-8 near the start.
+func isBinary(content []byte) bool {
+	head := content
+	if len(head) > binarySniffLen {
+		head = head[:binarySniffLen]
+		// Don't count a multi-byte character cut off by the sniff length.
+		for i := 0; i < utf8.UTFMax-1 && !utf8.Valid(head); i++ {
+			head = head[:len(head)-1]
+		}
+	}
+	return bytes.IndexByte(head, 0) != -1 || !utf8.Valid(head)
+}
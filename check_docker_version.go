@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"enum/aws"
+	"enum/ssh"
+)
+
+// checkDockerVersion reports the docker daemon version of every running instance and
+// whether it meets minVersion.
+func checkDockerVersion(minVersion string) error {
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+
+	hosts := make([]string, len(instances))
+	for i, instance := range instances {
+		hosts[i] = instance.PrivateIP
+	}
+	versionCmd := runtimeCommand(func(cli string) string {
+		if cli != "docker" {
+			return "true" // Nothing to check; parseVersionOutput reports the runtime
+		}
+		return "sudo docker version --format '{{.Server.Version}}'"
+	})
+	results, err := ssh.CheckMinDockerVersion(hosts, minVersion, versionCmd, parseVersionOutput, ssh.SSHOptions{Verbose: verbose, Scheduler: hostScheduler})
+	if err != nil {
+		return err
+	}
+
+	var outdated, unknown int
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Instance ID\tName\tDocker Version\tStatus")
+	for i, result := range results {
+		status := "ok"
+		switch {
+		case result.Error != "":
+			status = "unknown: " + result.Error
+			unknown++
+		case !result.OK:
+			status = "below " + minVersion
+			outdated++
+		}
+		version := result.Version
+		if version == "" {
+			version = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", instances[i].InstanceID, instances[i].Name, version, status)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d of %d instances run docker older than %s", outdated, len(results), minVersion)
+	if unknown > 0 {
+		fmt.Printf(", %d could not be checked", unknown)
+	}
+	fmt.Println(".")
+	return nil
+}
+
+// parseVersionOutput reads the daemon version from the output of check-docker-version's
+// runtimeCommand, refusing hosts where docker isn't the active runtime.
+func parseVersionOutput(output string) (string, error) {
+	runtime, version := splitRuntimeOutput(output)
+	switch runtime {
+	case runtimeDocker:
+		return strings.TrimSpace(version), nil
+	case runtimeContainerd:
+		return "", fmt.Errorf("runs containerd without docker")
+	}
+	return "", fmt.Errorf("unexpected output %q", strings.TrimSpace(output))
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVersionOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    string
+		wantErr string
+	}{
+		{name: "docker", output: "docker\n24.0.5\n", want: "24.0.5"},
+		{name: "docker after a banner", output: "Authorized use only\n\ndocker\n20.10.25\n", want: "20.10.25"},
+		{name: "containerd", output: "containerd\n", wantErr: "runs containerd"},
+		{name: "garbage", output: "bash: systemctl: command not found\n", wantErr: "unexpected output"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVersionOutput(tt.output)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseVersionOutput(%q) = %q, %v; want %q", tt.output, got, err, tt.want)
+			}
+		})
+	}
+}
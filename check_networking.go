@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"enum/ssh"
+)
+
+// iptablesRule is a rule or chain policy from iptables -L -n -v output.
+type iptablesRule struct {
+	Packets     string
+	Bytes       string
+	Target      string
+	Protocol    string
+	In          string
+	Out         string
+	Source      string
+	Destination string
+	Extra       string
+}
+
+// checkNetworking reports the DROP rules in the FORWARD chain and the bridge network
+// options on the host running a container, the usual suspects when containers can't talk.
+func checkNetworking(containerID string) error {
+	instance, err := locateContainer(containerID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Container %s runs on %s (%s)\n\n", containerID, instance.Name, instance.PrivateIP)
+
+	output, err := ssh.SSHCommand(instance.PrivateIP, "sudo iptables -L FORWARD -n -v -x", false)
+	if err != nil {
+		return fmt.Errorf("error listing iptables rules on %s: %v", instance.Name, err)
+	}
+	policy, drops := parseForwardDrops(output)
+
+	// docker sets the FORWARD policy to DROP and relies on its own ACCEPT rules, so only
+	// call it out when packets have actually hit it.
+	fmt.Printf("FORWARD chain policy: %s\n", policy)
+	if len(drops) == 0 {
+		fmt.Println("No DROP rules in the FORWARD chain.")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Packets\tBytes\tProtocol\tIn\tOut\tSource\tDestination\tMatch")
+		for _, rule := range drops {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				rule.Packets, rule.Bytes, rule.Protocol, rule.In, rule.Out, rule.Source, rule.Destination, rule.Extra)
+		}
+		w.Flush()
+	}
+
+	options, err := ssh.SSHCommand(instance.PrivateIP, "sudo "+containerCLI(instance)+" network inspect bridge --format '{{.Options}}'", false)
+	if err != nil {
+		return fmt.Errorf("error inspecting the bridge network on %s: %v", instance.Name, err)
+	}
+	fmt.Printf("\nBridge network options: %s\n", strings.TrimSpace(options))
+	return nil
+}
+
+// parseForwardDrops returns the chain policy line and every DROP rule from iptables -L FORWARD -n -v output.
+func parseForwardDrops(output string) (string, []iptablesRule) {
+	policy := "unknown"
+	var drops []iptablesRule
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "Chain FORWARD") {
+			// "Chain FORWARD (policy DROP 12 packets, 720 bytes)"
+			if start := strings.Index(line, "(policy "); start != -1 {
+				policy = strings.TrimSuffix(line[start+len("(policy "):], ")")
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 9 || fields[2] != "DROP" {
+			continue
+		}
+		drops = append(drops, iptablesRule{
+			Packets:     fields[0],
+			Bytes:       fields[1],
+			Target:      fields[2],
+			Protocol:    fields[3],
+			In:          fields[5],
+			Out:         fields[6],
+			Source:      fields[7],
+			Destination: fields[8],
+			Extra:       strings.Join(fields[9:], " "),
+		})
+	}
+	return policy, drops
+}
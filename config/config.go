@@ -0,0 +1,152 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// File is the on-disk enum configuration.
+type File struct {
+	Preferences       map[string]string      `json:"preferences,omitempty"`
+	Environments      map[string]Environment `json:"environments,omitempty"`
+	AllowedOperations []string               `json:"allowed_operations,omitempty"`
+
+	// RemoteEnv is set on every remote command. Values may use {local_user}, {cluster}, {env} and {reason}.
+	RemoteEnv map[string]string `json:"remote_env,omitempty"`
+
+	Out *OutConfig `json:"out,omitempty"`
+
+	// Actions are the remediations `enum action` can run, by name. They are only ever
+	// read from this file, never from the command line.
+	Actions map[string]Action `json:"actions,omitempty"`
+}
+
+// Action is a command template run on a host or inside a container by `enum action`.
+// {name} placeholders in Command are replaced with the shell-quoted parameter values.
+type Action struct {
+	Description string        `json:"description,omitempty"`
+	Target      string        `json:"target"` // "host" or "container"
+	Command     string        `json:"command"`
+	Params      []ActionParam `json:"params,omitempty"`
+}
+
+// ActionParam declares a parameter of an action. A parameter that is not required
+// takes Default, which may be empty, when no value is given.
+type ActionParam struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required,omitempty"`
+	Pattern  string `json:"pattern,omitempty"` // Regular expression the whole value must match
+	Default  string `json:"default,omitempty"`
+}
+
+// OutConfig controls uploads made by --out s3://... and --out https://...
+type OutConfig struct {
+	S3KMSKeyID string `json:"s3_kms_key_id,omitempty"` // Encrypt S3 uploads with SSE-KMS using this key
+	TokenEnv   string `json:"token_env,omitempty"`     // Environment variable holding the bearer token for HTTP uploads; defaults to ENUM_OUT_TOKEN
+}
+
+// Environment holds settings shared by a group of clusters, such as prod or staging.
+type Environment struct {
+	Clusters    []string `json:"clusters,omitempty"`
+	MaxSession  string   `json:"max_session,omitempty"`  // Go duration, e.g. "30m"; empty or "0" disables
+	IdleTimeout string   `json:"idle_timeout,omitempty"` // Go duration, e.g. "10m"; empty or "0" disables
+
+	// Concurrency and Throttle are the defaults for --concurrency and --throttle.
+	Concurrency int    `json:"concurrency,omitempty"`
+	Throttle    string `json:"throttle,omitempty"` // Go duration, e.g. "500ms"
+
+	// MaxSessions is the default for --max-sessions, for hosts whose sshd MaxSessions is low.
+	MaxSessions int `json:"max_sessions,omitempty"`
+
+	// ExpectedAccount is the AWS account ID the credentials must belong to when set.
+	ExpectedAccount string `json:"expected_account,omitempty"`
+
+	// Protected makes mutating commands on the environment's clusters require --reason.
+	Protected bool `json:"protected,omitempty"`
+
+	// AllowedOperations replaces the top-level list for this environment when set.
+	AllowedOperations []string `json:"allowed_operations,omitempty"`
+
+	// RemoteEnv adds to, and overrides keys of, the top-level remote_env.
+	RemoteEnv map[string]string `json:"remote_env,omitempty"`
+}
+
+// Environment returns the environment called name or, when name is empty, the
+// first environment (by name) that lists cluster. The bool reports whether one was found.
+func (f *File) Environment(name, cluster string) (string, Environment, bool) {
+	if name != "" {
+		env, ok := f.Environments[name]
+		return name, env, ok
+	}
+
+	var names []string
+	for envName := range f.Environments {
+		names = append(names, envName)
+	}
+	sort.Strings(names)
+	for _, envName := range names {
+		for _, c := range f.Environments[envName].Clusters {
+			if c == cluster {
+				return envName, f.Environments[envName], true
+			}
+		}
+	}
+	return "", Environment{}, false
+}
+
+// Path returns the location of the config file. ENUM_CONFIG overrides the default
+// of enum/config.json under the user's config directory.
+func Path() (string, error) {
+	if path := os.Getenv("ENUM_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to find config directory: %v", err)
+	}
+	return filepath.Join(dir, "enum", "config.json"), nil
+}
+
+// Load reads the config file, returning an empty configuration when it doesn't exist.
+func Load() (*File, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{}
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config file %s: %v", path, err)
+	}
+	if err := json.Unmarshal(raw, file); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return file, nil
+}
+
+// Save writes the configuration to the config file, creating its directory if needed.
+func (f *File) Save() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("unable to create config directory: %v", err)
+	}
+
+	raw, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode config: %v", err)
+	}
+	if err := os.WriteFile(path, append(raw, '\n'), 0o600); err != nil {
+		return fmt.Errorf("unable to write config file %s: %v", path, err)
+	}
+	return nil
+}
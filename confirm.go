@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// promptIn and promptOut are where confirmations are asked. Tests replace them.
+var (
+	promptIn  io.Reader = os.Stdin
+	promptOut io.Writer = os.Stdout
+)
+
+// errNotConfirmed is returned by confirmChange when the answer wasn't yes.
+var errNotConfirmed = fmt.Errorf("not confirmed")
+
+// confirmChange is the confirmation every mutating command asks before changing the
+// cluster. In a protected environment it refuses without --reason, even with assumeYes.
+// The reason, if given, is shown with the question, which assumeYes skips.
+func confirmChange(prompt string, assumeYes bool) error {
+	if err := missingReason("this change is refused:"); err != nil {
+		return err
+	}
+	if operatorReason != "" {
+		fmt.Fprintf(promptOut, "Reason: %s\n", operatorReason)
+	}
+	if assumeYes {
+		return nil
+	}
+	if !confirm(prompt) {
+		return errNotConfirmed
+	}
+	return nil
+}
+
+// confirm asks a yes/no question on the terminal and reports whether the answer was yes.
+// Anything other than "y" or "yes", including end of input, counts as no.
+func confirm(prompt string) bool {
+	fmt.Fprintf(promptOut, "%s [y/N]: ", prompt)
+	answer, err := bufio.NewReader(promptIn).ReadString('\n')
+	if err != nil {
+		fmt.Fprintln(promptOut)
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
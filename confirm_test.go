@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"enum/config"
+
+	"github.com/spf13/cobra"
+)
+
+// setUpConfirm points the confirmation prompt at answer and returns what it prints.
+func setUpConfirm(t *testing.T, answer, reason, protected string) *bytes.Buffer {
+	t.Helper()
+	var out bytes.Buffer
+	oldIn, oldOut, oldReason, oldProtected := promptIn, promptOut, operatorReason, protectedEnvironment
+	t.Cleanup(func() {
+		promptIn, promptOut, operatorReason, protectedEnvironment = oldIn, oldOut, oldReason, oldProtected
+	})
+	promptIn, promptOut = strings.NewReader(answer), &out
+	operatorReason, protectedEnvironment = reason, protected
+	return &out
+}
+
+func TestConfirmChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		answer     string
+		assumeYes  bool
+		reason     string
+		protected  string
+		wantErr    string
+		wantOutput []string
+		wantPrompt bool
+	}{
+		{name: "yes", answer: "y\n", wantPrompt: true},
+		{name: "yes spelled out", answer: " YES \n", wantPrompt: true},
+		{name: "no", answer: "n\n", wantErr: errNotConfirmed.Error(), wantPrompt: true},
+		{name: "end of input", answer: "", wantErr: errNotConfirmed.Error(), wantPrompt: true},
+		{name: "assume yes", assumeYes: true},
+		{name: "reason shown", answer: "y\n", reason: "INC-1", wantOutput: []string{"Reason: INC-1"}, wantPrompt: true},
+		{name: "reason shown with assume yes", assumeYes: true, reason: "INC-1", wantOutput: []string{"Reason: INC-1"}},
+		{name: "protected with reason", answer: "y\n", reason: "INC-1", protected: "prod", wantPrompt: true},
+		{name: "protected without reason", answer: "y\n", protected: "prod", wantErr: `environment "prod" is protected`},
+		{name: "protected without reason despite assume yes", assumeYes: true, protected: "prod", wantErr: `environment "prod" is protected`},
+		{name: "protected with blank reason", answer: "y\n", reason: "  ", protected: "prod", wantErr: "--reason"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := setUpConfirm(t, tt.answer, tt.reason, tt.protected)
+			err := confirmChange("Do it?", tt.assumeYes)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output %q doesn't contain %q", out.String(), want)
+				}
+			}
+			if prompted := strings.Contains(out.String(), "Do it? [y/N]"); prompted != tt.wantPrompt {
+				t.Errorf("prompted = %v, want %v; output %q", prompted, tt.wantPrompt, out.String())
+			}
+		})
+	}
+}
+
+func TestRequireReason(t *testing.T) {
+	file := &config.File{Environments: map[string]config.Environment{
+		"prod":    {Clusters: []string{"prod-cluster"}, Protected: true},
+		"staging": {Clusters: []string{"staging-cluster"}},
+	}}
+	tests := []struct {
+		name          string
+		command       string
+		cluster       string
+		reason        string
+		wantErr       string
+		wantProtected string
+	}{
+		{name: "read in protected", command: "list-ec2", cluster: "prod-cluster"},
+		{name: "mutate in protected without reason", command: "restart-all", cluster: "prod-cluster", wantErr: "restart-all is classed as mutate-container", wantProtected: "prod"},
+		{name: "mutate in protected with reason", command: "restart-all", cluster: "prod-cluster", reason: "INC-1", wantProtected: "prod"},
+		{name: "mutate in unprotected", command: "action", cluster: "staging-cluster"},
+		{name: "mutate outside any environment", command: "action", cluster: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpConfirm(t, "", tt.reason, "stale")
+			oldCluster := ActiveConfig.ClusterName
+			t.Cleanup(func() { ActiveConfig.ClusterName = oldCluster })
+			ActiveConfig.ClusterName = tt.cluster
+
+			root := &cobra.Command{Use: "enum"}
+			cmd := &cobra.Command{Use: tt.command}
+			root.AddCommand(cmd)
+			err := requireReason(cmd, file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if protectedEnvironment != tt.wantProtected {
+				t.Errorf("protectedEnvironment = %q, want %q", protectedEnvironment, tt.wantProtected)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"enum/ssh"
+)
+
+// cpuThrottleInterval is how often --watch samples the container.
+const cpuThrottleInterval = 5 * time.Second
+
+// cpuThrottleSample is a container's CPU usage and its cgroup's cumulative throttling counters.
+type cpuThrottleSample struct {
+	CPUPerc       string
+	Periods       int64 // CFS enforcement periods elapsed
+	Throttled     int64 // Periods in which the container was throttled
+	ThrottledTime time.Duration
+}
+
+// cpuThrottleCommand prints docker stats for the container, a separator line and its
+// cgroup's cpu.stat. The cgroup is found through the container's init process, which
+// covers both the ECS (/ecs/<task>/<id>) and plain docker (/docker/<id>) hierarchies
+// on cgroup v1 and v2.
+func cpuThrottleCommand(cli, containerID string) string {
+	return fmt.Sprintf(`pid=$(sudo %[1]s inspect --format '{{.State.Pid}}' %[2]s) && `+
+		`sudo %[1]s stats --no-stream --format '{{json .}}' %[2]s && echo --- && `+
+		`if [ -f /sys/fs/cgroup/cgroup.controllers ]; then `+
+		`dir=/sys/fs/cgroup$(sudo awk -F: '$1 == "0" {print $3}' /proc/$pid/cgroup); `+
+		`else dir=/sys/fs/cgroup/cpu$(sudo awk -F: '$2 ~ /(^|,)cpu(,|$)/ {print $3}' /proc/$pid/cgroup); fi && `+
+		`sudo cat "$dir/cpu.stat"`, cli, ssh.ShellQuote(containerID))
+}
+
+// cpuThrottle prints a container's CPU usage and throttling. With watch it keeps sampling
+// until interrupted, printing the throttling since the previous sample.
+func cpuThrottle(containerID string, watch bool) error {
+	instance, err := locateContainer(containerID)
+	if err != nil {
+		return err
+	}
+	conn, err := ssh.Connect(instance.PrivateIP, verbose)
+	if err != nil {
+		return fmt.Errorf("error connecting to %s: %v", instance.Name, err)
+	}
+	defer conn.Close()
+
+	cmd := cpuThrottleCommand(containerCLI(instance), containerID)
+	sample := func() (cpuThrottleSample, error) {
+		result, err := conn.Run(cmd)
+		if err != nil {
+			return cpuThrottleSample{}, fmt.Errorf("error reading CPU stats on %s: %v", instance.Name, err)
+		}
+		if result.ExitCode != 0 {
+			return cpuThrottleSample{}, fmt.Errorf("error reading CPU stats on %s: exit status %d: %s", instance.Name, result.ExitCode, strings.TrimSpace(result.Stderr))
+		}
+		return parseCPUThrottle(result.Stdout)
+	}
+
+	current, err := sample()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Container %s runs on %s (%s)\n\n", containerID, instance.Name, instance.PrivateIP)
+	if !watch {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CPU %\tPeriods\tThrottled Periods\tThrottled %\tThrottled Time")
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", current.CPUPerc, current.Periods, current.Throttled,
+			throttledPercent(current.Throttled, current.Periods), current.ThrottledTime)
+		return w.Flush()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(cpuThrottleInterval)
+	defer ticker.Stop()
+
+	// Rows are printed as they come, so columns are padded by hand rather than with a tabwriter.
+	fmt.Printf("%-20s  %8s  %9s  %11s  %s\n", "Time", "CPU %", "Throttled", "Throttled %", "Throttled Time")
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		next, err := sample()
+		if err != nil {
+			return err
+		}
+		periods, throttled := next.Periods-current.Periods, next.Throttled-current.Throttled
+		fmt.Printf("%-20s  %8s  %9d  %11s  %s\n", time.Now().Format(time.RFC3339), next.CPUPerc, throttled,
+			throttledPercent(throttled, periods), next.ThrottledTime-current.ThrottledTime)
+		current = next
+	}
+}
+
+// parseCPUThrottle parses the output of cpuThrottleCommand. cgroup v1 reports
+// throttled_time in nanoseconds, v2 throttled_usec in microseconds.
+func parseCPUThrottle(output string) (cpuThrottleSample, error) {
+	stats, cpuStat, found := strings.Cut(output, "---")
+	if !found {
+		return cpuThrottleSample{}, fmt.Errorf("unexpected output %q", output)
+	}
+	var sample cpuThrottleSample
+	var docker struct {
+		CPUPerc string
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stats)), &docker); err != nil {
+		return sample, fmt.Errorf("error parsing container stats: %v", err)
+	}
+	sample.CPUPerc = docker.CPUPerc
+
+	for _, line := range strings.Split(cpuStat, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), " ")
+		if !found {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "nr_periods":
+			sample.Periods = n
+		case "nr_throttled":
+			sample.Throttled = n
+		case "throttled_time":
+			sample.ThrottledTime = time.Duration(n)
+		case "throttled_usec":
+			sample.ThrottledTime = time.Duration(n) * time.Microsecond
+		}
+	}
+	return sample, nil
+}
+
+// throttledPercent formats throttled as a share of periods, or "-" when no periods
+// elapsed, as for containers without a CPU limit.
+func throttledPercent(throttled, periods int64) string {
+	if periods == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(throttled)*100/float64(periods))
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"enum/aws"
+	"enum/ssh"
+)
+
+// curlTimeout is curl's -m, in seconds.
+const curlTimeout = 5
+
+// curlTarget selects which of a container's ports curl requests.
+type curlTarget struct {
+	PortName      string // Port mapping name in the task definition
+	ContainerPort int
+}
+
+// curlResult is one container's response.
+type curlResult struct {
+	Record   containerRecord
+	HostPort int
+	Status   string // HTTP status code, "000" when no response arrived
+	Latency  string // Seconds, as curl's time_total
+	Err      string // Why the port couldn't be resolved or the request failed
+}
+
+// OK reports whether the container answered with a 2xx status.
+func (r curlResult) OK() bool {
+	return r.Err == "" && strings.HasPrefix(r.Status, "2")
+}
+
+// curlPortInfo is what curlContainers reads from docker inspect to resolve a host port.
+type curlPortInfo struct {
+	TaskDefinition string // family:revision, from the ECS agent's labels
+	ContainerName  string
+	NetworkMode    string
+	Ports          map[string][]struct{ HostPort string } // "8080/tcp" -> bindings
+}
+
+// curlContainers requests path from every running container matching searchTerm,
+// from the container's own host against its mapped host port, and prints the status
+// and latency of each. It returns the number of containers that didn't answer with 2xx.
+func curlContainers(searchTerm, path string, target curlTarget) (int, error) {
+	if target.PortName != "" && target.ContainerPort != 0 {
+		return 0, fmt.Errorf("use either --port-name or --container-port, not both")
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	byHost := make(map[string][]containerRecord)
+	var hosts []aws.InstanceData
+	for _, record := range scanContainers(instances, false) {
+		if len(matchingTerms(record, []string{searchTerm})) == 0 {
+			continue
+		}
+		id := record.Instance.InstanceID
+		if _, ok := byHost[id]; !ok {
+			hosts = append(hosts, record.Instance)
+		}
+		byHost[id] = append(byHost[id], record)
+	}
+	if len(hosts) == 0 {
+		return 0, fmt.Errorf("no running containers match %q", searchTerm)
+	}
+
+	ports := &namedPorts{cache: make(map[string]int64)}
+	perHost := make([][]curlResult, len(hosts))
+	hostScheduler.Run(len(hosts), func(h int) {
+		instance := hosts[h]
+		results, err := curlHost(instance, byHost[instance.InstanceID], path, target, ports)
+		if err != nil {
+			log.Printf("Error checking containers on instance %s: %v", instance.Name, err)
+			return
+		}
+		perHost[h] = results
+	})
+
+	failures := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Container\tInstance\tHost Port\tStatus\tLatency (s)\t")
+	for _, results := range perHost {
+		for _, result := range results {
+			status, flag := result.Status, ""
+			if result.Err != "" {
+				status = result.Err
+			}
+			if !result.OK() {
+				flag = "!"
+				failures++
+			}
+			hostPort := "-"
+			if result.HostPort != 0 {
+				hostPort = strconv.Itoa(result.HostPort)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", result.Record.Name, result.Record.Instance.Name, hostPort, status, result.Latency, flag)
+		}
+	}
+	w.Flush()
+	if failures > 0 {
+		fmt.Printf("\n! %d containers didn't answer %s with 2xx\n", failures, path)
+	}
+	return failures, nil
+}
+
+// curlHost resolves the host port of each record on instance and requests path from
+// each over one SSH connection.
+func curlHost(instance aws.InstanceData, records []containerRecord, path string, target curlTarget, ports *namedPorts) ([]curlResult, error) {
+	conn, err := ssh.Connect(instance.PrivateIP, verbose)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var ids []string
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	inspectCmd := "sudo " + containerCLI(instance) + " inspect --format '" +
+		`{{.Id}}	{{index .Config.Labels "com.amazonaws.ecs.task-definition-family"}}:{{index .Config.Labels "com.amazonaws.ecs.task-definition-version"}}	` +
+		`{{index .Config.Labels "com.amazonaws.ecs.container-name"}}	{{.HostConfig.NetworkMode}}	{{json .NetworkSettings.Ports}}' ` + strings.Join(ids, " ")
+	output, err := conn.Run(inspectCmd)
+	if err != nil {
+		return nil, err
+	}
+	if output.ExitCode != 0 {
+		return nil, fmt.Errorf("inspect exited with status %d: %s", output.ExitCode, strings.TrimSpace(output.Stderr))
+	}
+	infos := make(map[string]curlPortInfo) // Full container ID -> ports
+	for _, line := range strings.Split(output.Stdout, "\n") {
+		parts := strings.SplitN(line, "\t", 5)
+		if len(parts) < 5 {
+			continue
+		}
+		info := curlPortInfo{TaskDefinition: parts[1], ContainerName: parts[2], NetworkMode: parts[3]}
+		_ = json.Unmarshal([]byte(parts[4]), &info.Ports) // null for containers without published ports
+		infos[parts[0]] = info
+	}
+
+	var results []curlResult
+	for _, record := range records {
+		result := curlResult{Record: record, Latency: "-"}
+		var info curlPortInfo
+		for id, candidate := range infos {
+			if strings.HasPrefix(id, record.ID) {
+				info = candidate
+			}
+		}
+		result.HostPort, err = resolveHostPort(info, target, ports)
+		if err != nil {
+			result.Err = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		url := fmt.Sprintf("http://localhost:%d%s", result.HostPort, path)
+		curl, err := conn.Run(fmt.Sprintf("curl -sS -m %d -o /dev/null -w '%%{http_code} %%{time_total}' %s", curlTimeout, ssh.ShellQuote(url)))
+		if err != nil {
+			return results, err
+		}
+		if fields := strings.Fields(curl.Stdout); len(fields) == 2 {
+			result.Status, result.Latency = fields[0], fields[1]
+		}
+		if curl.ExitCode != 0 {
+			result.Err = strings.TrimPrefix(strings.TrimSpace(curl.Stderr), "curl: ")
+			if result.Err == "" {
+				result.Err = fmt.Sprintf("curl exited with status %d", curl.ExitCode)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// resolveHostPort picks the host port serving the selected container port. Without a
+// selection, the container's only published TCP port is used.
+func resolveHostPort(info curlPortInfo, target curlTarget, ports *namedPorts) (int, error) {
+	containerPort := int64(target.ContainerPort)
+	if target.PortName != "" {
+		if info.ContainerName == "" {
+			return 0, fmt.Errorf("not an ECS task container, so --port-name can't be resolved")
+		}
+		var err error
+		if containerPort, err = ports.lookup(info.TaskDefinition, info.ContainerName, target.PortName); err != nil {
+			return 0, err
+		}
+	}
+
+	// With host networking the container listens on the host directly.
+	if info.NetworkMode == "host" {
+		if containerPort == 0 {
+			return 0, fmt.Errorf("host network mode; pick a port with --port-name or --container-port")
+		}
+		return int(containerPort), nil
+	}
+
+	var published []string
+	for port, bindings := range info.Ports {
+		if strings.HasSuffix(port, "/tcp") && len(bindings) > 0 {
+			published = append(published, port)
+		}
+	}
+	key := fmt.Sprintf("%d/tcp", containerPort)
+	if containerPort == 0 {
+		if len(published) != 1 {
+			return 0, fmt.Errorf("%d published ports; pick one with --port-name or --container-port", len(published))
+		}
+		key = published[0]
+	}
+	bindings := info.Ports[key]
+	if len(bindings) == 0 {
+		return 0, fmt.Errorf("port %s is not published", key)
+	}
+	return strconv.Atoi(bindings[0].HostPort)
+}
+
+// namedPorts caches port mapping name lookups, as every task of a service shares its
+// task definition.
+type namedPorts struct {
+	mu    sync.Mutex
+	cache map[string]int64 // "taskDefinition/container/port name" -> container port
+}
+
+func (p *namedPorts) lookup(taskDefinition, containerName, portName string) (int64, error) {
+	key := taskDefinition + "/" + containerName + "/" + portName
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if port, ok := p.cache[key]; ok {
+		return port, nil
+	}
+	port, err := aws.FetchNamedContainerPort(taskDefinition, containerName, portName, awsProfile)
+	if err != nil {
+		return 0, err
+	}
+	p.cache[key] = port
+	return port, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"enum/aws"
+)
+
+// goldenConfig is the expected state of every node, read by drift-check. Unset fields aren't checked.
+type goldenConfig struct {
+	InstanceType     string            `json:"instance_type,omitempty"`
+	ImageID          string            `json:"ami_id,omitempty"`
+	SecurityGroupIDs []string          `json:"security_group_ids,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`
+}
+
+// driftDeviation is a field of an instance that differs from the golden config.
+type driftDeviation struct {
+	InstanceID string `json:"instance_id"`
+	Name       string `json:"name"`
+	Field      string `json:"field"`
+	Expected   string `json:"expected"`
+	Actual     string `json:"actual"`
+}
+
+// driftCheck compares every running instance with the golden config at path and
+// returns the number of deviations found.
+func driftCheck(path, output string) (int, error) {
+	if err := oneOf("table", "json")(output); err != nil {
+		return 0, fmt.Errorf("unsupported output format %q: %v", output, err)
+	}
+	golden, err := loadGoldenConfig(path)
+	if err != nil {
+		return 0, err
+	}
+
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	deviations := []driftDeviation{}
+	for _, instance := range instances {
+		deviations = append(deviations, compareGolden(instance, golden)...)
+	}
+
+	if output == "json" {
+		return len(deviations), printJSON(deviations)
+	}
+	if len(deviations) == 0 {
+		fmt.Printf("All %d instances match %s.\n", len(instances), path)
+		return 0, nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Instance ID\tName\tField\tExpected\tActual")
+	for _, d := range deviations {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.InstanceID, d.Name, d.Field, d.Expected, d.Actual)
+	}
+	w.Flush()
+	return len(deviations), nil
+}
+
+// loadGoldenConfig reads a golden config file. Only JSON is supported.
+func loadGoldenConfig(path string) (goldenConfig, error) {
+	var golden goldenConfig
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return golden, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields() // Catch misspelt keys that would silently skip a check
+	if err := decoder.Decode(&golden); err != nil {
+		return golden, fmt.Errorf("unable to parse golden config %s: %v", path, err)
+	}
+	return golden, nil
+}
+
+// compareGolden returns the fields of instance that differ from golden.
+func compareGolden(instance aws.InstanceData, golden goldenConfig) []driftDeviation {
+	var deviations []driftDeviation
+	deviate := func(field, expected, actual string) {
+		if expected != actual {
+			deviations = append(deviations, driftDeviation{
+				InstanceID: instance.InstanceID,
+				Name:       instance.Name,
+				Field:      field,
+				Expected:   expected,
+				Actual:     actual,
+			})
+		}
+	}
+
+	if golden.InstanceType != "" {
+		deviate("instance_type", golden.InstanceType, instance.Type)
+	}
+	if golden.ImageID != "" {
+		deviate("ami_id", golden.ImageID, instance.ImageID)
+	}
+	if golden.SecurityGroupIDs != nil {
+		expected, actual := slices.Clone(golden.SecurityGroupIDs), slices.Clone(instance.SecurityGroupIDs)
+		sort.Strings(expected)
+		sort.Strings(actual)
+		deviate("security_group_ids", strings.Join(expected, ","), strings.Join(actual, ","))
+	}
+
+	keys := make([]string, 0, len(golden.Tags))
+	for key := range golden.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		actual, ok := instance.Tags[key]
+		if !ok {
+			actual = "(missing)"
+		}
+		deviate("tag:"+key, golden.Tags[key], actual)
+	}
+	return deviations
+}
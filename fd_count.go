@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"enum/ssh"
+)
+
+// fdCountInterval is how often --watch samples the container.
+const fdCountInterval = 5 * time.Second
+
+// fdCountCommand prints the number of open file descriptors of the container's init
+// process and its soft "Max open files" limit.
+func fdCountCommand(cli, containerID string) string {
+	return fmt.Sprintf(`pid=$(sudo %s inspect --format '{{.State.Pid}}' %s) && `+
+		`sudo ls /proc/$pid/fd | wc -l && sudo awk '/^Max open files/ {print $4}' /proc/$pid/limits`,
+		cli, ssh.ShellQuote(containerID))
+}
+
+// fdCount prints how many file descriptors a container's main process has open. With
+// watch it keeps sampling until interrupted. It returns whether any sample exceeded
+// threshold; a threshold of 0 never warns.
+func fdCount(containerID string, watch bool, threshold int) (bool, error) {
+	instance, err := locateContainer(containerID)
+	if err != nil {
+		return false, err
+	}
+	conn, err := ssh.Connect(instance.PrivateIP, verbose)
+	if err != nil {
+		return false, fmt.Errorf("error connecting to %s: %v", instance.Name, err)
+	}
+	defer conn.Close()
+
+	cmd := fdCountCommand(containerCLI(instance), containerID)
+	sample := func() (count int, limit string, err error) {
+		result, err := conn.Run(cmd)
+		if err != nil {
+			return 0, "", fmt.Errorf("error counting file descriptors on %s: %v", instance.Name, err)
+		}
+		if result.ExitCode != 0 {
+			return 0, "", fmt.Errorf("error counting file descriptors on %s: exit status %d: %s", instance.Name, result.ExitCode, strings.TrimSpace(result.Stderr))
+		}
+		fields := strings.Fields(result.Stdout)
+		if len(fields) != 2 {
+			return 0, "", fmt.Errorf("unexpected output from %s: %q", instance.Name, result.Stdout)
+		}
+		if count, err = strconv.Atoi(fields[0]); err != nil {
+			return 0, "", fmt.Errorf("unexpected descriptor count %q", fields[0])
+		}
+		return count, fields[1], nil
+	}
+
+	exceeded := false
+	report := func(count int, limit string) {
+		flag := ""
+		if threshold > 0 && count > threshold {
+			flag = fmt.Sprintf("  ! above %d", threshold)
+			exceeded = true
+		}
+		fmt.Printf("%-20s  %8d  %8s%s\n", time.Now().Format(time.RFC3339), count, limit, flag)
+	}
+
+	count, limit, err := sample()
+	if err != nil {
+		return false, err
+	}
+	fmt.Printf("Container %s runs on %s (%s)\n\n", containerID, instance.Name, instance.PrivateIP)
+	fmt.Printf("%-20s  %8s  %8s\n", "Time", "Open FDs", "Limit")
+	report(count, limit)
+	if !watch {
+		return exceeded, nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(fdCountInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return exceeded, nil
+		case <-ticker.C:
+		}
+		if count, limit, err = sample(); err != nil {
+			return exceeded, err
+		}
+		report(count, limit)
+	}
+}
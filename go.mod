@@ -6,13 +6,13 @@ require (
 	github.com/aws/aws-sdk-go v1.52.0
 	github.com/jlandowner/go-interactive-ssh v0.0.0-20240107104616-870518dfe9fb
 	github.com/spf13/cobra v1.8.0
+	github.com/spf13/pflag v1.0.5
 	golang.org/x/crypto v0.22.0
+	golang.org/x/term v0.19.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/sys v0.19.0 // indirect
-	golang.org/x/term v0.19.0 // indirect
 )
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"syscall"
+
+	"enum/aws"
+)
+
+// connectionDropped reports whether err looks like the host went away rather than a
+// problem with the command: the connection was refused or reset.
+func connectionDropped(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	// Most SSH errors are flattened to strings on their way up.
+	message := err.Error()
+	return strings.Contains(message, "connection refused") || strings.Contains(message, "connection reset")
+}
+
+// newAWSClients creates the clients classifyHostFailure re-checks instances with. Tests replace it.
+var newAWSClients = func() (aws.Clients, error) {
+	return aws.NewClients(awsProfile)
+}
+
+// classifyHostFailure explains err from a host that stopped answering during a long scan.
+// When the connection dropped and the instance turns out to be terminating or draining,
+// as after a spot interruption, the failure is reported as such instead of as an SSH error.
+func classifyHostFailure(instance aws.InstanceData, err error) error {
+	if !connectionDropped(err) {
+		return err
+	}
+	cluster := instance.Cluster
+	if cluster == "" {
+		cluster = ActiveConfig.ClusterName
+	}
+	clients, checkErr := newAWSClients()
+	if checkErr != nil {
+		log.Printf("Error re-checking instance %s: %v", instance.Name, checkErr)
+		return err
+	}
+	reason, checkErr := aws.InstanceGoingAway(clients, cluster, instance.InstanceID)
+	if checkErr != nil {
+		log.Printf("Error re-checking instance %s: %v", instance.Name, checkErr)
+		return err
+	}
+	if reason == "" {
+		return err
+	}
+	return fmt.Errorf("instance terminating (%s)", reason)
+}
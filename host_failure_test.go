@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"enum/aws"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+type stateEC2 struct {
+	ec2iface.EC2API
+	state string
+	calls int
+}
+
+func (f *stateEC2) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	f.calls++
+	return &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{
+		Instances: []*ec2.Instance{{State: &ec2.InstanceState{Name: awssdk.String(f.state)}}},
+	}}}, nil
+}
+
+type drainingECS struct {
+	ecsiface.ECSAPI
+	draining bool
+	cluster  string
+}
+
+func (f *drainingECS) ListContainerInstances(input *ecs.ListContainerInstancesInput) (*ecs.ListContainerInstancesOutput, error) {
+	f.cluster = awssdk.StringValue(input.Cluster)
+	out := &ecs.ListContainerInstancesOutput{}
+	if f.draining {
+		out.ContainerInstanceArns = awssdk.StringSlice([]string{"arn:aws:ecs:::container-instance/x"})
+	}
+	return out, nil
+}
+
+// useFakeClients makes classifyHostFailure re-check instances against the fakes.
+func useFakeClients(t *testing.T, ec2Fake ec2iface.EC2API, ecsFake ecsiface.ECSAPI) {
+	previous := newAWSClients
+	t.Cleanup(func() { newAWSClients = previous })
+	newAWSClients = func() (aws.Clients, error) {
+		return aws.Clients{EC2: ec2Fake, ECS: ecsFake}, nil
+	}
+}
+
+func TestClassifyHostFailure(t *testing.T) {
+	refused := fmt.Errorf("failed to dial SSH: dial tcp 10.0.0.5:22: connect: %w", syscall.ECONNREFUSED)
+	reset := errors.New("failed to run command 'docker ps': read tcp 10.0.0.5:22: connection reset by peer")
+	instance := aws.InstanceData{InstanceID: "i-0abc", Name: "web-1", Cluster: "prod"}
+
+	tests := []struct {
+		name     string
+		err      error
+		state    string
+		draining bool
+		want     string
+	}{
+		{name: "terminating", err: refused, state: ec2.InstanceStateNameShuttingDown, want: "instance terminating (EC2 state shutting-down)"},
+		{name: "draining", err: reset, state: ec2.InstanceStateNameRunning, draining: true, want: "instance terminating (container instance DRAINING)"},
+		{name: "healthy instance keeps the SSH error", err: refused, state: ec2.InstanceStateNameRunning, want: refused.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ecsFake := &drainingECS{draining: tt.draining}
+			useFakeClients(t, &stateEC2{state: tt.state}, ecsFake)
+			got := classifyHostFailure(instance, tt.err)
+			if got.Error() != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if tt.state == ec2.InstanceStateNameRunning && ecsFake.cluster != "prod" {
+				t.Errorf("checked cluster %q, want the instance's cluster", ecsFake.cluster)
+			}
+		})
+	}
+}
+
+func TestClassifyHostFailureOnlyRechecksDroppedConnections(t *testing.T) {
+	ec2Fake := &stateEC2{state: ec2.InstanceStateNameShuttingDown}
+	useFakeClients(t, ec2Fake, &drainingECS{})
+	authErr := errors.New("ssh: handshake failed: ssh: unable to authenticate")
+	if got := classifyHostFailure(aws.InstanceData{InstanceID: "i-0abc"}, authErr); got != authErr {
+		t.Errorf("got %v, want the original error", got)
+	}
+	if ec2Fake.calls != 0 {
+		t.Error("EC2 was asked about a failure that isn't a dropped connection")
+	}
+}
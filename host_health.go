@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// hostSkipAfter is how many consecutive hard failures take a host out of the rest of the run.
+const hostSkipAfter = 2
+
+// hardFailures are the error texts of SSH failures that retrying within the same run
+// won't fix: the host refuses our credentials or can't be routed to. Timeouts, refused
+// connections and the like are left out; they are often transient.
+var hardFailures = []string{
+	"unable to authenticate",
+	"permission denied",
+	"no route to host",
+	"host is unreachable",
+	"network is unreachable",
+	"no such host",
+}
+
+// hostHealth remembers, for the life of the process, which hosts keep failing hard, so
+// commands that contact hosts in several phases don't pay for a dead host in each phase.
+type hostHealth struct {
+	mu       sync.Mutex
+	disabled bool              // --no-skip-failed
+	failures map[string]int    // Consecutive hard failures by host
+	skipped  map[string]string // Hosts taken out of the run, with the failure that did it
+}
+
+// hostRegistry is the process-wide hostHealth used by connectProbed.
+var hostRegistry = &hostHealth{failures: make(map[string]int), skipped: make(map[string]string)}
+
+// skip returns the reason host has been taken out of the run, if it has.
+func (h *hostHealth) skip(host string) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	reason, ok := h.skipped[host]
+	return reason, ok
+}
+
+// record notes the outcome of contacting host. A success or a transient failure resets
+// the count of consecutive hard failures; the hostSkipAfter-th hard failure in a row
+// takes the host out of the run, unless skipping is disabled.
+func (h *hostHealth) record(host string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err == nil || !isHardFailure(err) {
+		delete(h.failures, host)
+		return
+	}
+	h.failures[host]++
+	if h.failures[host] >= hostSkipAfter && !h.disabled {
+		h.skipped[host] = err.Error()
+	}
+}
+
+// summary lists the hosts taken out of the run, sorted, or "" when there are none.
+func (h *hostHealth) summary() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.skipped) == 0 {
+		return ""
+	}
+	hosts := make([]string, 0, len(h.skipped))
+	for host := range h.skipped {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Skipped for the rest of the run after %d hard failures (use --no-skip-failed to keep retrying):", hostSkipAfter)
+	for _, host := range hosts {
+		fmt.Fprintf(&b, "\n  %s: %s", host, h.skipped[host])
+	}
+	return b.String()
+}
+
+// logHostSummary prints hostRegistry's summary, if any hosts were skipped.
+func logHostSummary() {
+	if summary := hostRegistry.summary(); summary != "" {
+		log.Print(summary)
+	}
+}
+
+// isHardFailure reports whether err is one of the hardFailures.
+func isHardFailure(err error) bool {
+	message := strings.ToLower(err.Error())
+	for _, failure := range hardFailures {
+		if strings.Contains(message, failure) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestHostHealth() *hostHealth {
+	return &hostHealth{failures: make(map[string]int), skipped: make(map[string]string)}
+}
+
+var (
+	errAuth      = errors.New("failed to dial SSH: ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey]")
+	errNoRoute   = errors.New("failed to dial SSH: dial tcp 10.0.0.5:22: connect: no route to host")
+	errTransient = errors.New("failed to dial SSH: dial tcp 10.0.0.5:22: i/o timeout")
+)
+
+func TestHostHealthSkipsAfterConsecutiveHardFailures(t *testing.T) {
+	h := newTestHostHealth()
+	h.record("10.0.0.5", errAuth)
+	if _, skipped := h.skip("10.0.0.5"); skipped {
+		t.Fatal("skipped after a single hard failure")
+	}
+	h.record("10.0.0.5", errNoRoute)
+	reason, skipped := h.skip("10.0.0.5")
+	if !skipped {
+		t.Fatal("not skipped after two hard failures in a row")
+	}
+	if reason != errNoRoute.Error() {
+		t.Errorf("reason = %q, want the last failure", reason)
+	}
+	if _, skipped := h.skip("10.0.0.6"); skipped {
+		t.Error("another host was skipped")
+	}
+}
+
+func TestHostHealthResets(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		reset error
+	}{
+		{name: "success", reset: nil},
+		{name: "transient failure", reset: errTransient},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHostHealth()
+			h.record("10.0.0.5", errAuth)
+			h.record("10.0.0.5", tt.reset)
+			h.record("10.0.0.5", errAuth)
+			if _, skipped := h.skip("10.0.0.5"); skipped {
+				t.Fatal("hard failures separated by a reset were counted as consecutive")
+			}
+			h.record("10.0.0.5", errAuth)
+			if _, skipped := h.skip("10.0.0.5"); !skipped {
+				t.Fatal("not skipped after two hard failures following the reset")
+			}
+		})
+	}
+}
+
+func TestHostHealthStaysSkipped(t *testing.T) {
+	h := newTestHostHealth()
+	h.record("10.0.0.5", errAuth)
+	h.record("10.0.0.5", errAuth)
+	h.record("10.0.0.5", nil)
+	if _, skipped := h.skip("10.0.0.5"); !skipped {
+		t.Error("a skipped host came back within the run")
+	}
+}
+
+func TestHostHealthDisabled(t *testing.T) {
+	h := newTestHostHealth()
+	h.disabled = true
+	for i := 0; i < 5; i++ {
+		h.record("10.0.0.5", errAuth)
+	}
+	if _, skipped := h.skip("10.0.0.5"); skipped {
+		t.Error("host skipped with --no-skip-failed")
+	}
+	if summary := h.summary(); summary != "" {
+		t.Errorf("summary = %q, want none", summary)
+	}
+}
+
+func TestHostHealthSummary(t *testing.T) {
+	h := newTestHostHealth()
+	if summary := h.summary(); summary != "" {
+		t.Errorf("summary with nothing skipped = %q", summary)
+	}
+	for _, host := range []string{"10.0.0.9", "10.0.0.5"} {
+		h.record(host, errAuth)
+		h.record(host, errAuth)
+	}
+	summary := h.summary()
+	first, second := strings.Index(summary, "10.0.0.5"), strings.Index(summary, "10.0.0.9")
+	if first == -1 || second == -1 || first > second {
+		t.Errorf("summary doesn't list both hosts in order:\n%s", summary)
+	}
+	if strings.Count(summary, "10.0.0.5") != 1 {
+		t.Errorf("host listed more than once:\n%s", summary)
+	}
+}
+
+func TestIsHardFailure(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errAuth, true},
+		{errNoRoute, true},
+		{errors.New("dial tcp: lookup ip-10-0-0-5: no such host"), true},
+		{errors.New("Permission denied (publickey)"), true},
+		{errTransient, false},
+		{errors.New("dial tcp 10.0.0.5:22: connect: connection refused"), false},
+	}
+	for _, tt := range tests {
+		if got := isHardFailure(tt.err); got != tt.want {
+			t.Errorf("isHardFailure(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"enum/aws"
+	"enum/config"
+
+	"github.com/spf13/cobra"
+)
+
+// checkIdentity confirms the AWS credentials belong to the account the environment
+// expects, so stale credentials fail fast instead of querying the wrong account.
+// Local commands never touch AWS and are skipped.
+func checkIdentity(cmd *cobra.Command, file *config.File) error {
+	if skipIdentityCheck {
+		return nil
+	}
+	if class, err := operationClass(cmd); err != nil || class == opLocal {
+		return err
+	}
+
+	envName, env, _ := file.Environment(environmentName, ActiveConfig.ClusterName)
+	if env.ExpectedAccount == "" && !verbose {
+		return nil
+	}
+
+	identity, err := aws.FetchCallerIdentity(awsProfile)
+	if err != nil {
+		return err
+	}
+	if verbose {
+		log.Printf("Authenticated to account %s as %s", identity.Account, identity.Arn)
+	}
+	if env.ExpectedAccount != "" && identity.Account != env.ExpectedAccount {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("you are authenticated to account %s but environment %s expects %s (%s)",
+			identity.Account, envName, env.ExpectedAccount, identity.Arn)
+	}
+	return nil
+}
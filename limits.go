@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"enum/aws"
+	"enum/ssh"
+)
+
+// memoryWarnPercent is the memory usage, as a percentage of the limit, above which a container is flagged.
+const memoryWarnPercent = 90
+
+// containerLimits joins a container's configured limits with a point-in-time usage sample.
+type containerLimits struct {
+	Instance          string
+	ID                string
+	Name              string
+	MemoryLimit       int64 // bytes, 0 when unlimited
+	MemoryReservation int64 // bytes
+	NanoCPUs          int64
+	CPUShares         int64
+	MemoryUsage       int64   // bytes
+	CPUPercent        float64 // as reported by docker stats, 100 per fully used CPU
+	HasStats          bool
+}
+
+// containerStats is one row of docker stats output.
+type containerStats struct {
+	CPUPercent  float64
+	MemoryUsage int64
+}
+
+// limitsReport prints limits and usage for running containers matching searchTerm.
+func limitsReport(searchTerm string) error {
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+
+	records := scanContainers(instances, false)
+	byHost := make(map[string][]containerRecord)
+	var hosts []string
+	for _, record := range records {
+		if searchTerm != "" && len(matchingTerms(record, []string{searchTerm})) == 0 {
+			continue
+		}
+		if _, ok := byHost[record.Instance.PrivateIP]; !ok {
+			hosts = append(hosts, record.Instance.PrivateIP)
+		}
+		byHost[record.Instance.PrivateIP] = append(byHost[record.Instance.PrivateIP], record)
+	}
+
+	var rows []containerLimits
+	for _, host := range hosts {
+		hostRecords := byHost[host]
+		var ids []string
+		for _, record := range hostRecords {
+			ids = append(ids, record.ID)
+		}
+
+		// One batched inspect and one batched stats sample per host.
+		cli := containerCLI(hostRecords[0].Instance)
+		inspectCmd := "sudo " + cli + " inspect --format '{{.Id}}\t{{.Name}}\t{{.HostConfig.Memory}}\t{{.HostConfig.MemoryReservation}}\t{{.HostConfig.NanoCpus}}\t{{.HostConfig.CpuShares}}' " + strings.Join(ids, " ")
+		inspectOutput, err := ssh.SSHCommand(host, inspectCmd, false)
+		if err != nil {
+			log.Printf("Error inspecting containers on instance %s: %v", hostRecords[0].Instance.Name, err)
+			continue
+		}
+		statsCmd := "sudo " + cli + " stats --no-stream --format '{{.ID}}\t{{.CPUPerc}}\t{{.MemUsage}}' " + strings.Join(ids, " ")
+		statsOutput, err := ssh.SSHCommand(host, statsCmd, false)
+		if err != nil {
+			log.Printf("Error sampling container stats on instance %s: %v", hostRecords[0].Instance.Name, err)
+		}
+
+		for _, row := range joinLimits(parseInspectLimits(inspectOutput), parseStats(statsOutput)) {
+			row.Instance = hostRecords[0].Instance.Name
+			rows = append(rows, row)
+		}
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No matching containers found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Instance\tContainer\tMemory Used\tMemory Limit\tMemory %\tCPU %\tCPU Limit\tCPU % of Limit\tFlag")
+	for _, row := range rows {
+		memLimit, memPercent := "none", "-"
+		if row.MemoryLimit > 0 {
+			memLimit = formatBytes(row.MemoryLimit)
+			if row.HasStats {
+				memPercent = fmt.Sprintf("%.1f%%", row.memoryPercent())
+			}
+		}
+		cpuLimit, cpuPercent := "none", "-"
+		if cpus := row.cpuLimit(); cpus > 0 {
+			cpuLimit = fmt.Sprintf("%.2f vCPU", cpus)
+			if row.HasStats {
+				cpuPercent = fmt.Sprintf("%.1f%%", row.CPUPercent/cpus)
+			}
+		}
+		used, cpu := "-", "-"
+		if row.HasStats {
+			used = formatBytes(row.MemoryUsage)
+			cpu = fmt.Sprintf("%.1f%%", row.CPUPercent)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			row.Instance, row.Name, used, memLimit, memPercent, cpu, cpuLimit, cpuPercent, row.flag())
+	}
+	w.Flush()
+
+	return nil
+}
+
+// memoryPercent returns memory usage as a percentage of the hard limit.
+func (c containerLimits) memoryPercent() float64 {
+	if c.MemoryLimit == 0 {
+		return 0
+	}
+	return float64(c.MemoryUsage) / float64(c.MemoryLimit) * 100
+}
+
+// cpuLimit returns the container's CPU allowance in vCPUs, preferring the hard
+// NanoCpus limit over the relative CpuShares weight.
+func (c containerLimits) cpuLimit() float64 {
+	if c.NanoCPUs > 0 {
+		return float64(c.NanoCPUs) / 1e9
+	}
+	return float64(c.CPUShares) / 1024
+}
+
+// flag explains why a container needs attention, or returns "" if it doesn't.
+func (c containerLimits) flag() string {
+	switch {
+	case c.MemoryLimit == 0:
+		return "NO MEMORY LIMIT"
+	case c.HasStats && c.memoryPercent() > memoryWarnPercent:
+		return "HIGH MEMORY"
+	}
+	return ""
+}
+
+// parseInspectLimits parses the batched docker inspect output, keyed by full container ID.
+func parseInspectLimits(output string) map[string]containerLimits {
+	limits := make(map[string]containerLimits)
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.Split(line, "\t")
+		if len(parts) < 6 {
+			continue
+		}
+		memory, _ := strconv.ParseInt(parts[2], 10, 64)
+		reservation, _ := strconv.ParseInt(parts[3], 10, 64)
+		nanoCPUs, _ := strconv.ParseInt(parts[4], 10, 64)
+		shares, _ := strconv.ParseInt(parts[5], 10, 64)
+		limits[parts[0]] = containerLimits{
+			ID:                parts[0],
+			Name:              strings.TrimPrefix(parts[1], "/"),
+			MemoryLimit:       memory,
+			MemoryReservation: reservation,
+			NanoCPUs:          nanoCPUs,
+			CPUShares:         shares,
+		}
+	}
+	return limits
+}
+
+// parseStats parses docker stats output, keyed by the short container ID docker prints.
+func parseStats(output string) map[string]containerStats {
+	stats := make(map[string]containerStats)
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.Split(line, "\t")
+		if len(parts) < 3 {
+			continue
+		}
+		cpu, err := strconv.ParseFloat(strings.TrimSuffix(parts[1], "%"), 64)
+		if err != nil {
+			continue
+		}
+		usage, err := parseSize(strings.TrimSpace(strings.SplitN(parts[2], "/", 2)[0]))
+		if err != nil {
+			continue
+		}
+		stats[parts[0]] = containerStats{CPUPercent: cpu, MemoryUsage: usage}
+	}
+	return stats
+}
+
+// joinLimits attaches the stats sample to each inspected container, matching the short stats ID against the full ID.
+func joinLimits(limits map[string]containerLimits, stats map[string]containerStats) []containerLimits {
+	var joined []containerLimits
+	for id, limit := range limits {
+		for shortID, sample := range stats {
+			if strings.HasPrefix(id, shortID) {
+				limit.CPUPercent = sample.CPUPercent
+				limit.MemoryUsage = sample.MemoryUsage
+				limit.HasStats = true
+				break
+			}
+		}
+		joined = append(joined, limit)
+	}
+
+	// Highest memory pressure first, unlimited containers last.
+	sort.Slice(joined, func(i, j int) bool {
+		if joined[i].memoryPercent() != joined[j].memoryPercent() {
+			return joined[i].memoryPercent() > joined[j].memoryPercent()
+		}
+		return joined[i].Name < joined[j].Name
+	})
+	return joined
+}
+
+// parseSize parses docker's human readable sizes such as "12.5MiB" or "1.2GB" into bytes.
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"kB", 1e3}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"B", 1},
+	}
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.suffix) {
+			value, err := strconv.ParseFloat(strings.TrimSuffix(s, unit.suffix), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid size %q", s)
+			}
+			return int64(value * unit.multiplier), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid size %q", s)
+}
+
+// formatBytes renders a byte count in binary units.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.2fGiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
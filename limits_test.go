@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJoinLimits(t *testing.T) {
+	type row struct {
+		name     string
+		hasStats bool
+		flag     string
+	}
+	tests := []struct {
+		name    string
+		inspect []string
+		stats   []string
+		want    []row
+	}{
+		{
+			name:    "over 90 percent of the limit is flagged",
+			inspect: []string{"aaaaaaaaaaaa1111\t/web\t104857600\t0\t0\t1024"},
+			stats:   []string{"aaaaaaaaaaaa\t12.5%\t95MiB / 100MiB"},
+			want:    []row{{"web", true, "HIGH MEMORY"}},
+		},
+		{
+			name:    "exactly 90 percent is not flagged",
+			inspect: []string{"aaaaaaaaaaaa1111\t/web\t104857600\t0\t0\t1024"},
+			stats:   []string{"aaaaaaaaaaaa\t1.0%\t90MiB / 100MiB"},
+			want:    []row{{"web", true, ""}},
+		},
+		{
+			name:    "no memory limit",
+			inspect: []string{"bbbbbbbbbbbb2222\t/worker\t0\t0\t0\t0"},
+			stats:   []string{"bbbbbbbbbbbb\t3.0%\t2GiB / 7.6GiB"},
+			want:    []row{{"worker", true, "NO MEMORY LIMIT"}},
+		},
+		{
+			name:    "inspected container missing from stats",
+			inspect: []string{"cccccccccccc3333\t/cron\t104857600\t0\t0\t1024"},
+			stats:   []string{"dddddddddddd\t3.0%\t99MiB / 100MiB"},
+			want:    []row{{"cron", false, ""}},
+		},
+		{
+			name:    "stats for a container that was not inspected are dropped",
+			inspect: nil,
+			stats:   []string{"eeeeeeeeeeee\t3.0%\t99MiB / 100MiB"},
+			want:    nil,
+		},
+		{
+			name: "sorted by memory pressure with unlimited last",
+			inspect: []string{
+				"bbbbbbbbbbbb2222\t/worker\t0\t0\t0\t0",
+				"aaaaaaaaaaaa1111\t/web\t104857600\t0\t0\t1024",
+				"ffffffffffff6666\t/api\t104857600\t0\t0\t1024",
+			},
+			stats: []string{
+				"aaaaaaaaaaaa\t1.0%\t50MiB / 100MiB",
+				"ffffffffffff\t1.0%\t95MiB / 100MiB",
+				"bbbbbbbbbbbb\t1.0%\t2GiB / 7.6GiB",
+			},
+			want: []row{{"api", true, "HIGH MEMORY"}, {"web", true, ""}, {"worker", true, "NO MEMORY LIMIT"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			joined := joinLimits(parseInspectLimits(strings.Join(tt.inspect, "\n")), parseStats(strings.Join(tt.stats, "\n")))
+			if len(joined) != len(tt.want) {
+				t.Fatalf("joined %d containers, want %d: %+v", len(joined), len(tt.want), joined)
+			}
+			for i, want := range tt.want {
+				got := row{joined[i].Name, joined[i].HasStats, joined[i].flag()}
+				if got != want {
+					t.Errorf("row %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
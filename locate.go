@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"enum/aws"
+	"enum/ssh"
+)
+
+// containerCacheTTL is how long locateContainer remembers which instance a container is on.
+// A container never moves between instances and a remembered instance is checked over SSH
+// before it is used, so this only bounds how stale the cached instance details get.
+const containerCacheTTL = time.Hour
+
+// sshCommand runs a command on a host for locateContainer, inspect and restart-all. Tests replace it.
+var sshCommand = ssh.SSHCommand
+
+// containerLocation is where locateContainer last found a container.
+type containerLocation struct {
+	Instance aws.InstanceData `json:"instance"`
+	FoundAt  time.Time        `json:"found_at"`
+}
+
+// containerCacheName is the cache entry mapping container IDs to instances for the cluster
+// and the AWS profile and region in use.
+func containerCacheName() string {
+	return "containers-" + awsProfile + "-" + aws.Region() + "-" + ActiveConfig.ClusterName
+}
+
+// errContainerNotFound is returned by locateContainer when no instance has the container.
+var errContainerNotFound = errors.New("not found on any instance")
+
+// locateContainer returns the instance running (or holding the stopped) container
+// with the given ID. The instance it was last found on is checked first, without asking
+// AWS; otherwise every reachable instance of the cluster is checked in turn. The
+// returned instance has its ContainerRuntime set.
+func locateContainer(containerID string) (aws.InstanceData, error) {
+	checkCmd := runtimeCommand(func(cli string) string {
+		return fmt.Sprintf("sudo %s ps -a --filter \"id=%s\" --format '{{.ID}}'", cli, containerID)
+	})
+
+	locations := make(map[string]containerLocation)
+	readCache(containerCacheName(), containerCacheTTL, &locations)
+	if location, ok := locations[containerID]; ok && time.Since(location.FoundAt) <= containerCacheTTL {
+		if runtime, found, err := containerOnHost(location.Instance, checkCmd); err == nil && found {
+			location.Instance.ContainerRuntime = runtime
+			return location.Instance, nil
+		}
+	}
+
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return aws.InstanceData{}, fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	for _, instance := range instances {
+		if instance.PrivateIP == "" {
+			continue
+		}
+		runtime, found, err := containerOnHost(instance, checkCmd)
+		if err != nil {
+			log.Printf("Error checking container on instance %s: %v", instance.InstanceID, err)
+			continue
+		}
+		if found {
+			instance.ContainerRuntime = runtime
+			rememberContainer(locations, containerID, instance)
+			return instance, nil
+		}
+	}
+	return aws.InstanceData{}, fmt.Errorf("container %s %w", containerID, errContainerNotFound)
+}
+
+// containerOnHost runs checkCmd on instance and reports whether it listed the container,
+// along with the instance's runtime.
+func containerOnHost(instance aws.InstanceData, checkCmd string) (runtime string, found bool, err error) {
+	output, err := sshCommand(instance.PrivateIP, checkCmd, false)
+	if err != nil {
+		return "", false, err
+	}
+	runtime, output = splitRuntimeOutput(output)
+	return runtime, strings.TrimSpace(output) != "", nil
+}
+
+// rememberContainer records that containerID is on instance, dropping locations older
+// than containerCacheTTL. Failing to write the cache only costs a rescan next time.
+func rememberContainer(locations map[string]containerLocation, containerID string, instance aws.InstanceData) {
+	for id, location := range locations {
+		if time.Since(location.FoundAt) > containerCacheTTL {
+			delete(locations, id)
+		}
+	}
+	locations[containerID] = containerLocation{Instance: instance, FoundAt: time.Now()}
+	if err := writeCache(containerCacheName(), ActiveConfig.ClusterName, locations); err != nil && verbose {
+		log.Printf("Error caching the location of container %s: %v", containerID, err)
+	}
+}
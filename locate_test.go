@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"enum/aws"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// stubCluster answers the ECS and EC2 calls of an instance fetch with a one-instance cluster.
+func stubCluster(r *request.Request) {
+	switch out := r.Data.(type) {
+	case *ecs.ListContainerInstancesOutput:
+		out.ContainerInstanceArns = awssdk.StringSlice([]string{"arn:aws:ecs:us-west-2:1:container-instance/prod/abc"})
+	case *ecs.DescribeContainerInstancesOutput:
+		out.ContainerInstances = []*ecs.ContainerInstance{{Ec2InstanceId: awssdk.String("i-0abc")}}
+	case *ec2.DescribeInstancesOutput:
+		out.Reservations = []*ec2.Reservation{{Instances: []*ec2.Instance{{
+			InstanceId:       awssdk.String("i-0abc"),
+			PrivateIpAddress: awssdk.String("10.0.0.2"),
+			State:            &ec2.InstanceState{Name: awssdk.String(ec2.InstanceStateNameRunning)},
+			Placement:        &ec2.Placement{AvailabilityZone: awssdk.String("us-west-2a")},
+			Tags:             []*ec2.Tag{{Key: awssdk.String("Name"), Value: awssdk.String("web-1")}},
+		}}}}
+	default:
+		r.Error = fmt.Errorf("unexpected call to %s", r.Operation.Name)
+	}
+}
+
+// ecsAndEC2Calls is how many ECS and EC2 requests have been sent so far.
+func ecsAndEC2Calls() int {
+	total := 0
+	for name, count := range aws.APICallCounts() {
+		if strings.HasPrefix(name, "ecs:") || strings.HasPrefix(name, "ec2:") {
+			total += count
+		}
+	}
+	return total
+}
+
+// setUpLocate points locateContainer at a stubbed AWS API, a fresh cache directory and
+// hosts where hostContainers lists the containers on each private IP.
+func setUpLocate(t *testing.T, hostContainers map[string]string) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("AWS_REGION", "us-west-2")
+	aws.StubAPI(stubCluster)
+	previousCluster, previousSSH := ActiveConfig.ClusterName, sshCommand
+	t.Cleanup(func() {
+		aws.StubAPI(nil)
+		ActiveConfig.ClusterName, sshCommand = previousCluster, previousSSH
+	})
+	ActiveConfig.ClusterName = "prod"
+	sshCommand = func(host, command string, verbose bool) (string, error) {
+		containers, ok := hostContainers[host]
+		if !ok {
+			return "", fmt.Errorf("unexpected SSH to %s", host)
+		}
+		return "docker\n" + containers, nil
+	}
+}
+
+func TestLocateContainerCachedWithinTTL(t *testing.T) {
+	setUpLocate(t, map[string]string{"10.0.0.2": "abc123\n"})
+
+	before := ecsAndEC2Calls()
+	instance, err := locateContainer("abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.InstanceID != "i-0abc" || instance.ContainerRuntime != "docker" {
+		t.Fatalf("located on %+v", instance)
+	}
+	first := ecsAndEC2Calls() - before
+	if first == 0 {
+		t.Fatal("the first locate made no ECS or EC2 calls; is the API stub in place?")
+	}
+
+	before = ecsAndEC2Calls()
+	instance, err = locateContainer("abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.InstanceID != "i-0abc" || instance.ContainerRuntime != "docker" {
+		t.Fatalf("second locate found %+v", instance)
+	}
+	if calls := ecsAndEC2Calls() - before; calls != 0 {
+		t.Errorf("second locate within the TTL made %d ECS/EC2 calls, want 0 (the first made %d)", calls, first)
+	}
+}
+
+func TestLocateContainerRescansWhenCachedHostLostIt(t *testing.T) {
+	hosts := map[string]string{"10.0.0.2": "abc123\n"}
+	setUpLocate(t, hosts)
+	if _, err := locateContainer("abc123"); err != nil {
+		t.Fatal(err)
+	}
+
+	hosts["10.0.0.2"] = ""
+	before := ecsAndEC2Calls()
+	if _, err := locateContainer("abc123"); err == nil {
+		t.Fatal("found a container its host no longer lists")
+	}
+	if ecsAndEC2Calls() == before {
+		t.Error("a stale cache entry was trusted without rescanning the cluster")
+	}
+}
+
+func TestLocateContainerRefreshDropsCache(t *testing.T) {
+	setUpLocate(t, map[string]string{"10.0.0.2": "abc123\n"})
+	if _, err := locateContainer("abc123"); err != nil {
+		t.Fatal(err)
+	}
+	if removed, err := invalidateCache("prod"); err != nil || removed == 0 {
+		t.Fatalf("invalidateCache removed %d entries, err %v", removed, err)
+	}
+
+	before := ecsAndEC2Calls()
+	if _, err := locateContainer("abc123"); err != nil {
+		t.Fatal(err)
+	}
+	if ecsAndEC2Calls() == before {
+		t.Error("locate after --refresh didn't rescan the cluster")
+	}
+}
+
+func TestInspectContainerLocatesFirst(t *testing.T) {
+	setUpLocate(t, map[string]string{"10.0.0.2": "abc123\n"})
+	located := sshCommand
+	var inspected []string
+	sshCommand = func(host, command string, verbose bool) (string, error) {
+		if strings.Contains(command, " inspect ") {
+			inspected = append(inspected, host+": "+command)
+			return `[{"Id": "abc123"}]`, nil
+		}
+		return located(host, command, verbose)
+	}
+
+	out := filepath.Join(t.TempDir(), "inspect.json")
+	if err := inspectContainer("abc123", out); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.2: sudo docker inspect abc123"}
+	if !reflect.DeepEqual(inspected, want) {
+		t.Errorf("inspected %q, want %q", inspected, want)
+	}
+	if data, err := os.ReadFile(out); err != nil || !strings.Contains(string(data), `"abc123"`) {
+		t.Errorf("--out file holds %q, %v", data, err)
+	}
+
+	// The second inspect finds the container through locateContainer's cache.
+	before := ecsAndEC2Calls()
+	if err := inspectContainer("abc123", out); err != nil {
+		t.Fatal(err)
+	}
+	if calls := ecsAndEC2Calls() - before; calls != 0 {
+		t.Errorf("second inspect made %d ECS/EC2 calls, want 0", calls)
+	}
+}
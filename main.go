@@ -1,15 +1,30 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"enum/aws"
+	"enum/config"
+	"enum/scheduler"
 	"enum/ssh"
+	"enum/timeparse"
+	"enum/trace"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var (
@@ -19,26 +34,129 @@ var (
 	human_readable_comand_name = "enum"
 	awsProfile                 = "default"
 	ActiveConfig               Config
+	environmentName            = os.Getenv("ENUM_ENV")
 )
 var allContainers bool = false
+var noInteractive bool
+var skipIdentityCheck bool
+var verbose bool
+var refreshCache bool
+var allClusters bool
+var tracePath string
+var skipVPCCheck bool
+var hostScheduler scheduler.Scheduler
+var maxSessions int
+var userConfig = &config.File{}
+var paletteArgs []string
+var displayOptions aws.DisplayOptions
 
 type Config struct {
 	ClusterName string
+	Region      string // --region; empty to use the environment's or the default region
 }
 
 func main() {
 	awsProfile = os.Getenv("AWS_PROFILE")
 
+	rootCmd := newRootCmd()
+	err := rootCmd.Execute()
+
+	// Run whatever the interactive palette assembled, exactly as if it had been typed.
+	if err == nil && paletteArgs != nil {
+		rootCmd.SetArgs(paletteArgs)
+		err = rootCmd.Execute()
+	}
+
+	if err != nil {
+		log.Println(err)
+		exit(1)
+	}
+	finishRun()
+}
+
+// newRootCmd builds the enum command and every subcommand under it.
+func newRootCmd() *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:   human_readable_comand_name,
 		Short: "Enumerate this and that",
 		Long:  `This is a tool to help troubleshoot ECS clusters using ec2 worker nodes.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			cmd.Help()
+			if noInteractive || !isInteractiveTerminal() {
+				cmd.Help()
+				return
+			}
+			var err error
+			if paletteArgs, err = runPalette(); err != nil {
+				log.Printf("Error: %v", err)
+			}
+		},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			runningCommand = cmd
+			if tracePath != "" {
+				trace.Enable()
+			}
+			aws.SetRegion(ActiveConfig.Region)
+
+			span := trace.Start("config load", "main")
+			var err error
+			userConfig, err = config.Load()
+			span.End()
+			if err != nil {
+				return err
+			}
+			if err := authorizeOperation(cmd, userConfig); err != nil {
+				return err
+			}
+			if err := requireReason(cmd, userConfig); err != nil {
+				return err
+			}
+			if err := resolveScheduler(cmd); err != nil {
+				return err
+			}
+			if err := useSnapshot(cmd); err != nil {
+				return err
+			}
+			if err := resolveRemoteEnv(userConfig); err != nil {
+				return err
+			}
+			if fromSnapshot != "" {
+				return applyPreferences(cmd, userConfig.Preferences)
+			}
+			span = trace.Start("identity check", "main")
+			err = checkIdentity(cmd, userConfig)
+			span.End()
+			if err != nil {
+				return err
+			}
+			if refreshCache {
+				if _, err := invalidateCache(ActiveConfig.ClusterName); err != nil {
+					return err
+				}
+				if verbose {
+					log.Println("cache invalidated")
+				}
+			}
+			return applyPreferences(cmd, userConfig.Preferences)
 		},
 	}
 
 	rootCmd.PersistentFlags().StringVarP(&ActiveConfig.ClusterName, "cluster", "c", "", "Name of the ECS cluster (required)")
+	rootCmd.PersistentFlags().StringVarP(&ActiveConfig.Region, "region", "r", "", "AWS region of the cluster (defaults to $AWS_REGION, $AWS_DEFAULT_REGION, the profile's region, then us-west-2)")
+	rootCmd.PersistentFlags().StringVar(&operatorReason, "reason", "", "Why you are running a mutating command, e.g. \"INC-1234 rolling bad config\"; shown when confirming and sent to hosts as ENUM_REASON")
+	rootCmd.PersistentFlags().StringVar(&environmentName, "env", environmentName, "Config environment to use (defaults to $ENUM_ENV or the environment listing the cluster)")
+	rootCmd.PersistentFlags().BoolVar(&skipIdentityCheck, "skip-identity-check", false, "Don't check the AWS account against the environment's expected_account")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Print extra diagnostics, such as the AWS identity in use")
+	rootCmd.PersistentFlags().BoolVar(&allClusters, "all-clusters", false, "Work across every cluster in the account instead of --cluster")
+	rootCmd.PersistentFlags().StringVar(&tracePath, "trace", "", "Write a Chrome trace-event file of the command's phases to this path")
+	rootCmd.PersistentFlags().BoolVar(&skipVPCCheck, "skip-vpc-check", false, "Don't warn when a cluster's instances span more than one VPC")
+	rootCmd.PersistentFlags().IntVar(&hostScheduler.Concurrency, "concurrency", 1, "Number of hosts to contact at once in cluster-wide operations")
+	rootCmd.PersistentFlags().IntVar(&maxSessions, "max-sessions", 2, "Most SSH sessions to run at once over one connection to a host")
+	rootCmd.PersistentFlags().DurationVar(&hostScheduler.Throttle, "throttle", 0, "Pause between starting operations on successive hosts, e.g. 500ms")
+	rootCmd.PersistentFlags().StringVar(&maxOutput, "max-output", defaultMaxOutput, "Truncate non-streaming output beyond this size, e.g. 10MiB; 0 disables")
+	rootCmd.PersistentFlags().StringVar(&fromSnapshot, "from-snapshot", "", "Read instances and containers from a file written by snapshot instead of AWS and SSH (find and list-ec2 only)")
+	rootCmd.PersistentFlags().BoolVar(&hostRegistry.disabled, "no-skip-failed", false, "Keep retrying hosts that failed hard (authentication denied, no route) instead of skipping them for the rest of the run")
+	rootCmd.PersistentFlags().BoolVar(&refreshCache, "refresh", false, "Discard the cluster's cached lookups, such as container locations, and the cached cluster list, then look them up again")
+	rootCmd.Flags().BoolVar(&noInteractive, "no-interactive", false, "Print help instead of the interactive command palette")
 
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "version",
@@ -48,310 +166,1728 @@ func main() {
 		},
 	})
 
+	var ec2Output, ec2States, ec2Out string
+	var ec2Filter ec2Filters
+
 	listEc2InstancesCmd := &cobra.Command{
-		Use:   "list-ec2",
-		Short: "List EC2 instances for a cluster",
+		Use:         "list-ec2",
+		Short:       "List EC2 instances for a cluster",
+		Annotations: map[string]string{outputFormatsAnnotation: "table,json,csv"},
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := listEC2Instances(); err != nil {
+			ec2Filter.AgentCPUSet = cmd.Flags().Changed("agent-cpu-gt")
+			ec2Filter.AgentMemSet = cmd.Flags().Changed("agent-mem-gt")
+			ec2Filter.ZombiesSet = cmd.Flags().Changed("zombies-gt")
+			if err := listEC2Instances(ec2Output, ec2States, ec2Out, ec2Filter); err != nil {
 				log.Printf("Error listing EC2 instances: %v", err)
 			}
 		},
 	}
+	listEc2InstancesCmd.Flags().StringVarP(&ec2Output, "output", "o", "table", "Output format: table, json or csv")
+	listEc2InstancesCmd.Flags().StringVar(&ec2Out, "out", "", "Write json or csv output to this file, s3://bucket/key or https:// URL instead of stdout")
+	listEc2InstancesCmd.Flags().StringVar(&ec2States, "state", "all", "Instance states to include: all, or a comma separated list such as running,stopping")
+	listEc2InstancesCmd.Flags().BoolVar(&displayOptions.ShowResources, "show-resources", false, "Show registered vCPUs and memory for each instance")
+	listEc2InstancesCmd.Flags().BoolVar(&displayOptions.ShowAttributes, "show-attributes", false, "Show custom container instance attributes as JSON")
+	listEc2InstancesCmd.Flags().BoolVar(&ec2Filter.UpdateStuck, "update-stuck", false, "Only show instances whose ECS agent update has been PENDING for over 30 minutes")
+	listEc2InstancesCmd.Flags().BoolVar(&ec2Filter.SSMActive, "ssm-active", false, "Only show instances reachable through SSM Session Manager")
+	listEc2InstancesCmd.Flags().BoolVar(&displayOptions.ShowTiming, "show-timing", false, "Show launch time, when the primary network interface attached and when the instance registered with ECS")
+	listEc2InstancesCmd.Flags().BoolVar(&displayOptions.ShowManagedDraining, "show-managed-draining", false, "Show whether each instance's capacity provider manages draining, and whether it is under way")
+	listEc2InstancesCmd.Flags().BoolVar(&ec2Filter.ManagedDrainingPending, "managed-draining-pending", false, "Only show instances that ECS managed draining is moving tasks off")
+	listEc2InstancesCmd.Flags().Float64Var(&ec2Filter.AgentCPUGT, "agent-cpu-gt", 0, "Only show instances whose ECS agent uses more than this percentage of CPU (checked over SSH)")
+	listEc2InstancesCmd.Flags().Float64Var(&ec2Filter.AgentMemGT, "agent-mem-gt", 0, "Only show instances whose ECS agent uses more than this many MB of memory (checked over SSH)")
+	listEc2InstancesCmd.Flags().BoolVar(&displayOptions.ShowCloudWatchAgent, "show-cw-agent", false, "Show whether the CloudWatch agent is running and configured on each instance (checked over SSH)")
+	listEc2InstancesCmd.Flags().BoolVar(&ec2Filter.MultiCluster, "multi-cluster-only", false, "Only show instances registered with more than one ECS cluster (checks every cluster in the account)")
+	listEc2InstancesCmd.Flags().StringVar(&ec2Filter.StorageDriver, "storage-driver-filter", "", "Only show instances whose docker storage driver is this, e.g. overlay2 (checked over SSH)")
+	listEc2InstancesCmd.Flags().BoolVar(&ec2Filter.StatusCheckFailed, "status-check-failed", false, "Only show instances whose EC2 system or instance status check isn't ok")
+	listEc2InstancesCmd.Flags().BoolVar(&displayOptions.ShowZombies, "check-zombies", false, "Show how many zombie processes each instance has (checked over SSH)")
+	listEc2InstancesCmd.Flags().IntVar(&ec2Filter.ZombiesGT, "zombies-gt", 0, "Only show instances with more than this many zombie processes (checked over SSH)")
+	listEc2InstancesCmd.Flags().BoolVar(&ec2Filter.RegistrationErrors, "has-registration-errors", false, "Only show instances whose ECS agent log has registration failures (checked over SSH)")
+	listEc2InstancesCmd.Flags().BoolVar(&ec2Filter.CgroupV1, "cgroup-v1", false, "Only show instances using the legacy cgroup v1 hierarchy (checked over SSH)")
+	listEc2InstancesCmd.Flags().BoolVar(&ec2Filter.CgroupV2, "cgroup-v2", false, "Only show instances using the unified cgroup v2 hierarchy (checked over SSH)")
+	listEc2InstancesCmd.Flags().BoolVar(&displayOptions.ShowTags, "show-tags", false, "Show every EC2 tag as key=value pairs")
+	listEc2InstancesCmd.Flags().StringSliceVar(&displayOptions.TagColumns, "tag-select", nil, "Comma separated tag keys to show as columns of their own")
+	listEc2InstancesCmd.Flags().BoolVar(&displayOptions.ShowDrainReason, "show-drain-reason", false, "Show why DRAINING container instances are draining")
 	rootCmd.AddCommand(listEc2InstancesCmd)
 
+	var ecsOutput string
+
 	listECSClusters := &cobra.Command{
-		Use:   "list-ecs",
-		Short: "List ECS clusters",
+		Use:         "list-ecs",
+		Short:       "List ECS clusters",
+		Annotations: map[string]string{outputFormatsAnnotation: "table,json"},
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := aws.ListECSClusters(awsProfile); err != nil {
+			if err := listClusters(ecsOutput); err != nil {
 				log.Printf("Error listing ECS Clusters: %v", err)
 			}
 		},
 	}
+	listECSClusters.Flags().StringVarP(&ecsOutput, "output", "o", "table", "Output format: table or json")
 	rootCmd.AddCommand(listECSClusters)
 
-	var searchTerm string
+	var groupBy, findStates, findSort, findOut string
+	var findWide bool
+	var findParallel int
 
 	findCmd := &cobra.Command{
-		Use:   "find [search-term]",
-		Short: "Find running or stopped containers by search term",
+		Use:   "find [search-term...]",
+		Short: "Find running or stopped containers by one or more search terms",
 		Run: func(cmd *cobra.Command, args []string) {
-			if len(args) == 0 {
-				find("", allContainers) // Pass the allContainers flag to the find function
-			} else {
-				searchTerm = args[0]
-				find(searchTerm, allContainers) // Pass the allContainers flag to the find function
+			if err := find(args, allContainers, groupBy, findStates, findSort, findOut, findWide); err != nil {
+				fatalf("Error finding containers: %v", err)
 			}
 		},
 	}
 	findCmd.Flags().BoolVarP(&allContainers, "all", "a", false, "Include stopped containers") // Add --all flag
+	findCmd.Flags().StringVar(&groupBy, "group-by", "", "Group results by \"term\", showing counts for every search term")
+	findCmd.Flags().StringVar(&findSort, "sort", "", "Sort by \"running-for\" (most recently started first) or \"created\" (oldest first)")
+	findCmd.Flags().BoolVar(&findWide, "wide", false, "Show each container's image and its architecture, flagging images built for another architecture than the host")
+	findCmd.Flags().StringVar(&findOut, "out", "", "Write the matching containers as JSON to this file, s3://bucket/key or https:// URL")
+	findCmd.Flags().IntVar(&findParallel, "parallel", defaultParallel, "Number of hosts to search at once (overrides --concurrency and the environment's concurrency)")
+	findCmd.Flags().StringVar(&findStates, "state", "running", "Instance states to search, e.g. running,stopping to reach containers on instances shutting down")
 	rootCmd.AddCommand(findCmd)
 
+	var inspectOut string
+
 	inspectCmd := &cobra.Command{
 		Use:   "inspect [container-id]",
 		Short: "Inspect a container by its ID",
 		Args:  cobra.ExactArgs(1), // Requires exactly one argument
 		Run: func(cmd *cobra.Command, args []string) {
 			containerID := args[0]
-			if err := inspectContainer(containerID); err != nil {
+			if err := inspectContainer(containerID, inspectOut); err != nil {
 				log.Printf("Error inspecting container %s: %v", containerID, err)
 			}
 		},
 	}
+	inspectCmd.Flags().StringVar(&inspectOut, "out", "", "Write the full inspect document to this file, s3://bucket/key or https:// URL instead of stdout")
+	addNotFoundOutputFlag(inspectCmd)
 	rootCmd.AddCommand(inspectCmd)
 
+	var logsTail string
+
 	logsCmd := &cobra.Command{
 		Use:   "logs [container-id]",
 		Short: "Follow the logs of a container by its ID",
 		Args:  cobra.ExactArgs(1), // Requires exactly one argument
 		Run: func(cmd *cobra.Command, args []string) {
 			containerID := args[0]
-			if err := followContainerLogs(containerID); err != nil {
+			if err := followContainerLogs(containerID, logsTail); err != nil {
 				log.Printf("Error following logs for container %s: %v", containerID, err)
 			}
 		},
 	}
+	logsCmd.Flags().StringVar(&logsTail, "tail", "all", "Number of lines to show from the end of the logs")
+	addNotFoundOutputFlag(logsCmd)
 	rootCmd.AddCommand(logsCmd)
 
+	var sessionLimits ssh.SessionLimits
+	var shellService string
+	var shellReplica replicaChoice
+
 	shellCmd := &cobra.Command{
 		Use:   "shell [container-id] [shell] [args...]",
 		Short: "Start an interactive shell session in a specified container with an optional shell",
-		Args:  cobra.MinimumNArgs(1), // Requires at least one argument
+		Args: func(cmd *cobra.Command, args []string) error {
+			if shellService == "" && len(args) == 0 {
+				return fmt.Errorf("requires a container ID, or --service")
+			}
+			return nil
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			containerID := args[0]
-			shellArgs := args[1:]
-			if err := shell(containerID, shellArgs); err != nil {
-				log.Fatalf("Failed to start interactive session: %v", err)
+			limits, err := resolveSessionLimits(cmd, sessionLimits)
+			if err != nil {
+				fatalf("Invalid session limits: %v", err)
+			}
+			if shellService != "" {
+				// Every argument belongs to the shell command when the service picks the container.
+				if err := serviceShell(shellService, shellReplica, args, limits); err != nil {
+					fatalf("Failed to start interactive session: %v", err)
+				}
+				return
+			}
+			if err := shell(args[0], args[1:], limits); err != nil {
+				fatalf("Failed to start interactive session: %v", err)
 			}
 		},
 	}
+	addNotFoundOutputFlag(shellCmd)
+	shellCmd.Flags().StringVar(&shellService, "service", "", "Open the shell in a running task of this ECS service instead of a container ID")
+	shellCmd.Flags().IntVar(&shellReplica.Index, "index", 0, "With --service, use the Nth running task, oldest first (default: take turns)")
+	shellCmd.Flags().BoolVar(&shellReplica.Newest, "newest", false, "With --service, use the most recently started task")
+	shellCmd.Flags().BoolVar(&shellReplica.Oldest, "oldest", false, "With --service, use the longest running task")
+	shellCmd.Flags().DurationVar(&sessionLimits.MaxSession, "max-session", 0, "Close the session after this long (0 disables)")
+	shellCmd.Flags().DurationVar(&sessionLimits.IdleTimeout, "idle-timeout", 0, "Close the session after this long without input or output (0 disables)")
 	rootCmd.AddCommand(shellCmd)
 
-	if err := rootCmd.Execute(); err != nil {
-		log.Println(err)
-		os.Exit(1)
+	var hostLimits ssh.SessionLimits
+	sshCmd := &cobra.Command{
+		Use:   "ssh [instance-id]",
+		Short: "Start an interactive login shell on a cluster instance, by instance ID or name",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			limits, err := resolveSessionLimits(cmd, hostLimits)
+			if err != nil {
+				fatalf("Invalid session limits: %v", err)
+			}
+			instance, err := findRunningInstance(args[0])
+			if err != nil {
+				fatalf("Failed to start interactive session: %v", err)
+			}
+			fmt.Printf("Connecting to instance %s (%s)...\n", instance.InstanceID, instance.Name)
+			if err := ssh.SSHInteractiveHost(instance.PrivateIP, limits); err != nil {
+				fatalf("Failed to start interactive session: %v", err)
+			}
+		},
 	}
-}
+	sshCmd.Flags().DurationVar(&hostLimits.MaxSession, "max-session", 0, "Close the session after this long (0 disables)")
+	sshCmd.Flags().DurationVar(&hostLimits.IdleTimeout, "idle-timeout", 0, "Close the session after this long without input or output (0 disables)")
+	rootCmd.AddCommand(sshCmd)
 
-func listEC2Instances() error {
-	instances, err := aws.FetchEC2InstanceData(ActiveConfig.ClusterName, awsProfile, false)
-	if err != nil {
-		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	var oomSince string
+	var oomService string
+
+	oomCmd := &cobra.Command{
+		Use:   "oom",
+		Short: "Report containers and processes killed by the OOM killer",
+		Run: func(cmd *cobra.Command, args []string) {
+			since, err := timeparse.ParseSinceUntil(oomSince, time.Now())
+			if err != nil {
+				log.Printf("Error parsing --since: %v", err)
+				exit(1)
+			}
+			count, err := oomReport(since, oomService)
+			if err != nil {
+				log.Printf("Error building OOM report: %v", err)
+				exit(1)
+			}
+			if count > 0 {
+				exit(1)
+			}
+		},
 	}
+	oomCmd.Flags().StringVar(&oomSince, "since", "24h", "Only report OOM kills since this time: "+timeparse.Formats)
+	oomCmd.Flags().StringVar(&oomService, "service", "", "Only report containers of this service (task definition family)")
+	rootCmd.AddCommand(oomCmd)
 
-	if len(instances) == 0 {
-		log.Println("No EC2 instances found for the specified cluster.")
-		return nil
+	var metricsFile string
+
+	prometheusMetricsCmd := &cobra.Command{
+		Use:   "prometheus-metrics",
+		Short: "Write cluster node metrics in the Prometheus text format",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := prometheusMetrics(metricsFile); err != nil {
+				log.Printf("Error writing Prometheus metrics: %v", err)
+			}
+		},
 	}
+	prometheusMetricsCmd.Flags().StringVar(&metricsFile, "out", "", "Write metrics to this file instead of stdout")
+	rootCmd.AddCommand(prometheusMetricsCmd)
 
-	aws.DisplayEC2Instances(instances)
-	return nil
-}
+	staleImagesCmd := &cobra.Command{
+		Use:   "stale-images",
+		Short: "Find containers running an older image than their tag points to in ECR",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := staleImages(); err != nil {
+				log.Printf("Error checking for stale images: %v", err)
+			}
+		},
+	}
+	rootCmd.AddCommand(staleImagesCmd)
 
-func find(searchTerm string, all bool) {
-	instances, err := aws.FetchEC2InstanceData(ActiveConfig.ClusterName, awsProfile, true)
-	if err != nil {
-		log.Fatalf("Error fetching instances: %v", err)
+	var inventoryGroup string
+
+	ansibleInventoryCmd := &cobra.Command{
+		Use:   "ansible-inventory",
+		Short: "Print an Ansible dynamic inventory of the cluster nodes",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := ansibleInventory(inventoryGroup); err != nil {
+				log.Printf("Error generating Ansible inventory: %v", err)
+			}
+		},
 	}
+	ansibleInventoryCmd.Flags().StringVar(&inventoryGroup, "group", "", "Inventory group name (defaults to the cluster name)")
+	rootCmd.AddCommand(ansibleInventoryCmd)
 
-	// Define column widths.
-	const (
-		instanceWidth   = 20
-		idWidth         = 12
-		statusWidth     = 12
-		runningForWidth = 15
-		nameWidth       = 60
-	)
+	var identityFile, bastion string
 
-	// Print the table header with fixed width for each column.
-	fmt.Printf("%-*s %-*s %-*s %-*s %-*s\n",
-		instanceWidth, "EC2 Instance",
-		idWidth, "Container ID",
-		statusWidth, "Status",
-		runningForWidth, "Running For",
-		nameWidth, "Container Name")
+	sshConfigCmd := &cobra.Command{
+		Use:   "ssh-config",
+		Short: "Print an ssh_config snippet for the cluster nodes",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := sshConfig(identityFile, bastion); err != nil {
+				log.Printf("Error generating ssh config: %v", err)
+			}
+		},
+	}
+	sshConfigCmd.Flags().StringVar(&identityFile, "identity-file", "", "IdentityFile to use for every host")
+	sshConfigCmd.Flags().StringVar(&bastion, "bastion", "", "Host to use as ProxyJump for every host")
+	rootCmd.AddCommand(sshConfigCmd)
 
-	for _, instance := range instances {
-		if instance.PrivateIP == "" {
-			continue // Skip if no SSH access
-		}
+	var sshConfigOut string
+	var sshConfigPrint bool
 
-		var cmd string
-		// Choose the appropriate Docker command based on the --all flag
-		if all {
-			if searchTerm == "" {
-				cmd = "sudo docker ps -a --format '{{.Names}}\t{{.ID}}\t{{.Status}}\t{{.RunningFor}}'"
-			} else {
-				cleanedSearchTerm := strings.ReplaceAll(searchTerm, " ", "")
-				cmd = fmt.Sprintf("sudo docker ps -a --format '{{.Names}}\t{{.ID}}\t{{.Status}}\t{{.RunningFor}}' | grep '%s'", cleanedSearchTerm)
-			}
-		} else {
-			if searchTerm == "" {
-				cmd = "sudo docker ps --format '{{.Names}}\t{{.ID}}\t{{.Status}}\t{{.RunningFor}}'"
-			} else {
-				cleanedSearchTerm := strings.ReplaceAll(searchTerm, " ", "")
-				cmd = fmt.Sprintf("sudo docker ps --format '{{.Names}}\t{{.ID}}\t{{.Status}}\t{{.RunningFor}}' | grep '%s'", cleanedSearchTerm)
+	generateSSHConfigCmd := &cobra.Command{
+		Use:   "generate-ssh-config",
+		Short: "Keep a block of Host entries for the cluster nodes in an ssh_config file",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := generateSSHConfig(sshConfigOut, sshConfigPrint, identityFile, bastion); err != nil {
+				log.Printf("Error generating ssh config: %v", err)
 			}
-		}
+		},
+	}
+	generateSSHConfigCmd.Flags().StringVar(&sshConfigOut, "out", "~/.ssh/enum_clusters.conf", "ssh_config file to keep the cluster's block in")
+	generateSSHConfigCmd.Flags().BoolVar(&sshConfigPrint, "print", false, "Print the Host entries instead of writing them")
+	generateSSHConfigCmd.Flags().StringVar(&identityFile, "identity-file", "", "IdentityFile to use for every host")
+	generateSSHConfigCmd.Flags().StringVar(&bastion, "bastion", "", "Host to use as ProxyJump for every host")
+	rootCmd.AddCommand(generateSSHConfigCmd)
 
-		// Execute the command and collect output
-		output, err := ssh.SSHCommand(instance.PrivateIP, cmd, false, true)
-		if err != nil {
-			log.Printf("Error executing command on instance %s: %v", instance.Name, err)
-			continue
-		}
+	var apiOutput string
 
-		// Split output by lines and format each line according to defined widths
-		for _, line := range strings.Split(output, "\n") {
-			if line != "" {
-				parts := strings.Split(line, "\t")
-				if len(parts) >= 4 { // Ensure the line has all expected fields to prevent errors
-					fmt.Printf("%-*s %-*s %-*s %-*s %-*s\n",
-						instanceWidth, instance.Name,
-						idWidth, parts[1],
-						statusWidth, parts[2],
-						runningForWidth, parts[3],
-						nameWidth, parts[0])
-				}
+	apiDescribeCmd := &cobra.Command{
+		Use:         "api-describe",
+		Short:       "Describe enum's commands and flags in a machine-readable form",
+		Annotations: map[string]string{outputFormatsAnnotation: "json"},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := apiDescribe(rootCmd, apiOutput); err != nil {
+				fatalf("Error describing commands: %v", err)
 			}
-		}
+		},
 	}
-}
+	apiDescribeCmd.Flags().StringVarP(&apiOutput, "output", "o", "json", "Output format: json")
+	rootCmd.AddCommand(apiDescribeCmd)
 
-func inspectContainer(containerID string) error {
-	// Fetch the list of EC2 instances in the cluster.
-	instances, err := aws.FetchEC2InstanceData(ActiveConfig.ClusterName, awsProfile, true)
-	if err != nil {
-		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "elb-health [instance-id]",
+		Short: "Show an instance's health in every load balancer target group it belongs to",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			statuses, err := aws.FetchELBHealthStatus(args[0], "", awsProfile)
+			if err != nil {
+				log.Printf("Error fetching target health: %v", err)
+				return
+			}
+			if len(statuses) == 0 {
+				fmt.Printf("Instance %s isn't registered with any instance target group.\n", args[0])
+				return
+			}
+			aws.DisplayELBHealthStatus(statuses)
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "nat-gateways [instance-id]",
+		Short: "List the NAT gateways of an instance's VPC",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := natGateways(args[0]); err != nil {
+				log.Printf("Error listing NAT gateways: %v", err)
+			}
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "autoscaling-config [service-name]",
+		Short: "Show a service's Application Auto Scaling capacity limits and policies",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			scaling, err := aws.FetchServiceAutoScaling(ActiveConfig.ClusterName, args[0], "", awsProfile)
+			if err != nil {
+				log.Printf("Error fetching auto scaling configuration: %v", err)
+				return
+			}
+			if scaling == nil {
+				fmt.Printf("Service %s has no Application Auto Scaling target.\n", args[0])
+				return
+			}
+			aws.DisplayServiceAutoScaling(scaling)
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "exec-config",
+		Short: "Show the cluster's ECS Exec logging configuration",
+		Run: func(cmd *cobra.Command, args []string) {
+			execConfig, err := aws.FetchECSExecConfiguration(ActiveConfig.ClusterName, awsProfile)
+			if err != nil {
+				log.Printf("Error fetching ECS Exec configuration: %v", err)
+				return
+			}
+			aws.DisplayECSExecConfiguration(execConfig)
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "diff-env [family] [rev1] [rev2]",
+		Short: "Show environment variables added, removed or changed between two task definition revisions",
+		Args:  cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			rev1, err1 := strconv.Atoi(args[1])
+			rev2, err2 := strconv.Atoi(args[2])
+			if err1 != nil || err2 != nil {
+				log.Printf("Error: revisions must be numbers, got %q and %q", args[1], args[2])
+				return
+			}
+			diffs, err := aws.DiffTaskDefEnvVars(args[0], rev1, rev2, awsProfile)
+			if err != nil {
+				log.Printf("Error comparing task definitions: %v", err)
+				return
+			}
+			if len(diffs) == 0 {
+				fmt.Printf("No environment variable changes between %s:%d and %s:%d.\n", args[0], rev1, args[0], rev2)
+				return
+			}
+			aws.DisplayEnvVarDiffs(diffs)
+		},
+	})
+
+	var attributesInstance string
+	var requiredAttributes []string
+
+	attributesCmd := &cobra.Command{
+		Use:   "attributes",
+		Short: "Show container instance attributes next to the services' placement constraints",
+		Run: func(cmd *cobra.Command, args []string) {
+			missing, err := showAttributes(attributesInstance, requiredAttributes)
+			if err != nil {
+				log.Printf("Error: %v", err)
+				exit(1)
+			}
+			if missing > 0 {
+				exit(1)
+			}
+		},
 	}
+	attributesCmd.Flags().StringVar(&attributesInstance, "instance", "", "Only show the instance with this ID or name")
+	attributesCmd.Flags().StringArrayVar(&requiredAttributes, "require", nil, "Flag instances lacking this attribute, as key or key=value (repeatable)")
+	rootCmd.AddCommand(attributesCmd)
 
-	for _, instance := range instances {
-		if instance.PrivateIP == "" {
-			continue
-		}
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "placement-failures [service-name]",
+		Short: "Summarize why ECS couldn't place a service's tasks",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			failures, err := aws.FetchPlacementFailures(ActiveConfig.ClusterName, args[0], awsProfile)
+			if err != nil {
+				log.Printf("Error fetching placement failures: %v", err)
+				return
+			}
+			if len(failures) == 0 {
+				fmt.Printf("No placement failures in the recent events of service %s.\n", args[0])
+				return
+			}
+			aws.DisplayPlacementFailures(failures)
+		},
+	})
 
-		// Check if the container is running on the instance.
-		checkCmd := fmt.Sprintf("sudo docker ps -a --filter \"id=%s\" --format '{{.ID}}'", containerID)
-		checkOutput, err := ssh.SSHCommand(instance.PrivateIP, checkCmd, false, false)
-		if err != nil {
-			log.Printf("Error checking container on instance %s: %v", instance.InstanceID, err)
-			continue
-		}
-		if checkOutput == "" {
-			continue // No container with the specified ID was found on this host.
-		}
+	rootCmd.AddCommand(newStoppedTasksCmd("stopped-tasks", "List recently stopped ECS tasks, why they stopped, their exit codes and the instance they ran on", "", 10))
+	rootCmd.AddCommand(newStoppedTasksCmd("stopped", "Show why tasks stopped recently, with exit codes and the instance they ran on", "2h", 0))
 
-		// If the container ID matches the expected ID, inspect it.
-		inspectCmd := fmt.Sprintf("sudo docker inspect %s", containerID)
-		inspectOutput, err := ssh.SSHCommand(instance.PrivateIP, inspectCmd, false, false)
-		if err != nil {
-			log.Printf("Error executing inspect on instance %s: %v", instance.InstanceID, err)
-			continue
-		}
+	var appendTo string
 
-		if inspectOutput != "" {
-			fmt.Printf("---------- Inspect output from %s ----------\n", instance.Name)
-			fmt.Println(inspectOutput)
-			return nil // Stop after successful inspection, as only one such container should exist.
-		}
+	hostsFileCmd := &cobra.Command{
+		Use:   "hosts-file",
+		Short: "Print an /etc/hosts fragment for the cluster nodes",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := hostsFile(appendTo); err != nil {
+				log.Printf("Error generating hosts file: %v", err)
+			}
+		},
 	}
+	hostsFileCmd.Flags().StringVar(&appendTo, "append-to", "", "Write the entries into a delimited block of this hosts file instead of stdout, replacing the cluster's previous block")
+	rootCmd.AddCommand(hostsFileCmd)
 
-	fmt.Println("Container not found on any instance.")
-	return nil
-}
+	var terraformFormat string
 
-func followContainerLogs(containerID string) error {
-	// Fetch the list of EC2 instances in the cluster.
-	instances, err := aws.FetchEC2InstanceData(ActiveConfig.ClusterName, awsProfile, true)
-	if err != nil {
-		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	terraformImportCmd := &cobra.Command{
+		Use:   "terraform-import",
+		Short: "Print terraform import commands or HCL data sources for the cluster nodes",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := terraformImport(terraformFormat); err != nil {
+				log.Printf("Error generating Terraform output: %v", err)
+			}
+		},
 	}
+	terraformImportCmd.Flags().StringVar(&terraformFormat, "format", "import", "Output format: import (terraform import commands) or hcl (aws_instance data sources)")
+	rootCmd.AddCommand(terraformImportCmd)
 
-	found := false
-	for _, instance := range instances {
-		if instance.PrivateIP == "" {
-			continue
-		}
+	limitsCmd := &cobra.Command{
+		Use:   "limits [search-term]",
+		Short: "Compare container CPU and memory limits with current usage",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			searchTerm := ""
+			if len(args) == 1 {
+				searchTerm = args[0]
+			}
+			if err := limitsReport(searchTerm); err != nil {
+				log.Printf("Error reporting container limits: %v", err)
+			}
+		},
+	}
+	rootCmd.AddCommand(limitsCmd)
 
-		// Check if the container is running on the instance.
-		checkCmd := fmt.Sprintf("sudo docker ps -a --filter \"id=%s\" --format '{{.ID}}'", containerID)
-		checkOutput, err := ssh.SSHCommand(instance.PrivateIP, checkCmd, false, false)
-		if err != nil {
-			log.Printf("Error checking container on instance %s: %v", instance.InstanceID, err)
-			continue
-		}
-		if checkOutput == "" {
-			continue // No container with the specified ID was found on this host.
-		}
+	var eventHours int
 
-		// If the container ID matches the expected ID, follow its logs.
-		logCmd := fmt.Sprintf("sudo docker logs -f %s", containerID)
-		fmt.Printf("Attempting to follow logs on instance %s (%s)\n", instance.InstanceID, instance.Name)
-		// Execute SSH command to follow logs, streaming directly to console
-		logErr := ssh.SSHCommandStream(instance.PrivateIP, logCmd)
-		if logErr != nil {
-			log.Printf("Error executing command on instance %s: %v", instance.InstanceID, logErr)
-			continue
-		}
-		found = true
-		break
+	instanceEventsCmd := &cobra.Command{
+		Use:   "instance-events [instance-id]",
+		Short: "Show recent CloudTrail API activity for an EC2 instance",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			events, err := aws.FetchRecentAPIEvents(args[0], "", awsProfile, eventHours)
+			if err != nil {
+				log.Printf("Error fetching CloudTrail events: %v", err)
+				return
+			}
+			if len(events) == 0 {
+				fmt.Printf("No API events found for %s in the last %d hours.\n", args[0], eventHours)
+				return
+			}
+			aws.DisplayCloudTrailEvents(events)
+		},
 	}
+	instanceEventsCmd.Flags().IntVar(&eventHours, "hours", 24, "How many hours back to look")
+	rootCmd.AddCommand(instanceEventsCmd)
+
+	var amiTarget, amiOutput string
+	var amiWatch bool
 
-	if !found {
-		fmt.Println("Container not found on any instance or unable to connect.")
+	amiRolloutCmd := &cobra.Command{
+		Use:         "ami-rollout",
+		Short:       "Show which AMIs the cluster's instances run and how far a rollout has got",
+		Annotations: map[string]string{outputFormatsAnnotation: "table,json"},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := amiRolloutReport(amiTarget, amiOutput, amiWatch); err != nil {
+				log.Printf("Error: %v", err)
+			}
+		},
 	}
+	amiRolloutCmd.Flags().StringVar(&amiTarget, "target", "", "AMI ID the cluster is rolling out to")
+	amiRolloutCmd.Flags().StringVarP(&amiOutput, "output", "o", "table", "Output format: table or json")
+	amiRolloutCmd.Flags().BoolVar(&amiWatch, "watch", false, "Refresh every 30 seconds until every instance is on --target")
+	rootCmd.AddCommand(amiRolloutCmd)
 
-	return nil
-}
+	var goldenPath, driftOutput string
 
-func shell(containerID string, args []string) error {
-	// Fetch EC2 instances for the specified cluster
-	instances, err := aws.FetchEC2InstanceData(ActiveConfig.ClusterName, awsProfile, true)
-	if err != nil {
-		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	driftCheckCmd := &cobra.Command{
+		Use:         "drift-check",
+		Short:       "Report instances that differ from a golden config of type, AMI, security groups and tags",
+		Annotations: map[string]string{outputFormatsAnnotation: "table,json"},
+		Run: func(cmd *cobra.Command, args []string) {
+			deviations, err := driftCheck(goldenPath, driftOutput)
+			if err != nil {
+				log.Printf("Error: %v", err)
+				exit(1)
+			}
+			if deviations > 0 {
+				exit(1)
+			}
+		},
 	}
+	driftCheckCmd.Flags().StringVar(&goldenPath, "golden", "golden.json", "Path to the golden config JSON file")
+	driftCheckCmd.Flags().StringVarP(&driftOutput, "output", "o", "table", "Output format: table or json")
+	rootCmd.AddCommand(driftCheckCmd)
 
-	// Set default shell if no arguments are provided
-	var fullCommand string
-	if len(args) == 0 {
-		fullCommand = "/bin/sh"
-	} else {
-		fullCommand = strings.Join(args, " ")
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "trust-hosts",
+		Short: "Record the cluster's SSH host keys, verified from the EC2 console output where possible",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := trustHosts(); err != nil {
+				log.Printf("Error: %v", err)
+			}
+		},
+	})
+
+	var notifyContainer, notifyUntil, notifyExec string
+	var notifyInterval, notifyTimeout time.Duration
+
+	notifyCmd := &cobra.Command{
+		Use:   "notify",
+		Short: "Wait until a container is gone, healthy, unhealthy or restarted, then alert",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := notify(notifyContainer, notifyUntil, notifyInterval, notifyTimeout, notifyExec); err != nil {
+				log.Printf("Error: %v", err)
+				exit(1)
+			}
+		},
 	}
+	notifyCmd.Flags().StringVar(&notifyContainer, "container", "", "ID of the container to watch")
+	notifyCmd.Flags().StringVar(&notifyUntil, "until", untilGone, "Condition to wait for: gone, healthy, unhealthy or restarted")
+	notifyCmd.Flags().DurationVar(&notifyInterval, "interval", 5*time.Second, "How often to poll the container's host")
+	notifyCmd.Flags().DurationVar(&notifyTimeout, "timeout", 0, "Give up and exit non-zero after this long (0 waits indefinitely)")
+	notifyCmd.Flags().StringVar(&notifyExec, "exec", "", "Shell command to run locally once the condition is met")
+	rootCmd.AddCommand(notifyCmd)
 
-	// Flag to indicate if the container was found
-	found := false
+	var actionParams []string
+	var actionYes bool
 
-	// Loop through each EC2 instance
-	for _, instance := range instances {
-		if instance.PrivateIP == "" {
-			continue
-		}
+	actionCmd := &cobra.Command{
+		Use:   "action [name] [target]",
+		Short: "Run a remediation action from the config file on a host or container",
+		Long:  "Run a remediation action defined under \"actions\" in the config file. The target is an instance ID or name for host actions and a container ID for container actions. Without arguments, list the configured actions.",
+		Args:  cobra.MaximumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				listActions()
+				return
+			}
+			if len(args) != 2 {
+				log.Printf("Error: action %s needs a target", args[0])
+				exit(1)
+			}
+			if err := runAction(args[0], actionParams, args[1], actionYes); err != nil {
+				log.Printf("Error: %v", err)
+				exit(1)
+			}
+		},
+	}
+	actionCmd.Flags().StringArrayVar(&actionParams, "param", nil, "Action parameter as key=value (repeatable)")
+	actionCmd.Flags().BoolVarP(&actionYes, "yes", "y", false, "Don't ask for confirmation")
+	rootCmd.AddCommand(actionCmd)
 
-		// SSH command to search for the container
-		checkCmd := fmt.Sprintf("sudo docker ps --filter \"id=%s\" --format '{{.ID}}'", containerID)
-		output, err := ssh.SSHCommand(instance.PrivateIP, checkCmd, false, false)
-		if err != nil {
-			log.Printf("Error executing command on instance %s: %v", instance.InstanceID, err)
-			continue
-		}
+	var syslogFilter, syslogGrep string
+	var syslogLines int
+	var syslogFollow bool
 
-		// If the container is found on this instance, start an interactive shell session
-		if output != "" {
-			fmt.Printf("Container %s found on instance %s (%s). Starting shell session...\n", containerID, instance.InstanceID, instance.Name)
-			err := ssh.SSHInteractiveShell(instance.PrivateIP, containerID, fullCommand)
+	syslogCmd := &cobra.Command{
+		Use:   "syslog",
+		Short: "Stream the system log of several instances at once, prefixed with the instance name",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := syslog(syslogFilter, syslogGrep, syslogLines, syslogFollow); err != nil {
+				log.Printf("Error: %v", err)
+			}
+		},
+	}
+	syslogCmd.Flags().StringVar(&syslogFilter, "filter", "", "Only include instances whose name contains this")
+	syslogCmd.Flags().StringVar(&syslogGrep, "grep", "", "Only show lines matching this regular expression, e.g. 'kernel|ecs'")
+	syslogCmd.Flags().IntVarP(&syslogLines, "lines", "n", 20, "Number of earlier lines to show from each instance")
+	syslogCmd.Flags().BoolVarP(&syslogFollow, "follow", "f", false, "Keep streaming new lines, reconnecting to hosts that drop")
+	rootCmd.AddCommand(syslogCmd)
+
+	var pushOpts pushOptions
+	var pushCleanupOnly bool
+	var pushParallel int
+
+	pushCmd := &cobra.Command{
+		Use:   "push <local-file> <remote-path>",
+		Short: "Copy a file to every running instance, verifying its checksum",
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			switch {
+			case pushCleanupOnly:
+				err = pushCleanup(ActiveConfig.ClusterName)
+			case len(args) != 2:
+				err = fmt.Errorf("push needs a local file and a remote path")
+			default:
+				err = push(args[0], args[1], pushOpts)
+			}
 			if err != nil {
-				log.Printf("Error starting interactive shell session: %v", err)
-				continue
+				log.Printf("Error: %v", err)
+				exit(1)
 			}
-			found = true
-			break
-		}
+		},
 	}
+	pushCmd.Flags().StringVar(&pushOpts.Mode, "mode", "0644", "Octal permissions for the remote file")
+	pushCmd.Flags().StringVar(&pushOpts.Filter, "filter", "", "Only push to instances whose name contains this")
+	pushCmd.Flags().BoolVar(&pushOpts.AllowAnyPath, "allow-any-path", false, "Allow remote paths outside /tmp and /home")
+	pushCmd.Flags().DurationVar(&pushOpts.CleanupAfter, "cleanup-after", 0, "Schedule removal with at after this long, e.g. 1h, and record the file for --cleanup")
+	pushCmd.Flags().BoolVar(&pushCleanupOnly, "cleanup", false, "Remove the files recorded by earlier pushes with --cleanup-after")
+	pushCmd.Flags().IntVar(&pushParallel, "parallel", defaultParallel, "Number of hosts to copy to at once (overrides --concurrency and the environment's concurrency)")
+	rootCmd.AddCommand(pushCmd)
 
-	if !found {
-		fmt.Println("Container not found on any instance or unable to connect.")
+	var expectVersions string
+	var versionsParallel int
+
+	versionsCmd := &cobra.Command{
+		Use:   "versions",
+		Short: "Show the spread of docker, containerd and ECS agent versions across the cluster",
+		Run: func(cmd *cobra.Command, args []string) {
+			deviating, err := fleetVersions(expectVersions)
+			if err != nil {
+				log.Printf("Error collecting versions: %v", err)
+				exit(1)
+			}
+			if deviating > 0 {
+				exit(1)
+			}
+		},
 	}
+	versionsCmd.Flags().StringVar(&expectVersions, "expect", "", "Exit non-zero when any instance runs other versions, e.g. docker=24.0.7,agent=1.79.0")
+	versionsCmd.Flags().IntVar(&versionsParallel, "parallel", defaultParallel, "Number of hosts to read at once (overrides --concurrency and the environment's concurrency)")
+	rootCmd.AddCommand(versionsCmd)
 
+	var minDockerVersion string
+	checkDockerVersionCmd := &cobra.Command{
+		Use:   "check-docker-version",
+		Short: "Check every instance runs at least a minimum docker daemon version",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDockerVersion(minDockerVersion); err != nil {
+				log.Printf("Error checking docker versions: %v", err)
+			}
+		},
+	}
+	checkDockerVersionCmd.Flags().StringVar(&minDockerVersion, "min-version", "20.10", "Minimum docker daemon version, e.g. 20.10")
+	rootCmd.AddCommand(checkDockerVersionCmd)
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "check-networking [container-id]",
+		Short: "Report iptables DROP rules and bridge options on the host running a container",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkNetworking(args[0]); err != nil {
+				log.Printf("Error checking networking: %v", err)
+			}
+		},
+	})
+
+	var catGrep string
+	var catSum bool
+
+	catCmd := &cobra.Command{
+		Use:   "cat [search-term] [path-in-container]",
+		Short: "Print a file from inside every matching container, or compare its checksums",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := catFiles(args[0], args[1], catGrep, catSum); err != nil {
+				log.Printf("Error reading files: %v", err)
+			}
+		},
+	}
+	catCmd.Flags().StringVar(&catGrep, "grep", "", "Only print lines matching this regular expression")
+	catCmd.Flags().BoolVar(&catSum, "sha256", false, "Print only each file's SHA-256, flagging containers whose copy differs")
+	rootCmd.AddCommand(catCmd)
+
+	var curlPortName string
+	var curlContainerPort int
+	var curlFailOnError bool
+
+	curlCmd := &cobra.Command{
+		Use:   "curl [search-term] [path]",
+		Short: "Request an HTTP path, such as /healthz, from every matching container through its host",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			failures, err := curlContainers(args[0], args[1], curlTarget{PortName: curlPortName, ContainerPort: curlContainerPort})
+			if err != nil {
+				log.Printf("Error checking containers: %v", err)
+				exit(1)
+			}
+			if failures > 0 && curlFailOnError {
+				exit(1)
+			}
+		},
+	}
+	curlCmd.Flags().StringVar(&curlPortName, "port-name", "", "Port mapping name from the task definition, e.g. http")
+	curlCmd.Flags().IntVar(&curlContainerPort, "container-port", 0, "Container port to request (default: the container's only published port)")
+	curlCmd.Flags().BoolVar(&curlFailOnError, "fail-on-error", false, "Exit non-zero when any container doesn't answer with 2xx")
+	rootCmd.AddCommand(curlCmd)
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "snapshot [file]",
+		Short: "Save the cluster's instances and containers to a file for use with --from-snapshot",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := writeSnapshot(args[0]); err != nil {
+				log.Printf("Error writing snapshot: %v", err)
+			}
+		},
+	})
+
+	var fdCountWatch bool
+	var fdCountThreshold int
+
+	fdCountCmd := &cobra.Command{
+		Use:   "fd-count [container-id]",
+		Short: "Show how many file descriptors a container's main process has open",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			exceeded, err := fdCount(args[0], fdCountWatch, fdCountThreshold)
+			if err != nil {
+				log.Printf("Error counting file descriptors: %v", err)
+				exit(1)
+			}
+			if exceeded {
+				exit(1)
+			}
+		},
+	}
+	fdCountCmd.Flags().BoolVar(&fdCountWatch, "watch", false, "Sample every 5 seconds until interrupted")
+	fdCountCmd.Flags().IntVar(&fdCountThreshold, "warn-threshold", 0, "Flag samples with more open descriptors than this and exit non-zero (0 disables)")
+	rootCmd.AddCommand(fdCountCmd)
+
+	var cpuThrottleWatch bool
+
+	cpuThrottleCmd := &cobra.Command{
+		Use:   "cpu-throttle [container-id]",
+		Short: "Show a container's CPU usage and how often its CPU limit throttles it",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cpuThrottle(args[0], cpuThrottleWatch); err != nil {
+				log.Printf("Error reading CPU throttling: %v", err)
+			}
+		},
+	}
+	cpuThrottleCmd.Flags().BoolVar(&cpuThrottleWatch, "watch", false, "Sample every 5 seconds until interrupted, showing throttling since the previous sample")
+	rootCmd.AddCommand(cpuThrottleCmd)
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "docker-plugins [instance-id]",
+		Short: "List the docker plugins, such as volume drivers, installed on an instance",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := dockerPlugins(args[0]); err != nil {
+				log.Printf("Error listing docker plugins: %v", err)
+			}
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "docker-networks [instance-id]",
+		Short: "List the docker networks on an instance with their subnets",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := dockerNetworks(args[0]); err != nil {
+				log.Printf("Error listing docker networks: %v", err)
+			}
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "whois [container-id]",
+		Short: "Show the ECS task and service that own a container",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := whois(args[0]); err != nil {
+				log.Printf("Error: %v", err)
+			}
+		},
+	})
+
+	var restartBatch int
+	var waitHealthy, minUp time.Duration
+	var assumeYes, protect bool
+	var protectWindow time.Duration
+
+	restartAllCmd := &cobra.Command{
+		Use:   "restart-all [search-term]",
+		Short: "Restart every matching container in rolling batches",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			window := time.Duration(0)
+			if protect {
+				window = protectWindow
+			}
+			if err := restartAll(args[0], restartBatch, waitHealthy, minUp, window, assumeYes); err != nil {
+				log.Println(err)
+				exit(1)
+			}
+		},
+	}
+	restartAllCmd.Flags().IntVar(&restartBatch, "batch", 1, "Number of containers to restart at a time")
+	restartAllCmd.Flags().DurationVar(&waitHealthy, "wait-healthy", 60*time.Second, "How long to wait for each batch to become healthy before aborting")
+	restartAllCmd.Flags().DurationVar(&minUp, "min-up", 10*time.Second, "How long a container without a healthcheck must stay up to count as healthy")
+	restartAllCmd.Flags().BoolVar(&protect, "protect", false, "Protect each batch's ECS service tasks from scale-in while their containers restart")
+	restartAllCmd.Flags().DurationVar(&protectWindow, "protect-window", 10*time.Minute, "How long --protect holds protection if enum can't release it")
+	restartAllCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask for confirmation")
+	rootCmd.AddCommand(restartAllCmd)
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "capacity-metrics",
+		Short: "Show capacity provider reservation against the managed scaling target",
+		Run: func(cmd *cobra.Command, args []string) {
+			metrics, err := aws.FetchCapacityProviderMetrics(ActiveConfig.ClusterName, awsProfile)
+			if err != nil {
+				log.Printf("Error fetching capacity provider metrics: %v", err)
+				return
+			}
+			if len(metrics) == 0 {
+				fmt.Println("No capacity providers are attached to this cluster.")
+				return
+			}
+			aws.DisplayCapacityProviderMetrics(metrics)
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "scaling-status",
+		Short: "Show the managed scaling settings of the cluster's capacity providers",
+		Run: func(cmd *cobra.Command, args []string) {
+			providers, err := aws.FetchCapacityProviders(ActiveConfig.ClusterName, awsProfile)
+			if err != nil {
+				log.Printf("Error fetching capacity providers: %v", err)
+				return
+			}
+			if len(providers) == 0 {
+				fmt.Println("No capacity providers are attached to this cluster.")
+				return
+			}
+			aws.DisplayCapacityProviders(providers)
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "task-fit [task-definition]",
+		Short: "Show which container instances have room for one more copy of a task definition",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			results, err := aws.ValidateTaskFit(ActiveConfig.ClusterName, args[0], awsProfile)
+			if err != nil {
+				log.Printf("Error checking task fit: %v", err)
+				return
+			}
+			if len(results) == 0 {
+				fmt.Println("No container instances are registered with this cluster.")
+				return
+			}
+			aws.DisplayTaskFit(results)
+			fits := 0
+			for _, result := range results {
+				if result.Fits {
+					fits++
+				}
+			}
+			fmt.Printf("\n%d of %d instances can fit %s.\n", fits, len(results), args[0])
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "memory-report",
+		Short: "Show the cluster's registered, allocated and free memory",
+		Run: func(cmd *cobra.Command, args []string) {
+			instances, err := fetchInstances(aws.RunningStates)
+			if err != nil {
+				log.Printf("Error fetching EC2 instance data: %v", err)
+				return
+			}
+			summary := aws.SummarizeMemory(instances)
+			if summary.Instances == 0 {
+				fmt.Println("No container instances have registered memory with this cluster.")
+				return
+			}
+			aws.DisplayMemorySummary(summary)
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "sg-rules [instance-id]",
+		Short: "Show the security group rules that allow SSH to an instance",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			rules, err := aws.FetchSSHSecurityGroupRules(args[0], "", awsProfile)
+			if err != nil {
+				log.Printf("Error fetching security group rules: %v", err)
+				return
+			}
+			if len(rules) == 0 {
+				fmt.Printf("No security group rules allow SSH to %s.\n", args[0])
+				return
+			}
+			aws.DisplaySGRules(rules)
+		},
+	})
+
+	var asgName string
+
+	asgHistoryCmd := &cobra.Command{
+		Use:   "asg-history [instance-id]",
+		Short: "Show the auto scaling activities that launched or replaced an instance",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			group := asgName
+			if group == "" {
+				var err error
+				if group, err = aws.FindAutoScalingGroup(args[0], "", awsProfile); err != nil {
+					log.Printf("Error finding auto scaling group: %v (pass --asg for terminated instances)", err)
+					return
+				}
+			}
+			events, err := aws.FetchASGReplacementHistory(args[0], group, "", awsProfile)
+			if err != nil {
+				log.Printf("Error fetching scaling activities: %v", err)
+				return
+			}
+			if len(events) == 0 {
+				fmt.Printf("No scaling activities in %s mention %s.\n", group, args[0])
+				return
+			}
+			aws.DisplayASGActivityEvents(events)
+		},
+	}
+	asgHistoryCmd.Flags().StringVar(&asgName, "asg", "", "Auto scaling group name (looked up from the instance when omitted)")
+	rootCmd.AddCommand(asgHistoryCmd)
+
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show or change enum's configuration",
+	}
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "set [key] [value]",
+		Short: "Set a preference used as the default for the matching flag",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := setPreference(args[0], args[1]); err != nil {
+				fatalf("Error setting %s: %v", args[0], err)
+			}
+		},
+	})
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "Show the configuration and where each preference comes from",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := showConfig(rootCmd); err != nil {
+				fatalf("Error showing config: %v", err)
+			}
+		},
+	})
+	rootCmd.AddCommand(configCmd)
+
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect enum's on-disk cache",
+	}
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show what is cached, for which cluster, and how old each entry is",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cacheStatus(os.Stdout); err != nil {
+				fatalf("Error reading cache: %v", err)
+			}
+		},
+	})
+	rootCmd.AddCommand(cacheCmd)
+
+	return rootCmd
+}
+
+// finishRun prints the end-of-run summaries and writes the trace file. Commands that
+// exit early go through exit or fatalf so these still happen.
+func finishRun() {
+	logHostSummary()
+	if summary := aws.APICallSummary(); verbose && summary != "" {
+		log.Printf("AWS API calls: %s", summary)
+	}
+	if err := trace.WriteFile(tracePath); err != nil {
+		log.Printf("Error writing trace: %v", err)
+	}
+}
+
+// exit finishes the run and exits with code, for commands whose exit status reports a result.
+func exit(code int) {
+	finishRun()
+	os.Exit(code)
+}
+
+// fatalf is log.Fatalf that still finishes the run.
+func fatalf(format string, v ...any) {
+	log.Printf(format, v...)
+	exit(1)
+}
+
+// fetchInstances fetches the active cluster's instances in the given states. Warnings that come with
+// valid results, such as duplicate private IPs, have already been logged and are
+// not treated as failures. With --all-clusters every cluster in the account is fetched,
+// and with --from-snapshot the instances come from the snapshot file instead.
+func fetchInstances(states aws.InstanceStates) ([]aws.InstanceData, error) {
+	defer trace.Start("instance fetch", "main").End()
+	return source.Instances(states)
+}
+
+// Instances fetches the cluster's instances from AWS, or every cluster's with --all-clusters.
+func (liveSource) Instances(states aws.InstanceStates) ([]aws.InstanceData, error) {
+	if allClusters {
+		return fetchAllClusterInstances(states)
+	}
+	instances, err := aws.FetchEC2InstanceDataInStates(ActiveConfig.ClusterName, awsProfile, states)
+	if err != nil && !aws.IsWarning(err) {
+		return nil, err
+	}
+	logVPCWarning(err)
+	return instances, nil
+}
+
+// logVPCWarning prints the multiple VPC warning carried by err unless --skip-vpc-check is set.
+func logVPCWarning(err error) {
+	var multipleVPC *aws.MultipleVPCError
+	if errors.As(err, &multipleVPC) && !skipVPCCheck {
+		log.Printf("Warning: %v (use --skip-vpc-check if this is intentional)", multipleVPC)
+	}
+}
+
+// ec2Filters are the list-ec2 flags that narrow which instances are listed.
+type ec2Filters struct {
+	UpdateStuck            bool
+	SSMActive              bool
+	ManagedDrainingPending bool
+	AgentCPUGT, AgentMemGT float64
+	AgentCPUSet            bool // --agent-cpu-gt was given
+	AgentMemSet            bool // --agent-mem-gt was given
+	MultiCluster           bool
+	StorageDriver          string
+	StatusCheckFailed      bool
+	ZombiesGT              int
+	ZombiesSet             bool // --zombies-gt was given
+	RegistrationErrors     bool
+	CgroupV1, CgroupV2     bool
+}
+
+func listEC2Instances(output, stateList, out string, filter ec2Filters) error {
+	if err := oneOf("table", "json", "csv")(output); err != nil {
+		return fmt.Errorf("unsupported output format %q: %v", output, err)
+	}
+	if out != "" && output == "table" {
+		return fmt.Errorf("--out needs -o json or -o csv")
+	}
+	if filter.CgroupV1 && filter.CgroupV2 {
+		return fmt.Errorf("--cgroup-v1 and --cgroup-v2 can't be combined")
+	}
+	states, err := aws.ParseInstanceStates(stateList)
+	if err != nil {
+		return err
+	}
+
+	instances, err := fetchInstances(states)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	if filter.SSMActive {
+		if err := aws.PopulateSSMStatus(instances, awsProfile); err != nil {
+			return err
+		}
+	}
+	if filter.AgentCPUSet || filter.AgentMemSet {
+		aws.PopulateAgentUsage(instances, ssh.SSHOptions{Verbose: verbose, Scheduler: hostScheduler})
+		displayOptions.ShowAgentUsage = true
+	}
+	if filter.MultiCluster {
+		if err := aws.PopulateClusterMemberships(instances, awsProfile); err != nil {
+			return err
+		}
+		displayOptions.ShowClusters = true
+	}
+	if filter.StatusCheckFailed {
+		if err := aws.PopulateStatusChecks(instances, awsProfile); err != nil {
+			return err
+		}
+		displayOptions.ShowStatusChecks = true
+	}
+	if filter.StorageDriver != "" {
+		aws.PopulateStorageDriver(instances, ssh.SSHOptions{Verbose: verbose, Scheduler: hostScheduler})
+		displayOptions.ShowStorageDriver = true
+	}
+	if filter.CgroupV1 || filter.CgroupV2 {
+		aws.PopulateCgroupVersion(instances, ssh.SSHOptions{Verbose: verbose, Scheduler: hostScheduler})
+		displayOptions.ShowCgroupVersion = true
+	}
+	if filter.RegistrationErrors {
+		aws.PopulateRegistrationErrors(instances, ssh.SSHOptions{Verbose: verbose, Scheduler: hostScheduler})
+		displayOptions.ShowRegistration = true
+	}
+	if filter.ZombiesSet {
+		displayOptions.ShowZombies = true
+	}
+	if displayOptions.ShowZombies {
+		aws.PopulateZombieProcesses(instances, ssh.SSHOptions{Verbose: verbose, Scheduler: hostScheduler})
+	}
+	if displayOptions.ShowCloudWatchAgent {
+		aws.PopulateCloudWatchAgentStatus(instances, ssh.SSHOptions{Verbose: verbose, Scheduler: hostScheduler})
+	}
+	var filtered []aws.InstanceData
+	for _, instance := range instances {
+		if filter.UpdateStuck && !instance.UpdateStuck || filter.SSMActive && !instance.SSMAgentActive ||
+			filter.ManagedDrainingPending && instance.ManagedDraining != aws.ManagedDrainingPending ||
+			filter.AgentCPUSet && instance.AgentCPU <= filter.AgentCPUGT ||
+			filter.AgentMemSet && instance.AgentMemMB <= filter.AgentMemGT ||
+			filter.MultiCluster && len(instance.Clusters) < 2 ||
+			filter.StorageDriver != "" && instance.StorageDriver != filter.StorageDriver ||
+			filter.StatusCheckFailed && !instance.StatusCheckFailed() ||
+			filter.ZombiesSet && (!instance.ZombiesChecked || instance.ZombieProcessCount <= filter.ZombiesGT) ||
+			filter.RegistrationErrors && len(instance.RegistrationErrors) == 0 ||
+			filter.CgroupV1 && instance.CgroupVersion != 1 || filter.CgroupV2 && instance.CgroupVersion != 2 {
+			continue
+		}
+		filtered = append(filtered, instance)
+	}
+	instances = filtered
+
+	switch output {
+	case "json":
+		return outputJSON(out, instances)
+	case "csv":
+		if out == "" {
+			return aws.WriteCSV(instances, os.Stdout)
+		}
+		var buf bytes.Buffer
+		if err := aws.WriteCSV(instances, &buf); err != nil {
+			return err
+		}
+		_, err := writeOut(out, "text/csv", buf.Bytes())
+		return err
+	}
+
+	if len(instances) == 0 {
+		log.Println("No EC2 instances found for the specified cluster.")
+		return nil
+	}
+
+	displayOptions.Color = term.IsTerminal(int(os.Stdout.Fd()))
+	displayOptions.ShowCluster = allClusters
+	defer trace.Start("render", "main").Set("rows", len(instances)).End()
+	aws.DisplayEC2Instances(instances, displayOptions)
+	return nil
+}
+
+func listClusters(output string) error {
+	switch output {
+	case "table":
+		return aws.ListECSClusters(awsProfile)
+	case "json":
+		clients, err := aws.NewClients(awsProfile)
+		if err != nil {
+			return err
+		}
+		clusterNames, err := aws.FetchECSClusterNames(clients.ECS)
+		if err != nil {
+			return err
+		}
+		return printJSON(clusterNames)
+	default:
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+}
+
+// printJSON writes v to stdout as indented JSON, truncated at --max-output.
+func printJSON(v any) error {
+	w, err := cappedStdout()
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func prometheusMetrics(path string) error {
+	instances, err := fetchInstances(aws.AllStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+
+	if path == "" {
+		return aws.WritePrometheusMetrics(instances, os.Stdout)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create metrics file: %v", err)
+	}
+	defer file.Close()
+
+	return aws.WritePrometheusMetrics(instances, file)
+}
+
+func ansibleInventory(group string) error {
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+
+	if group == "" {
+		group = ActiveConfig.ClusterName
+	}
+	return aws.GenerateAnsibleInventory(instances, group, os.Stdout)
+}
+
+func sshConfig(identityFile, bastion string) error {
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+
+	return aws.GenerateSSHConfig(instances, identityFile, bastion, os.Stdout)
+}
+
+// generateSSHConfig writes Host entries for the active cluster's instances into
+// enum's marked block of out, creating the file if needed, or prints them.
+func generateSSHConfig(out string, print bool, identityFile, bastion string) error {
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+
+	sshUser := ""
+	if current, err := user.Current(); err == nil {
+		sshUser = current.Username
+	}
+
+	if print {
+		return aws.GenerateClusterSSHConfig(instances, ActiveConfig.ClusterName, sshUser, identityFile, bastion, os.Stdout)
+	}
+
+	var fragment strings.Builder
+	if err := aws.GenerateClusterSSHConfig(instances, ActiveConfig.ClusterName, sshUser, identityFile, bastion, &fragment); err != nil {
+		return err
+	}
+
+	if rest, ok := strings.CutPrefix(out, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("unable to find home directory: %v", err)
+		}
+		out = filepath.Join(home, rest)
+	}
+	if _, err := os.Stat(out); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(out), 0700); err != nil {
+			return fmt.Errorf("unable to create %s: %v", filepath.Dir(out), err)
+		}
+		if err := os.WriteFile(out, nil, 0600); err != nil {
+			return fmt.Errorf("unable to create %s: %v", out, err)
+		}
+	}
+	if err := writeManagedBlock(out, ActiveConfig.ClusterName, fragment.String()); err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated the %s block of %s; add \"Include %s\" to ~/.ssh/config to use it\n", ActiveConfig.ClusterName, out, out)
+	return nil
+}
+
+func hostsFile(appendTo string) error {
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+
+	if appendTo == "" {
+		return aws.GenerateHostsFile(instances, os.Stdout)
+	}
+
+	var fragment strings.Builder
+	if err := aws.GenerateHostsFile(instances, &fragment); err != nil {
+		return err
+	}
+	return writeManagedBlock(appendTo, ActiveConfig.ClusterName, fragment.String())
+}
+
+func natGateways(instanceID string) error {
+	instances, err := fetchInstances(aws.AllStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	index := slices.IndexFunc(instances, func(instance aws.InstanceData) bool {
+		return instance.InstanceID == instanceID
+	})
+	if index == -1 {
+		return fmt.Errorf("instance %s is not a member of the cluster", instanceID)
+	}
+	vpcID := instances[index].VPCID
+
+	gateways, err := aws.FetchNATGateways(vpcID, "", awsProfile)
+	if err != nil {
+		return err
+	}
+	if len(gateways) == 0 {
+		fmt.Printf("No NAT gateways in VPC %s.\n", vpcID)
+		return nil
+	}
+	fmt.Printf("NAT gateways in VPC %s of %s:\n\n", vpcID, instances[index].Name)
+	aws.DisplayNATGateways(gateways)
+	return nil
+}
+
+func dockerPlugins(instanceID string) error {
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	index := slices.IndexFunc(instances, func(instance aws.InstanceData) bool {
+		return instance.InstanceID == instanceID
+	})
+	if index == -1 {
+		return fmt.Errorf("instance %s is not a running member of the cluster", instanceID)
+	}
+
+	plugins, err := ssh.FetchDockerPlugins(instances[index].PrivateIP, containerCLI(instances[index]), ssh.SSHOptions{Verbose: verbose})
+	if err != nil {
+		return err
+	}
+	if len(plugins) == 0 {
+		fmt.Printf("No docker plugins installed on %s.\n", instances[index].Name)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tVersion\tEnabled\tDescription")
+	for _, plugin := range plugins {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", plugin.Name, plugin.Version, plugin.Enabled, plugin.Description)
+	}
+	return w.Flush()
+}
+
+func dockerNetworks(instanceID string) error {
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	index := slices.IndexFunc(instances, func(instance aws.InstanceData) bool {
+		return instance.InstanceID == instanceID
+	})
+	if index == -1 {
+		return fmt.Errorf("instance %s is not a running member of the cluster", instanceID)
+	}
+
+	networks, err := ssh.FetchDockerNetworks(instances[index].PrivateIP, containerCLI(instances[index]), ssh.SSHOptions{Verbose: verbose})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tID\tDriver\tScope\tSubnet\tGateway")
+	for _, network := range networks {
+		subnet, gateway := "-", "-"
+		if len(network.Subnets) > 0 {
+			var subnets, gateways []string
+			for _, config := range network.Subnets {
+				subnets = append(subnets, config.Subnet)
+				if config.Gateway != "" {
+					gateways = append(gateways, config.Gateway)
+				}
+			}
+			subnet = strings.Join(subnets, ",")
+			if len(gateways) > 0 {
+				gateway = strings.Join(gateways, ",")
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", network.Name, network.ID[:min(12, len(network.ID))], network.Driver, network.Scope, subnet, gateway)
+	}
+	return w.Flush()
+}
+
+func terraformImport(format string) error {
+	if err := oneOf("import", "hcl")(format); err != nil {
+		return fmt.Errorf("unsupported format %q: %v", format, err)
+	}
+
+	instances, err := fetchInstances(aws.AllStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+
+	if format == "hcl" {
+		return aws.GenerateTerraformDataSources(instances, os.Stdout)
+	}
+	return aws.GenerateTerraformImports(instances, os.Stdout)
+}
+
+func find(searchTerms []string, all bool, groupBy, stateList, sortBy, out string, wide bool) error {
+	if groupBy != "" && groupBy != "term" {
+		return fmt.Errorf("unsupported --group-by value %q", groupBy)
+	}
+	if out != "" && groupBy != "" {
+		return fmt.Errorf("--out can't be combined with --group-by")
+	}
+	if err := sortRecords(nil, sortBy); err != nil {
+		return err
+	}
+	if groupBy != "" && len(searchTerms) == 0 {
+		return fmt.Errorf("--group-by term needs at least one search term")
+	}
+	states, err := aws.ParseInstanceStates(stateList)
+	if err != nil {
+		return err
+	}
+
+	instances, err := fetchInstances(states)
+	if err != nil {
+		return fmt.Errorf("error fetching instances: %v", err)
+	}
+
+	records := scanContainers(instances, all)
+	_ = sortRecords(records, sortBy) // Validated above
+	if wide {
+		populateImageArchitectures(records, func(record containerRecord) bool {
+			return len(searchTerms) == 0 || len(matchingTerms(record, searchTerms)) > 0
+		})
+	}
+
+	if groupBy == "" {
+		var matches []containerRecord
+		for _, record := range records {
+			if len(searchTerms) == 0 || len(matchingTerms(record, searchTerms)) > 0 {
+				matches = append(matches, record)
+			}
+		}
+		if out != "" {
+			return outputJSON(out, findResults(matches))
+		}
+		renderFindTable(os.Stdout, matches, nil, wide)
+		if wide && slices.ContainsFunc(matches, archMismatch) {
+			fmt.Println("\n! image architecture differs from the host's")
+		}
+		if len(matches) == 0 {
+			for _, term := range searchTerms {
+				if hint := suggestionHint(term, records); hint != "" {
+					fmt.Fprintln(os.Stderr, hint)
+				}
+			}
+		}
+		return nil
+	}
+
+	groups := classifyByTerm(records, searchTerms)
+	multi := make(map[string]bool)
+	for _, record := range records {
+		if len(matchingTerms(record, searchTerms)) > 1 {
+			multi[record.Instance.InstanceID+"/"+record.ID] = true
+		}
+	}
+
+	for i, term := range searchTerms {
+		if i > 0 {
+			fmt.Println()
+		}
+		if len(groups[term]) == 0 {
+			fmt.Printf("0 matches for %s\n", term)
+			if hint := suggestionHint(term, records); hint != "" {
+				fmt.Fprintln(os.Stderr, hint)
+			}
+			continue
+		}
+		fmt.Printf("%d matches for %s\n", len(groups[term]), term)
+		renderFindTable(os.Stdout, groups[term], multi, wide)
+	}
+	if len(multi) > 0 {
+		fmt.Println("\n* matched more than one search term")
+	}
+	if wide && slices.ContainsFunc(records, archMismatch) {
+		fmt.Println("\n! image architecture differs from the host's")
+	}
+	return nil
+}
+
+// matchingTerms returns the search terms that match a container. A term matches
+// when it appears anywhere in the container's name, ID, status or age, ignoring
+// spaces in the term.
+func matchingTerms(record containerRecord, searchTerms []string) []string {
+	line := strings.Join([]string{record.Name, record.ID, record.Status, record.RunningFor}, "\t")
+
+	var matched []string
+	for _, term := range searchTerms {
+		if strings.Contains(line, strings.ReplaceAll(term, " ", "")) {
+			matched = append(matched, term)
+		}
+	}
+	return matched
+}
+
+// classifyByTerm groups containers under every search term they match. Terms
+// without matches are present with an empty slice.
+func classifyByTerm(records []containerRecord, searchTerms []string) map[string][]containerRecord {
+	groups := make(map[string][]containerRecord, len(searchTerms))
+	for _, term := range searchTerms {
+		groups[term] = []containerRecord{}
+	}
+	for _, record := range records {
+		for _, term := range matchingTerms(record, searchTerms) {
+			groups[term] = append(groups[term], record)
+		}
+	}
+	return groups
+}
+
+// renderFindTable prints containers as the find table. Containers whose
+// instance/ID key is in marked get a trailing asterisk on their name. wide adds
+// the image and its architecture, with a "!" where it differs from the host's.
+func renderFindTable(w io.Writer, records []containerRecord, marked map[string]bool, wide bool) {
+	defer trace.Start("render", "main").Set("rows", len(records)).End()
+
+	// Define column widths.
+	const (
+		instanceWidth   = 20
+		idWidth         = 12
+		statusWidth     = 12
+		runningForWidth = 15
+		nameWidth       = 60
+		imageWidth      = 50
+	)
+
+	// Print the table header with fixed width for each column.
+	fmt.Fprintf(w, "%-*s %-*s %-*s %-*s %-*s",
+		instanceWidth, "EC2 Instance",
+		idWidth, "Container ID",
+		statusWidth, "Status",
+		runningForWidth, "Running For",
+		nameWidth, "Container Name")
+	if wide {
+		fmt.Fprintf(w, " %-*s %s", imageWidth, "Image", "Arch")
+	}
+	fmt.Fprintln(w)
+
+	for _, record := range records {
+		name := record.Name
+		if marked[record.Instance.InstanceID+"/"+record.ID] {
+			name += " *"
+		}
+		fmt.Fprintf(w, "%-*s %-*s %-*s %-*s %-*s",
+			instanceWidth, record.Instance.Name,
+			idWidth, record.ID,
+			statusWidth, record.Status,
+			runningForWidth, record.RunningFor,
+			nameWidth, name)
+		if wide {
+			arch := record.ImageArch
+			if archMismatch(record) {
+				arch += " !"
+			}
+			fmt.Fprintf(w, " %-*s %s", imageWidth, record.Image, arch)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func inspectContainer(containerID, outPath string) error {
+	instance, err := locateContainer(containerID)
+	if errors.Is(err, errContainerNotFound) {
+		return reportNotFound(containerID)
+	} else if err != nil {
+		return err
+	}
+
+	inspectCmd := fmt.Sprintf("sudo %s inspect %s", containerCLI(instance), containerID)
+	inspectOutput, err := sshCommand(instance.PrivateIP, inspectCmd, false)
+	if err != nil {
+		return fmt.Errorf("error executing inspect on instance %s: %v", instance.InstanceID, err)
+	}
+	inspectOutput, banner := resyncJSON(inspectOutput)
+	warnBanner(instance, banner)
+	if inspectOutput == "" {
+		return fmt.Errorf("inspect on instance %s printed nothing", instance.InstanceID)
+	}
+
+	if outPath != "" {
+		printed, err := writeOut(outPath, "application/json", []byte(inspectOutput))
+		if err != nil {
+			return err
+		}
+		if printed {
+			return fmt.Errorf("inspect output from %s could not be written to %s and was printed instead", instance.Name, outPath)
+		}
+		fmt.Printf("Inspect output from %s written to %s\n", instance.Name, outPath)
+		return nil
+	}
+
+	w, err := cappedStdout()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "---------- Inspect output from %s ----------\n", instance.Name)
+	fmt.Fprintln(w, inspectOutput)
+	return w.Close()
+}
+
+func followContainerLogs(containerID, tail string) error {
+	if err := validateTail(tail); err != nil {
+		return fmt.Errorf("invalid --tail value %q: %v", tail, err)
+	}
+
+	instance, err := locateContainer(containerID)
+	if errors.Is(err, errContainerNotFound) {
+		return reportNotFound(containerID)
+	} else if err != nil {
+		return err
+	}
+
+	logCmd := fmt.Sprintf("sudo %s logs -f --tail %s %s", containerCLI(instance), tail, containerID)
+	fmt.Printf("Attempting to follow logs on instance %s (%s)\n", instance.InstanceID, instance.Name)
+	// Stream the logs directly to the console
+	if err := ssh.SSHCommandStream(instance.PrivateIP, logCmd); err != nil {
+		return fmt.Errorf("error executing command on instance %s: %v", instance.InstanceID, err)
+	}
+	return nil
+}
+
+// resolveScheduler fills in --concurrency, --throttle and --max-sessions from the active environment's config when they weren't given.
+func resolveScheduler(cmd *cobra.Command) error {
+	envConcurrency := false
+	if _, env, ok := userConfig.Environment(environmentName, ActiveConfig.ClusterName); ok {
+		if env.Concurrency > 0 && !cmd.Flags().Changed("concurrency") {
+			hostScheduler.Concurrency = env.Concurrency
+			envConcurrency = true
+		}
+		if env.Throttle != "" && !cmd.Flags().Changed("throttle") {
+			throttle, err := time.ParseDuration(env.Throttle)
+			if err != nil {
+				return fmt.Errorf("invalid throttle %q in config: %v", env.Throttle, err)
+			}
+			hostScheduler.Throttle = throttle
+		}
+		if env.MaxSessions > 0 && !cmd.Flags().Changed("max-sessions") {
+			maxSessions = env.MaxSessions
+		}
+	}
+	// Commands with --parallel, such as a search, have to reach every host, so they default
+	// to several hosts at once unless --concurrency or the environment says otherwise.
+	if parallel := cmd.Flags().Lookup("parallel"); parallel != nil &&
+		(parallel.Changed || !cmd.Flags().Changed("concurrency") && !envConcurrency) {
+		value, _ := cmd.Flags().GetInt("parallel")
+		if value < 1 {
+			return fmt.Errorf("--parallel must be at least 1")
+		}
+		hostScheduler.Concurrency = value
+	}
+	if maxSessions < 1 {
+		return fmt.Errorf("--max-sessions must be at least 1")
+	}
+	ssh.SetMaxSessions(maxSessions)
+	return nil
+}
+
+// resolveSessionLimits fills in limits not given on the command line from the active environment's config.
+func resolveSessionLimits(cmd *cobra.Command, limits ssh.SessionLimits) (ssh.SessionLimits, error) {
+	_, env, ok := userConfig.Environment(environmentName, ActiveConfig.ClusterName)
+	if !ok {
+		return limits, nil
+	}
+
+	defaults := []struct {
+		flag   string
+		value  string
+		target *time.Duration
+	}{
+		{"max-session", env.MaxSession, &limits.MaxSession},
+		{"idle-timeout", env.IdleTimeout, &limits.IdleTimeout},
+	}
+	for _, d := range defaults {
+		if d.value == "" || cmd.Flags().Changed(d.flag) {
+			continue
+		}
+		duration, err := time.ParseDuration(d.value)
+		if err != nil {
+			return limits, fmt.Errorf("invalid %s %q in config: %v", d.flag, d.value, err)
+		}
+		*d.target = duration
+	}
+	return limits, nil
+}
+
+// shellCommand is the command an interactive session runs: args joined, or /bin/sh by default.
+func shellCommand(args []string) string {
+	if len(args) == 0 {
+		return "/bin/sh"
+	}
+	return strings.Join(args, " ")
+}
+
+func shell(containerID string, args []string, limits ssh.SessionLimits) error {
+	instance, err := locateContainer(containerID)
+	if errors.Is(err, errContainerNotFound) {
+		return reportNotFound(containerID)
+	} else if err != nil {
+		return err
+	}
+
+	fmt.Printf("Container %s found on instance %s (%s). Starting shell session...\n", containerID, instance.InstanceID, instance.Name)
+	if err := ssh.SSHInteractiveShell(instance.PrivateIP, containerCLI(instance), containerID, shellCommand(args), limits); err != nil {
+		return fmt.Errorf("error starting interactive shell session: %v", err)
+	}
 	return nil
 }
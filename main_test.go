@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"enum/config"
+	"enum/scheduler"
+
+	"github.com/spf13/cobra"
+)
+
+func TestResolveSchedulerParallel(t *testing.T) {
+	tests := []struct {
+		name     string
+		parallel bool // The command has --parallel
+		args     []string
+		envConc  int
+		want     int
+	}{
+		{name: "no --parallel", want: 1},
+		{name: "no --parallel, --concurrency", args: []string{"--concurrency", "4"}, want: 4},
+		{name: "default --parallel", parallel: true, want: defaultParallel},
+		{name: "--parallel", parallel: true, args: []string{"--parallel", "3"}, want: 3},
+		{name: "--concurrency beats the default", parallel: true, args: []string{"--concurrency", "2"}, want: 2},
+		{name: "environment beats the default", parallel: true, envConc: 5, want: 5},
+		{name: "--parallel beats the environment", parallel: true, args: []string{"--parallel", "7"}, envConc: 5, want: 7},
+		{name: "--parallel beats --concurrency", parallel: true, args: []string{"--concurrency", "2", "--parallel", "6"}, want: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousScheduler, previousConfig, previousCluster, previousSessions := hostScheduler, userConfig, ActiveConfig.ClusterName, maxSessions
+			t.Cleanup(func() {
+				hostScheduler, userConfig, ActiveConfig.ClusterName, maxSessions = previousScheduler, previousConfig, previousCluster, previousSessions
+			})
+			hostScheduler = scheduler.Scheduler{}
+			ActiveConfig.ClusterName = "prod"
+			userConfig = &config.File{Environments: map[string]config.Environment{
+				"prod": {Clusters: []string{"prod"}, Concurrency: tt.envConc},
+			}}
+
+			cmd := &cobra.Command{Use: "versions"}
+			cmd.Flags().IntVar(&hostScheduler.Concurrency, "concurrency", 1, "")
+			cmd.Flags().IntVar(&maxSessions, "max-sessions", 2, "")
+			if tt.parallel {
+				cmd.Flags().Int("parallel", defaultParallel, "")
+			}
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if err := resolveScheduler(cmd); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hostScheduler.Concurrency != tt.want {
+				t.Errorf("concurrency = %d, want %d", hostScheduler.Concurrency, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyByTerm(t *testing.T) {
+	web := containerRecord{ID: "0a1b2c3d4e5f", Name: "payments-web", Status: "Up 2 hours", RunningFor: "2 hours ago"}
+	worker := containerRecord{ID: "9f8e7d6c5b4a", Name: "payments-worker", Status: "Up 5 minutes", RunningFor: "5 minutes ago"}
+	records := []containerRecord{web, worker}
+
+	tests := []struct {
+		name   string
+		terms  []string
+		record containerRecord
+		want   []string // matchingTerms for record
+		groups map[string][]string
+	}{
+		{
+			name:   "record matching several terms",
+			terms:  []string{"payments", "web", "hours"},
+			record: web,
+			want:   []string{"payments", "web", "hours"},
+			groups: map[string][]string{"payments": {"payments-web", "payments-worker"}, "web": {"payments-web"}, "hours": {"payments-web"}},
+		},
+		{
+			name:   "term without matches keeps an empty group",
+			terms:  []string{"worker", "billing"},
+			record: worker,
+			want:   []string{"worker"},
+			groups: map[string][]string{"worker": {"payments-worker"}, "billing": {}},
+		},
+		{
+			name:   "matching is case sensitive",
+			terms:  []string{"Payments", "UP"},
+			record: web,
+			want:   nil,
+			groups: map[string][]string{"Payments": {}, "UP": {}},
+		},
+		{
+			name:   "spaces in a term are ignored",
+			terms:  []string{"pay ments-web"},
+			record: web,
+			want:   []string{"pay ments-web"},
+			groups: map[string][]string{"pay ments-web": {"payments-web"}},
+		},
+		{
+			name:   "ID prefix",
+			terms:  []string{"9f8e"},
+			record: web,
+			want:   nil,
+			groups: map[string][]string{"9f8e": {"payments-worker"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchingTerms(tt.record, tt.terms); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchingTerms(%s) = %q, want %q", tt.record.Name, got, tt.want)
+			}
+			groups := make(map[string][]string)
+			for term, matched := range classifyByTerm(records, tt.terms) {
+				groups[term] = []string{}
+				for _, record := range matched {
+					groups[term] = append(groups[term], record.Name)
+				}
+			}
+			if !reflect.DeepEqual(groups, tt.groups) {
+				t.Errorf("classifyByTerm = %q, want %q", groups, tt.groups)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// spliceManagedBlock replaces the lines between the "# BEGIN enum <name>" and
+// "# END enum <name>" markers in existing with content, or appends a new marked
+// block when there is none, leaving everything outside the markers untouched.
+// The markers must be whole lines; the END marker may end the file without a newline.
+func spliceManagedBlock(existing, name, content string) string {
+	begin, end := "# BEGIN enum "+name, "# END enum "+name
+	block := begin + "\n" + strings.TrimRight(content, "\n") + "\n" + end + "\n"
+
+	// Pair each END with the nearest BEGIN above it, so a stray BEGIN left by hand
+	// doesn't swallow the lines between it and the real block.
+	lines := strings.SplitAfter(existing, "\n")
+	start := -1
+	for i, line := range lines {
+		switch strings.TrimSuffix(line, "\n") {
+		case begin:
+			start = i
+		case end:
+			if start != -1 {
+				return strings.Join(lines[:start], "") + block + strings.Join(lines[i+1:], "")
+			}
+		}
+	}
+
+	if existing != "" && !strings.HasSuffix(existing, "\n") {
+		existing += "\n"
+	}
+	return existing + block
+}
+
+// writeManagedBlock splices content into the named block of the file at path, keeping its
+// permissions. The new contents are written to a temporary file beside it and renamed into
+// place, so a crash or a full disk leaves the old file intact. A symlinked path is followed
+// and its target replaced, leaving the link in place.
+func writeManagedBlock(path, name, content string) error {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %v", path, err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %v", path, err)
+	}
+	existing, err := os.ReadFile(target)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %v", path, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".enum-*")
+	if err != nil {
+		return fmt.Errorf("unable to write %s: %v", path, err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	_, err = tmp.WriteString(spliceManagedBlock(string(existing), name, content))
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSpliceManagedBlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		want     string
+	}{
+		{
+			name:     "empty file",
+			existing: "",
+			want:     "# BEGIN enum prod\nHost a\n# END enum prod\n",
+		},
+		{
+			name:     "appends after other content",
+			existing: "Host bastion\n  User me\n",
+			want:     "Host bastion\n  User me\n# BEGIN enum prod\nHost a\n# END enum prod\n",
+		},
+		{
+			name:     "adds a missing final newline before appending",
+			existing: "Host bastion",
+			want:     "Host bastion\n# BEGIN enum prod\nHost a\n# END enum prod\n",
+		},
+		{
+			name:     "replaces an existing block in place",
+			existing: "Host x\n# BEGIN enum prod\nHost old\n# END enum prod\nHost y\n",
+			want:     "Host x\n# BEGIN enum prod\nHost a\n# END enum prod\nHost y\n",
+		},
+		{
+			name:     "end marker at EOF without a newline",
+			existing: "Host x\n# BEGIN enum prod\nHost old\n# END enum prod",
+			want:     "Host x\n# BEGIN enum prod\nHost a\n# END enum prod\n",
+		},
+		{
+			name:     "leaves other clusters' blocks alone",
+			existing: "# BEGIN enum staging\nHost s\n# END enum staging\n# BEGIN enum prod\nHost old\n# END enum prod\n",
+			want:     "# BEGIN enum staging\nHost s\n# END enum staging\n# BEGIN enum prod\nHost a\n# END enum prod\n",
+		},
+		{
+			name:     "a longer name sharing the prefix is not a match",
+			existing: "# BEGIN enum prod-eu\nHost e\n# END enum prod-eu\n",
+			want:     "# BEGIN enum prod-eu\nHost e\n# END enum prod-eu\n# BEGIN enum prod\nHost a\n# END enum prod\n",
+		},
+		{
+			name:     "markers must be whole lines",
+			existing: "# note: # BEGIN enum prod\nHost x\n",
+			want:     "# note: # BEGIN enum prod\nHost x\n# BEGIN enum prod\nHost a\n# END enum prod\n",
+		},
+		{
+			name:     "begin without end appends a new block",
+			existing: "# BEGIN enum prod\nHost old\n",
+			want:     "# BEGIN enum prod\nHost old\n# BEGIN enum prod\nHost a\n# END enum prod\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := spliceManagedBlock(tt.existing, "prod", "Host a\n\n")
+			if got != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+			if again := spliceManagedBlock(got, "prod", "Host a\n"); again != got {
+				t.Errorf("second splice changed the file:\n%q\nwant\n%q", again, got)
+			}
+		})
+	}
+}
+
+func TestWriteManagedBlock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, []byte("Host bastion\n# END enum prod"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := writeManagedBlock(path, "prod", "Host a\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Host bastion\n# END enum prod\n# BEGIN enum prod\nHost a\n# END enum prod\n"; string(got) != want {
+		t.Errorf("file is\n%q\nwant\n%q", got, want)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("mode = %o, want 600", perm)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestWriteManagedBlockFollowsSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles-ssh-config")
+	if err := os.WriteFile(target, []byte("Host bastion\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "config")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeManagedBlock(link, "prod", "Host a\n"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("symlink replaced by a regular file (err %v)", err)
+	}
+	got, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Host bastion\n# BEGIN enum prod\nHost a\n# END enum prod\n"; string(got) != want {
+		t.Errorf("target is\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestWriteManagedBlockMissingFile(t *testing.T) {
+	if err := writeManagedBlock(filepath.Join(t.TempDir(), "missing"), "prod", "Host a\n"); err == nil {
+		t.Error("writing into a missing file succeeded")
+	}
+}
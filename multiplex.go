@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"sync"
+	"time"
+
+	"enum/ssh"
+)
+
+// streamBuffer is how many lines each source may have waiting to be written before
+// further lines from it are dropped, so one flooding host can't stall the others.
+const streamBuffer = 1000
+
+// Reconnect backoff for followed streams whose connection drops.
+const (
+	streamRetryMin = 2 * time.Second
+	streamRetryMax = 30 * time.Second
+)
+
+// streamColors are the ANSI colors source labels cycle through.
+var streamColors = []string{"\033[36m", "\033[33m", "\033[32m", "\033[35m", "\033[34m", "\033[31m"}
+
+// streamSource is one remote command whose output is multiplexed.
+type streamSource struct {
+	Label   string // Printed before each line, e.g. the instance name
+	Host    string
+	Command string
+}
+
+// streamMultiplexer interleaves the line output of commands on several hosts, prefixing
+// each line with its source's label.
+type streamMultiplexer struct {
+	Out    io.Writer
+	Filter *regexp.Regexp // Only lines matching are written; nil writes every line
+	Color  bool
+	Follow bool // Reconnect to sources whose stream ends or fails, until cancelled
+}
+
+// Run streams every source until they all finish or ctx is cancelled.
+func (m *streamMultiplexer) Run(ctx context.Context, sources []streamSource) {
+	var writeMu sync.Mutex
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		label := source.Label
+		if m.Color {
+			label = streamColors[i%len(streamColors)] + label + "\033[0m"
+		}
+
+		lines := make(chan string, streamBuffer)
+		wg.Add(2)
+		go func(source streamSource) {
+			defer wg.Done()
+			defer close(lines)
+			m.stream(ctx, source, lines)
+		}(source)
+		go func() {
+			defer wg.Done()
+			for line := range lines {
+				writeMu.Lock()
+				fmt.Fprintf(m.Out, "%s | %s\n", label, line)
+				writeMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// stream feeds the output of one source into lines, reconnecting when following.
+func (m *streamMultiplexer) stream(ctx context.Context, source streamSource, lines chan<- string) {
+	dropped := 0
+	onLine := func(line string) {
+		if m.Filter != nil && !m.Filter.MatchString(line) {
+			return
+		}
+		if dropped > 0 {
+			select {
+			case lines <- fmt.Sprintf("[enum] dropped %d lines while output was backed up", dropped):
+				dropped = 0
+			default:
+			}
+		}
+		select {
+		case lines <- line:
+		default:
+			dropped++
+		}
+	}
+
+	retry := streamRetryMin
+	for {
+		err := streamOnce(ctx, source, onLine)
+		if ctx.Err() != nil || !m.Follow {
+			if err != nil {
+				log.Printf("%s: %v", source.Label, err)
+			}
+			return
+		}
+		if err != nil {
+			log.Printf("%s: %v; reconnecting in %s", source.Label, err, retry)
+		} else {
+			retry = streamRetryMin // The stream ran until the command ended; start over promptly
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		if err != nil {
+			retry = min(2*retry, streamRetryMax)
+		}
+	}
+}
+
+// streamOnce connects to a source and streams its command once.
+func streamOnce(ctx context.Context, source streamSource, onLine func(string)) error {
+	conn, err := ssh.Connect(source.Host, verbose)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.StreamLines(ctx, source.Command, onLine)
+}
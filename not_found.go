@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"enum/aws"
+
+	"github.com/spf13/cobra"
+)
+
+// notFoundOutput is the --output of the commands that look a container up by ID,
+// used for the diagnosis printed when it can't be found.
+var notFoundOutput string
+
+// notFoundDiagnosis collects what enum could learn about a container ID it couldn't find:
+// which hosts it could and couldn't check, whether a host still lists the container
+// (stopped, usually), whether ECS remembers a stopped task that ran it, and which scanned
+// containers have a similar ID or name.
+type notFoundDiagnosis struct {
+	ContainerID       string             `json:"container_id"`
+	InstancesScanned  int                `json:"instances_scanned"`
+	InstancesSkipped  []skippedInstance  `json:"instances_skipped"`
+	Containers        []findResult       `json:"containers"`
+	StoppedTasks      []stoppedTaskMatch `json:"stopped_tasks"`
+	StoppedTasksError string             `json:"stopped_tasks_error,omitempty"`
+	Suggestions       []string           `json:"suggestions"`
+}
+
+// skippedInstance is an instance the diagnosis couldn't check, with the reason.
+type skippedInstance struct {
+	InstanceID   string `json:"instance_id"`
+	InstanceName string `json:"instance_name"`
+	Reason       string `json:"reason"`
+}
+
+// stoppedTaskMatch is a stopped ECS task one of whose containers had the ID.
+type stoppedTaskMatch struct {
+	TaskArn       string    `json:"task_arn"`
+	Group         string    `json:"group"`
+	Container     string    `json:"container"`
+	ExitCode      *int64    `json:"exit_code"`
+	Reason        string    `json:"reason"`
+	StoppedReason string    `json:"stopped_reason"`
+	StoppedAt     time.Time `json:"stopped_at"`
+	InstanceID    string    `json:"instance_id"`
+}
+
+// sameContainerID reports whether two container IDs, either of which may be the short
+// form, name the same container.
+func sameContainerID(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+// diagnoseNotFound rescans the cluster, stopped containers included, and the stopped
+// tasks ECS still retains for containerID.
+func diagnoseNotFound(containerID string) (notFoundDiagnosis, error) {
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return newNotFoundDiagnosis(containerID), fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+
+	// Each host fills its own slot so the diagnosis keeps the instance order.
+	perHost := make([][]containerRecord, len(instances))
+	failures := make([]error, len(instances))
+	hostScheduler.Run(len(instances), func(i int) {
+		if instances[i].PrivateIP == "" {
+			failures[i] = fmt.Errorf("no private IP to connect to")
+			return
+		}
+		perHost[i], failures[i] = scanHost(instances[i], true)
+	})
+
+	stopped, stoppedErr := aws.ListStoppedTasks(ActiveConfig.ClusterName, awsProfile, 0)
+	return buildNotFoundDiagnosis(containerID, instances, perHost, failures, stopped, stoppedErr), nil
+}
+
+// newNotFoundDiagnosis returns an empty diagnosis, with empty rather than nil lists so
+// the JSON output always has them.
+func newNotFoundDiagnosis(containerID string) notFoundDiagnosis {
+	return notFoundDiagnosis{
+		ContainerID:      containerID,
+		InstancesSkipped: []skippedInstance{},
+		Containers:       []findResult{},
+		StoppedTasks:     []stoppedTaskMatch{},
+		Suggestions:      []string{},
+	}
+}
+
+// buildNotFoundDiagnosis puts together the diagnosis from each instance's containers or
+// failure, by index, and the stopped tasks ECS returned.
+func buildNotFoundDiagnosis(containerID string, instances []aws.InstanceData, perHost [][]containerRecord, failures []error, stopped []aws.StoppedTaskInfo, stoppedErr error) notFoundDiagnosis {
+	diagnosis := newNotFoundDiagnosis(containerID)
+
+	var records []containerRecord
+	for i, instance := range instances {
+		if failures[i] != nil {
+			diagnosis.InstancesSkipped = append(diagnosis.InstancesSkipped, skippedInstance{
+				InstanceID:   instance.InstanceID,
+				InstanceName: instance.Name,
+				Reason:       failures[i].Error(),
+			})
+			continue
+		}
+		diagnosis.InstancesScanned++
+		records = append(records, perHost[i]...)
+	}
+
+	var matches, candidates []string
+	for _, record := range records {
+		if sameContainerID(record.ID, containerID) {
+			matches = append(matches, record.ID)
+			diagnosis.Containers = append(diagnosis.Containers, findResults([]containerRecord{record})...)
+		}
+		candidates = append(candidates, record.ID, record.Name)
+	}
+	if len(matches) == 0 {
+		diagnosis.Suggestions = append(diagnosis.Suggestions, suggestNames(containerID, candidates)...)
+	}
+
+	if stoppedErr != nil {
+		diagnosis.StoppedTasksError = stoppedErr.Error()
+	}
+	for _, task := range stopped {
+		for _, container := range task.Containers {
+			if !sameContainerID(container.RuntimeID, containerID) {
+				continue
+			}
+			diagnosis.StoppedTasks = append(diagnosis.StoppedTasks, stoppedTaskMatch{
+				TaskArn:       task.TaskArn,
+				Group:         task.Group,
+				Container:     container.Name,
+				ExitCode:      container.ExitCode,
+				Reason:        container.Reason,
+				StoppedReason: task.StoppedReason,
+				StoppedAt:     task.StoppedAt,
+				InstanceID:    task.InstanceID,
+			})
+		}
+	}
+	return diagnosis
+}
+
+// print writes the diagnosis for a person to read.
+func (d notFoundDiagnosis) print(w io.Writer) {
+	fmt.Fprintf(w, "Container %s not found on any instance.\n", d.ContainerID)
+	fmt.Fprintf(w, "  Scanned %d of %d instances.\n", d.InstancesScanned, d.InstancesScanned+len(d.InstancesSkipped))
+	for _, skipped := range d.InstancesSkipped {
+		fmt.Fprintf(w, "  Skipped %s (%s): %s\n", skipped.InstanceName, skipped.InstanceID, firstLine(skipped.Reason, "unknown error"))
+	}
+	for _, container := range d.Containers {
+		fmt.Fprintf(w, "  %s (%s) still lists it as %s, status %q.\n",
+			container.InstanceName, container.InstanceID, container.Name, container.Status)
+	}
+	for _, task := range d.StoppedTasks {
+		exit := "never started"
+		if task.ExitCode != nil {
+			exit = fmt.Sprintf("exited %d", *task.ExitCode)
+		}
+		fmt.Fprintf(w, "  It was container %s of ECS task %s (%s), which stopped at %s: %s (%s).\n",
+			task.Container, taskID(task.TaskArn), task.Group, task.StoppedAt.Local().Format(time.RFC3339), task.StoppedReason, exit)
+	}
+	if d.StoppedTasksError != "" {
+		fmt.Fprintf(w, "  Couldn't check stopped ECS tasks: %s\n", d.StoppedTasksError)
+	} else if len(d.Containers) == 0 && len(d.StoppedTasks) == 0 {
+		fmt.Fprintln(w, "  No host lists it and no stopped ECS task ran it; it may have stopped more than an hour ago, when ECS stops retaining tasks.")
+	}
+	if len(d.Suggestions) > 0 {
+		fmt.Fprintf(w, "  Did you mean: %s?\n", strings.Join(d.Suggestions, ", "))
+	}
+}
+
+// addNotFoundOutputFlag adds --output, the format of the not-found diagnosis, to a
+// command that looks a container up by ID.
+func addNotFoundOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&notFoundOutput, "output", "o", "text", "Format of the diagnosis printed when the container isn't found: text or json")
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := oneOf("text", "json")(notFoundOutput); err != nil {
+			return fmt.Errorf("unsupported output format %q: %v", notFoundOutput, err)
+		}
+		return nil
+	}
+}
+
+// reportNotFound prints the diagnosis for a container ID that couldn't be found, as text
+// or, with --output json, as JSON.
+func reportNotFound(containerID string) error {
+	diagnosis, err := diagnoseNotFound(containerID)
+	if err != nil {
+		return err
+	}
+	if notFoundOutput == "json" {
+		return printJSON(diagnosis)
+	}
+	diagnosis.print(os.Stdout)
+	return nil
+}
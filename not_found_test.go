@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"enum/aws"
+)
+
+// notFoundFixture is a cluster of three instances: web-1 and web-2 reachable, web-3 not.
+var notFoundFixture = snapshotSource{file: snapshotFile{
+	Cluster: "prod",
+	Instances: []aws.InstanceData{
+		{InstanceID: "i-1", Name: "web-1", PrivateIP: "10.0.0.1", State: "running"},
+		{InstanceID: "i-2", Name: "web-2", PrivateIP: "10.0.0.2", State: "running"},
+		{InstanceID: "i-3", Name: "web-3", PrivateIP: "10.0.0.3", State: "running"},
+	},
+	Containers: []snapshotContainer{
+		{InstanceID: "i-1", ID: "aaa111bbb222", Name: "payments-api", Status: "Up 2 hours"},
+		{InstanceID: "i-2", ID: "ccc333ddd444", Name: "orders-worker", Status: "Exited (137) 5 minutes ago"},
+		{InstanceID: "i-3", ID: "eee555fff666", Name: "ledger", Status: "Up 1 hour"},
+	},
+}}
+
+// scanFixture returns what a scan of the fixture finds when the hosts in unreachable fail.
+func scanFixture(t *testing.T, unreachable map[string]error) ([]aws.InstanceData, [][]containerRecord, []error) {
+	t.Helper()
+	instances, err := notFoundFixture.Instances(aws.RunningStates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	perHost := make([][]containerRecord, len(instances))
+	failures := make([]error, len(instances))
+	for i, instance := range instances {
+		if err, ok := unreachable[instance.InstanceID]; ok {
+			failures[i] = err
+			continue
+		}
+		perHost[i] = notFoundFixture.Containers([]aws.InstanceData{instance}, true)
+	}
+	return instances, perHost, failures
+}
+
+func TestBuildNotFoundDiagnosisStoppedContainer(t *testing.T) {
+	instances, perHost, failures := scanFixture(t, map[string]error{"i-3": errors.New("ssh: unable to authenticate")})
+	d := buildNotFoundDiagnosis("ccc333", instances, perHost, failures, nil, nil)
+
+	if d.InstancesScanned != 2 {
+		t.Errorf("scanned %d instances, want 2", d.InstancesScanned)
+	}
+	wantSkipped := []skippedInstance{{InstanceID: "i-3", InstanceName: "web-3", Reason: "ssh: unable to authenticate"}}
+	if !reflect.DeepEqual(d.InstancesSkipped, wantSkipped) {
+		t.Errorf("skipped = %+v, want %+v", d.InstancesSkipped, wantSkipped)
+	}
+	if len(d.Containers) != 1 || d.Containers[0].InstanceName != "web-2" || d.Containers[0].ID != "ccc333ddd444" {
+		t.Errorf("containers = %+v, want the stopped one on web-2", d.Containers)
+	}
+	if len(d.Suggestions) != 0 {
+		t.Errorf("suggestions %v for a container that was found", d.Suggestions)
+	}
+}
+
+func TestBuildNotFoundDiagnosisSkippedInInstanceOrder(t *testing.T) {
+	instances, perHost, failures := scanFixture(t, map[string]error{
+		"i-3": errors.New("no route to host"),
+		"i-1": errors.New("connection refused"),
+	})
+	d := buildNotFoundDiagnosis("zzz", instances, perHost, failures, nil, nil)
+	var order []string
+	for _, skipped := range d.InstancesSkipped {
+		order = append(order, skipped.InstanceID)
+	}
+	if !reflect.DeepEqual(order, []string{"i-1", "i-3"}) {
+		t.Errorf("skipped in order %v, want instance order [i-1 i-3]", order)
+	}
+	if d.InstancesScanned != 1 {
+		t.Errorf("scanned %d instances, want 1", d.InstancesScanned)
+	}
+}
+
+func TestBuildNotFoundDiagnosisStoppedTask(t *testing.T) {
+	instances, perHost, failures := scanFixture(t, nil)
+	exit := int64(137)
+	stoppedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	stopped := []aws.StoppedTaskInfo{
+		{
+			TaskArn:       "arn:aws:ecs:us-west-2:1:task/prod/t1",
+			Group:         "service:payments",
+			StoppedReason: "Essential container in task exited",
+			StoppedAt:     stoppedAt,
+			InstanceID:    "i-2",
+			Containers: []aws.ContainerExit{
+				{Name: "sidecar", RuntimeID: "0123456789ab"},
+				{Name: "app", RuntimeID: "999888777666555", ExitCode: &exit, Reason: "OutOfMemoryError"},
+			},
+		},
+		{TaskArn: "arn:aws:ecs:us-west-2:1:task/prod/t2", Containers: []aws.ContainerExit{{Name: "app"}}},
+	}
+	d := buildNotFoundDiagnosis("999888777666", instances, perHost, failures, stopped, nil)
+
+	want := []stoppedTaskMatch{{
+		TaskArn:       "arn:aws:ecs:us-west-2:1:task/prod/t1",
+		Group:         "service:payments",
+		Container:     "app",
+		ExitCode:      &exit,
+		Reason:        "OutOfMemoryError",
+		StoppedReason: "Essential container in task exited",
+		StoppedAt:     stoppedAt,
+		InstanceID:    "i-2",
+	}}
+	if !reflect.DeepEqual(d.StoppedTasks, want) {
+		t.Errorf("stopped tasks = %+v, want %+v", d.StoppedTasks, want)
+	}
+	if len(d.Containers) != 0 {
+		t.Errorf("containers = %+v, want none", d.Containers)
+	}
+
+	var out bytes.Buffer
+	d.print(&out)
+	if !strings.Contains(out.String(), "container app of ECS task t1 (service:payments)") || !strings.Contains(out.String(), "exited 137") {
+		t.Errorf("text output doesn't describe the stopped task:\n%s", out.String())
+	}
+}
+
+func TestBuildNotFoundDiagnosisSuggestions(t *testing.T) {
+	instances, perHost, failures := scanFixture(t, nil)
+	d := buildNotFoundDiagnosis("payment-api", instances, perHost, failures, nil, errors.New("AccessDenied"))
+	if !reflect.DeepEqual(d.Suggestions, []string{"payments-api"}) {
+		t.Errorf("suggestions = %v, want [payments-api]", d.Suggestions)
+	}
+	if d.StoppedTasksError != "AccessDenied" {
+		t.Errorf("stopped tasks error = %q", d.StoppedTasksError)
+	}
+
+	var out bytes.Buffer
+	d.print(&out)
+	for _, want := range []string{"Scanned 3 of 3 instances.", "Couldn't check stopped ECS tasks: AccessDenied", "Did you mean: payments-api?"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("text output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestBuildNotFoundDiagnosisNothingFound(t *testing.T) {
+	instances, perHost, failures := scanFixture(t, nil)
+	d := buildNotFoundDiagnosis("0000000000", instances, perHost, failures, nil, nil)
+	if d.InstancesSkipped == nil || d.Containers == nil || d.StoppedTasks == nil || d.Suggestions == nil {
+		t.Error("empty lists are nil and would be null in the JSON output")
+	}
+	var out bytes.Buffer
+	d.print(&out)
+	if !strings.Contains(out.String(), "No host lists it and no stopped ECS task ran it") {
+		t.Errorf("text output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	"enum/ssh"
+
+	"golang.org/x/term"
+)
+
+// Conditions notify can wait for.
+const (
+	untilGone      = "gone"
+	untilHealthy   = "healthy"
+	untilUnhealthy = "unhealthy"
+	untilRestarted = "restarted"
+)
+
+// containerObservation is one poll of a container on its host.
+type containerObservation struct {
+	Running   bool   // The container is still running; false once it has stopped or been removed
+	Status    string // docker's State.Status, such as "running" or "exited"; empty once removed
+	Health    string
+	StartedAt string
+}
+
+// presenceWatch decides, one observation at a time, whether the condition a notify
+// is waiting for has been met, or can no longer be met.
+type presenceWatch struct {
+	until string
+	first *containerObservation
+}
+
+// observe records an observation and reports whether the condition is met. An error
+// means the condition can never be met, e.g. waiting for health on a container that stopped.
+func (w *presenceWatch) observe(obs containerObservation) (bool, error) {
+	if w.first == nil {
+		w.first = &obs
+	}
+
+	switch w.until {
+	case untilGone:
+		return !obs.Running, nil
+	case untilRestarted:
+		// docker restart stops the container before starting it again, so a stopped or
+		// restarting container is still on its way; only removal ends the wait early.
+		if obs.Status == "" {
+			return false, fmt.Errorf("container was removed before it restarted")
+		}
+		return obs.Status == "running" && obs.StartedAt != w.first.StartedAt, nil
+	}
+	if !obs.Running {
+		return false, fmt.Errorf("container stopped before becoming %s", w.until)
+	}
+
+	switch w.until {
+	case untilHealthy, untilUnhealthy:
+		if obs.Health == "" {
+			return false, fmt.Errorf("container has no health check")
+		}
+		return obs.Health == w.until, nil
+	}
+	return false, fmt.Errorf("unknown condition %q", w.until)
+}
+
+// parseObservation reads a poll's "status|startedAt|health" line; no line means the
+// container was removed. Like docker ps, it counts restarting and paused containers as running.
+func parseObservation(output string) containerObservation {
+	line := strings.TrimSpace(output)
+	if line == "" {
+		return containerObservation{}
+	}
+	fields := append(strings.SplitN(line, "|", 3), "", "")
+	obs := containerObservation{Status: fields[0], StartedAt: fields[1], Health: fields[2]}
+	switch obs.Status {
+	case "running", "restarting", "paused":
+		obs.Running = true
+	}
+	return obs
+}
+
+// notify polls the host of a container until the condition is met, then rings the
+// terminal bell and runs hook, if given, locally.
+func notify(containerID, until string, interval, timeout time.Duration, hook string) error {
+	if containerID == "" {
+		return fmt.Errorf("--container is required")
+	}
+	if err := oneOf(untilGone, untilHealthy, untilUnhealthy, untilRestarted)(until); err != nil {
+		return fmt.Errorf("unsupported --until %q: %v", until, err)
+	}
+
+	instance, err := locateContainer(containerID)
+	if err != nil {
+		return err
+	}
+	conn, err := ssh.Connect(instance.PrivateIP, verbose)
+	if err != nil {
+		return fmt.Errorf("error connecting to %s: %v", instance.Name, err)
+	}
+	defer conn.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// docker ps -a still lists stopped containers, so an empty result means the container was removed.
+	cli := containerCLI(instance)
+	pollCmd := fmt.Sprintf("sudo %s ps -a -q --no-trunc --filter \"id=%s\" | xargs -r sudo %s inspect --format '{{.State.Status}}|{{.State.StartedAt}}|{{if .State.Health}}{{.State.Health.Status}}{{end}}'",
+		cli, containerID, cli)
+
+	fmt.Printf("Waiting for container %s on %s to be %s...\n", containerID, instance.Name, until)
+	watch := &presenceWatch{until: until}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		output, err := conn.Run(pollCmd)
+		if err != nil {
+			return fmt.Errorf("error polling %s: %v", instance.Name, err)
+		}
+		if output.ExitCode != 0 {
+			return fmt.Errorf("error polling %s: exit status %d: %s", instance.Name, output.ExitCode, strings.TrimSpace(output.Stderr))
+		}
+
+		met, err := watch.observe(parseObservation(output.Stdout))
+		if err != nil {
+			return err
+		}
+		if met {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("timed out after %s waiting for container %s to be %s", timeout, containerID, until)
+			}
+			return fmt.Errorf("interrupted")
+		case <-ticker.C:
+		}
+	}
+
+	message := fmt.Sprintf("Container %s is %s.", containerID, until)
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		message = "\033[1m" + message + "\033[0m"
+	}
+	fmt.Println("\a" + message)
+
+	if hook != "" {
+		hookCmd := exec.Command("sh", "-c", hook)
+		hookCmd.Stdout, hookCmd.Stderr = os.Stdout, os.Stderr
+		if err := hookCmd.Run(); err != nil {
+			return fmt.Errorf("--exec hook failed: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseObservation(t *testing.T) {
+	tests := []struct {
+		in   string
+		want containerObservation
+	}{
+		{in: "", want: containerObservation{}},
+		{in: "\n", want: containerObservation{}},
+		{in: "running|2024-05-01T12:00:00Z|healthy\n", want: containerObservation{Running: true, Status: "running", StartedAt: "2024-05-01T12:00:00Z", Health: "healthy"}},
+		{in: "running|2024-05-01T12:00:00Z|", want: containerObservation{Running: true, Status: "running", StartedAt: "2024-05-01T12:00:00Z"}},
+		{in: "restarting|2024-05-01T12:00:00Z|", want: containerObservation{Running: true, Status: "restarting", StartedAt: "2024-05-01T12:00:00Z"}},
+		{in: "paused|2024-05-01T12:00:00Z|", want: containerObservation{Running: true, Status: "paused", StartedAt: "2024-05-01T12:00:00Z"}},
+		{in: "exited|2024-05-01T12:00:00Z|unhealthy", want: containerObservation{Status: "exited", StartedAt: "2024-05-01T12:00:00Z", Health: "unhealthy"}},
+		{in: "created", want: containerObservation{Status: "created"}},
+	}
+	for _, tt := range tests {
+		if got := parseObservation(tt.in); got != tt.want {
+			t.Errorf("parseObservation(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPresenceWatch(t *testing.T) {
+	const (
+		before = "2024-05-01T12:00:00Z"
+		after  = "2024-05-01T12:05:00Z"
+	)
+	running := func(startedAt, health string) containerObservation {
+		return containerObservation{Running: true, Status: "running", StartedAt: startedAt, Health: health}
+	}
+	exited := containerObservation{Status: "exited", StartedAt: before}
+	restarting := containerObservation{Running: true, Status: "restarting", StartedAt: before}
+	removed := containerObservation{}
+
+	tests := []struct {
+		name    string
+		until   string
+		polls   []containerObservation
+		wantMet []bool // One per poll
+		wantErr string // Of the last poll
+	}{
+		{name: "gone once stopped", until: untilGone, polls: []containerObservation{running(before, ""), exited}, wantMet: []bool{false, true}},
+		{name: "gone once removed", until: untilGone, polls: []containerObservation{running(before, ""), removed}, wantMet: []bool{false, true}},
+		{name: "gone already", until: untilGone, polls: []containerObservation{removed}, wantMet: []bool{true}},
+
+		{name: "healthy", until: untilHealthy, polls: []containerObservation{running(before, "starting"), running(before, "healthy")}, wantMet: []bool{false, true}},
+		{name: "unhealthy", until: untilUnhealthy, polls: []containerObservation{running(before, "healthy"), running(before, "unhealthy")}, wantMet: []bool{false, true}},
+		{name: "healthy without a health check", until: untilHealthy, polls: []containerObservation{running(before, "")}, wantMet: []bool{false}, wantErr: "no health check"},
+		{name: "healthy but stopped", until: untilHealthy, polls: []containerObservation{running(before, "starting"), exited}, wantMet: []bool{false, false}, wantErr: "stopped before becoming healthy"},
+
+		{name: "restarted", until: untilRestarted, polls: []containerObservation{running(before, ""), running(before, ""), running(after, "")}, wantMet: []bool{false, false, true}},
+		{
+			name:    "restarted through stopped and restarting",
+			until:   untilRestarted,
+			polls:   []containerObservation{running(before, ""), exited, restarting, running(after, "")},
+			wantMet: []bool{false, false, false, true},
+		},
+		{name: "restarted from stopped", until: untilRestarted, polls: []containerObservation{exited, exited, running(after, "")}, wantMet: []bool{false, false, true}},
+		{name: "restarted but removed", until: untilRestarted, polls: []containerObservation{running(before, ""), exited, removed}, wantMet: []bool{false, false, false}, wantErr: "removed before it restarted"},
+		{name: "restarted but created with the same start", until: untilRestarted, polls: []containerObservation{running(before, ""), {Status: "created", StartedAt: after}}, wantMet: []bool{false, false}},
+
+		{name: "unknown condition", until: "happy", polls: []containerObservation{running(before, "")}, wantMet: []bool{false}, wantErr: "unknown condition"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watch := &presenceWatch{until: tt.until}
+			for i, obs := range tt.polls {
+				met, err := watch.observe(obs)
+				last := i == len(tt.polls)-1
+				if last && tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("poll %d: err = %v, want containing %q", i, err, tt.wantErr)
+					}
+				} else if err != nil {
+					t.Fatalf("poll %d: unexpected error: %v", i, err)
+				}
+				if met != tt.wantMet[i] {
+					t.Errorf("poll %d: met = %v, want %v", i, met, tt.wantMet[i])
+				}
+			}
+		})
+	}
+}
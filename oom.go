@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"enum/aws"
+	"enum/ssh"
+	"enum/timeparse"
+)
+
+// oomEvent is a single OOM kill found on a host, either from docker's
+// container state or from the kernel log.
+type oomEvent struct {
+	Instance    string
+	Container   string
+	Time        time.Time
+	MemoryLimit int64
+	Kind        string // "cgroup" when the container limit was hit, "host" otherwise
+	Source      string // "docker" or "kernel"
+}
+
+// oomReport prints every OOM kill since the given time and returns the
+// number of events found.
+func oomReport(since timeparse.Time, service string) (int, error) {
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+
+	cutoff := since.At
+	var events []oomEvent
+	for _, instance := range instances {
+		if instance.PrivateIP == "" {
+			continue
+		}
+
+		containerEvents, err := containerOOMEvents(instance, cutoff, service)
+		if err != nil {
+			log.Printf("Error checking containers on instance %s: %v", instance.Name, err)
+			continue
+		}
+		events = append(events, containerEvents...)
+
+		// Kernel log lines can't be tied to a service, so skip them when narrowing.
+		if service != "" {
+			continue
+		}
+		kernelEvents, err := kernelOOMEvents(instance, cutoff)
+		if err != nil {
+			log.Printf("Error reading kernel log on instance %s: %v", instance.Name, err)
+			continue
+		}
+		events = append(events, mergeKernelOOMEvents(containerEvents, kernelEvents)...)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Time.After(events[j].Time)
+	})
+
+	if len(events) == 0 {
+		fmt.Printf("No OOM kills found %s.\n", since.Since())
+		return 0, nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Time\tInstance\tContainer\tMemory Limit\tKind\tSource")
+	for _, event := range events {
+		limit := "none"
+		if event.MemoryLimit > 0 {
+			limit = fmt.Sprintf("%dMiB", event.MemoryLimit/(1024*1024))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			event.Time.Local().Format(time.RFC3339),
+			event.Instance,
+			event.Container,
+			limit,
+			event.Kind,
+			event.Source)
+	}
+	w.Flush()
+
+	return len(events), nil
+}
+
+// containerOOMEvents inspects the exited containers on an instance and returns
+// those docker reports as OOM killed after the cutoff.
+func containerOOMEvents(instance aws.InstanceData, cutoff time.Time, service string) ([]oomEvent, error) {
+	ids, err := ssh.SSHCommand(instance.PrivateIP, runtimeCommand(func(cli string) string {
+		return "sudo " + cli + " ps -a -q --filter status=exited"
+	}), false)
+	if err != nil {
+		return nil, err
+	}
+	instance.ContainerRuntime, ids = splitRuntimeOutput(ids)
+	if strings.TrimSpace(ids) == "" {
+		return nil, nil
+	}
+
+	inspectCmd := fmt.Sprintf("sudo %s inspect --format '{{.Name}}\t{{.State.OOMKilled}}\t{{.State.FinishedAt}}\t{{.HostConfig.Memory}}\t{{index .Config.Labels \"com.amazonaws.ecs.task-definition-family\"}}' %s",
+		containerCLI(instance), strings.Join(strings.Fields(ids), " "))
+	output, err := ssh.SSHCommand(instance.PrivateIP, inspectCmd, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []oomEvent
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.Split(line, "\t")
+		if len(parts) < 5 || parts[1] != "true" {
+			continue
+		}
+		if service != "" && parts[4] != service {
+			continue
+		}
+		finishedAt, err := time.Parse(time.RFC3339Nano, parts[2])
+		if err != nil || finishedAt.Before(cutoff) {
+			continue
+		}
+		memory, _ := strconv.ParseInt(parts[3], 10, 64)
+
+		// Without a memory limit the container can only have been picked by the host OOM killer.
+		kind := "cgroup"
+		if memory == 0 {
+			kind = "host"
+		}
+		events = append(events, oomEvent{
+			Instance:    instance.Name,
+			Container:   strings.TrimPrefix(parts[0], "/"),
+			Time:        finishedAt,
+			MemoryLimit: memory,
+			Kind:        kind,
+			Source:      "docker",
+		})
+	}
+
+	return events, nil
+}
+
+// kernelOOMEvents reads the OOM killer lines from the kernel log, preferring
+// journald and falling back to dmesg.
+func kernelOOMEvents(instance aws.InstanceData, cutoff time.Time) ([]oomEvent, error) {
+	cmd := fmt.Sprintf("(sudo journalctl -k -o short-iso --no-pager --since '@%d' 2>/dev/null || sudo dmesg --time-format iso) | grep -i 'out of memory' | grep -i 'kill'",
+		cutoff.Unix())
+	output, err := ssh.SSHGrepCommand(instance.PrivateIP, cmd, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []oomEvent
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		timestamp, err := parseKernelTimestamp(fields[0])
+		if err != nil || timestamp.Before(cutoff) {
+			continue
+		}
+
+		kind := "host"
+		if strings.Contains(strings.ToLower(line), "memory cgroup out of memory") {
+			kind = "cgroup"
+		}
+		process := "unknown"
+		if start := strings.Index(line, "("); start != -1 {
+			if end := strings.Index(line[start:], ")"); end != -1 {
+				process = line[start+1 : start+end]
+			}
+		}
+		events = append(events, oomEvent{
+			Instance:  instance.Name,
+			Container: "(process " + process + ")",
+			Time:      timestamp,
+			Kind:      kind,
+			Source:    "kernel",
+		})
+	}
+
+	return events, nil
+}
+
+// mergeKernelOOMEvents drops kernel events that describe a kill docker already
+// reported, so each OOM kill appears once in the report.
+func mergeKernelOOMEvents(containerEvents, kernelEvents []oomEvent) []oomEvent {
+	const window = 10 * time.Second
+
+	var merged []oomEvent
+	for _, kernelEvent := range kernelEvents {
+		duplicate := false
+		for _, containerEvent := range containerEvents {
+			diff := containerEvent.Time.Sub(kernelEvent.Time)
+			if diff > -window && diff < window {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			merged = append(merged, kernelEvent)
+		}
+	}
+
+	return merged
+}
+
+// parseKernelTimestamp parses the leading timestamp of a journalctl short-iso
+// or dmesg iso line.
+func parseKernelTimestamp(s string) (time.Time, error) {
+	layouts := []string{
+		"2006-01-02T15:04:05-0700",
+		"2006-01-02T15:04:05,000000-07:00",
+		time.RFC3339,
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q", s)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"enum/config"
+
+	"github.com/spf13/cobra"
+)
+
+// Operation classes, from least to most invasive.
+const (
+	opLocal           = "local"            // Never touches the cluster; always allowed
+	opRead            = "read"             // Only reads state
+	opExec            = "exec"             // Runs arbitrary commands inside containers
+	opMutateContainer = "mutate-container" // Changes container state (restart, stop, ...)
+	opMutateECS       = "mutate-ecs"       // Changes ECS state (tasks, services, ...)
+	opMutateEC2       = "mutate-ec2"       // Changes instances or files on them
+)
+
+// operationClasses declares the operation class of every command, keyed by its
+// path below the root command. Every new command must be added here.
+var operationClasses = map[string]string{
+	"":                     opLocal, // The interactive palette; the command it runs is checked separately
+	"version":              opLocal,
+	"help":                 opLocal,
+	"completion":           opLocal,
+	"config":               opLocal,
+	"config set":           opLocal,
+	"config show":          opLocal,
+	"cache":                opLocal,
+	"cache status":         opLocal,
+	"api-describe":         opLocal,
+	"list-ec2":             opRead,
+	"list-ecs":             opRead,
+	"find":                 opRead,
+	"inspect":              opRead,
+	"whois":                opRead,
+	"docker-networks":      opRead,
+	"docker-plugins":       opRead,
+	"cat":                  opRead,
+	"curl":                 opRead,
+	"snapshot":             opRead,
+	"fd-count":             opRead,
+	"cpu-throttle":         opRead,
+	"check-networking":     opRead,
+	"check-docker-version": opRead,
+	"notify":               opRead,
+	"placement-failures":   opRead,
+	"attributes":           opRead,
+	"diff-env":             opRead,
+	"autoscaling-config":   opRead,
+	"exec-config":          opRead,
+	"nat-gateways":         opRead,
+	"elb-health":           opRead,
+	"action":               opMutateEC2,
+	"versions":             opRead,
+	"push":                 opMutateEC2,
+	"drift-check":          opRead,
+	"syslog":               opRead,
+	"trust-hosts":          opRead,
+	"ami-rollout":          opRead,
+	"logs":                 opRead,
+	"oom":                  opRead,
+	"limits":               opRead,
+	"stale-images":         opRead,
+	"stopped-tasks":        opRead,
+	"stopped":              opRead,
+	"prometheus-metrics":   opRead,
+	"ansible-inventory":    opRead,
+	"ssh-config":           opRead,
+	"generate-ssh-config":  opRead,
+	"hosts-file":           opRead,
+	"terraform-import":     opRead,
+	"instance-events":      opRead,
+	"asg-history":          opRead,
+	"task-fit":             opRead,
+	"memory-report":        opRead,
+	"scaling-status":       opRead,
+	"capacity-metrics":     opRead,
+	"sg-rules":             opRead,
+	"shell":                opExec,
+	"ssh":                  opMutateEC2,
+	"restart-all":          opMutateContainer,
+}
+
+// operationClass returns the declared class of cmd. Subcommands of cobra's
+// generated completion command inherit its class.
+func operationClass(cmd *cobra.Command) (string, error) {
+	path := strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()), " ")
+	if class, ok := operationClasses[path]; ok {
+		return class, nil
+	}
+	if top := strings.SplitN(path, " ", 2)[0]; top == "completion" {
+		return operationClasses[top], nil
+	}
+	return "", fmt.Errorf("command %q does not declare an operation class", path)
+}
+
+// authorizeOperation refuses cmd when the config restricts enum to operations that don't include it.
+// Entries in allowed_operations may name a class or a command; a ":qualifier" suffix such as
+// "shell:readonly" is accepted and matches the command itself.
+func authorizeOperation(cmd *cobra.Command, file *config.File) error {
+	class, err := operationClass(cmd)
+	if err != nil {
+		return err
+	}
+
+	allowed, source := file.AllowedOperations, "the config file"
+	if envName, env, ok := file.Environment(environmentName, ActiveConfig.ClusterName); ok && len(env.AllowedOperations) > 0 {
+		allowed, source = env.AllowedOperations, fmt.Sprintf("environment %q in the config file", envName)
+	}
+	if len(allowed) == 0 || class == opLocal {
+		return nil
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()), " ")
+	for _, entry := range allowed {
+		entry = strings.SplitN(entry, ":", 2)[0]
+		if entry == class || entry == name {
+			return nil
+		}
+	}
+
+	path, _ := config.Path()
+	cmd.SilenceUsage = true // The command line is fine; usage would only bury the reason
+	return fmt.Errorf("%s is classed as %s, which is not permitted by allowed_operations in %s (%s)", name, class, source, path)
+}
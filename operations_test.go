@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestEveryCommandDeclaresOperationClass(t *testing.T) {
+	root := newRootCmd()
+	// Cobra adds these on Execute; add them now so they are checked too.
+	root.InitDefaultHelpCmd()
+	root.InitDefaultCompletionCmd()
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for _, sub := range cmd.Commands() {
+			if _, err := operationClass(sub); err != nil {
+				t.Error(err)
+			}
+			walk(sub)
+		}
+	}
+	walk(root)
+}
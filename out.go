@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"enum/aws"
+	"enum/config"
+)
+
+// defaultOutTokenEnv holds the bearer token for --out https://... unless the config names another variable.
+const defaultOutTokenEnv = "ENUM_OUT_TOKEN"
+
+// outHTTPTimeout bounds an --out HTTP upload.
+const outHTTPTimeout = 30 * time.Second
+
+// outStdout is where writeOut prints output it couldn't upload. Tests replace it.
+var outStdout io.Writer = os.Stdout
+
+// writeOut delivers structured output to dest: a local file, s3://bucket/key, or an
+// http(s) URL that accepts a PUT. Uploads that fail print the data to stdout instead,
+// with a warning, so it isn't lost; printed reports that this happened and the data
+// never reached dest.
+func writeOut(dest, contentType string, data []byte) (printed bool, err error) {
+	switch {
+	case strings.HasPrefix(dest, "s3://"):
+		bucket, key, _ := strings.Cut(strings.TrimPrefix(dest, "s3://"), "/")
+		if bucket == "" || key == "" {
+			return false, fmt.Errorf("invalid --out %q, want s3://bucket/key", dest)
+		}
+		err = aws.PutS3Object(bucket, key, data, contentType, outConfig().S3KMSKeyID, awsProfile)
+	case strings.HasPrefix(dest, "https://"), strings.HasPrefix(dest, "http://"):
+		err = putHTTP(dest, contentType, data)
+	default:
+		if err := os.WriteFile(dest, data, 0o644); err != nil {
+			return false, fmt.Errorf("unable to write %s: %v", dest, err)
+		}
+		return false, nil
+	}
+
+	if err != nil {
+		log.Printf("Warning: %v; printing the output instead", err)
+		_, err = outStdout.Write(data)
+		return true, err
+	}
+	log.Printf("Output uploaded to %s", dest)
+	return false, nil
+}
+
+// outConfig returns the upload settings from the config file, empty when it has none.
+func outConfig() config.OutConfig {
+	if userConfig == nil || userConfig.Out == nil {
+		return config.OutConfig{}
+	}
+	return *userConfig.Out
+}
+
+// putHTTP uploads data to url with a PUT, authenticating with the bearer token in the
+// configured environment variable when it is set.
+func putHTTP(url, contentType string, data []byte) error {
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("invalid --out URL %q: %v", url, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	tokenEnv := outConfig().TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = defaultOutTokenEnv
+	}
+	if token := os.Getenv(tokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := (&http.Client{Timeout: outHTTPTimeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("error uploading to %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("error uploading to %s: %s %s", url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// outputJSON writes v as indented JSON to dest with writeOut, or to the capped stdout when dest is empty.
+func outputJSON(dest string, v any) error {
+	if dest == "" {
+		return printJSON(v)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = writeOut(dest, "application/json", append(data, '\n'))
+	return err
+}
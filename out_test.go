@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// captureOutStdout collects what writeOut prints instead of uploading.
+func captureOutStdout(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	old := outStdout
+	t.Cleanup(func() { outStdout = old })
+	outStdout = &buf
+	return &buf
+}
+
+func TestWriteOutHTTP(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		wantPrinted bool
+	}{
+		{name: "accepted", status: http.StatusOK},
+		{name: "created", status: http.StatusCreated},
+		{name: "rejected", status: http.StatusForbidden, wantPrinted: true},
+		{name: "server error", status: http.StatusInternalServerError, wantPrinted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(defaultOutTokenEnv, "secret")
+			var gotBody, gotAuth, gotType string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPut {
+					t.Errorf("method = %s, want PUT", r.Method)
+				}
+				body, _ := io.ReadAll(r.Body)
+				gotBody, gotAuth, gotType = string(body), r.Header.Get("Authorization"), r.Header.Get("Content-Type")
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+			stdout := captureOutStdout(t)
+
+			printed, err := writeOut(server.URL+"/inspect.json", "application/json", []byte(`{"id":"abc"}`))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if printed != tt.wantPrinted {
+				t.Errorf("printed = %v, want %v", printed, tt.wantPrinted)
+			}
+			if gotBody != `{"id":"abc"}` || gotAuth != "Bearer secret" || gotType != "application/json" {
+				t.Errorf("server got body %q, auth %q, type %q", gotBody, gotAuth, gotType)
+			}
+			wantStdout := ""
+			if tt.wantPrinted {
+				wantStdout = `{"id":"abc"}`
+			}
+			if stdout.String() != wantStdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), wantStdout)
+			}
+		})
+	}
+}
+
+func TestWriteOutUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+	stdout := captureOutStdout(t)
+
+	printed, err := writeOut(url, "text/csv", []byte("a,b\n"))
+	if err != nil || !printed {
+		t.Fatalf("writeOut = %v, %v; want printed, nil", printed, err)
+	}
+	if stdout.String() != "a,b\n" {
+		t.Errorf("stdout = %q, want the data", stdout.String())
+	}
+}
+
+func TestWriteOutFile(t *testing.T) {
+	stdout := captureOutStdout(t)
+	path := filepath.Join(t.TempDir(), "out.json")
+
+	printed, err := writeOut(path, "application/json", []byte("{}\n"))
+	if err != nil || printed {
+		t.Fatalf("writeOut = %v, %v; want not printed, nil", printed, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{}\n" {
+		t.Errorf("file holds %q, want %q", data, "{}\n")
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("printed %q for a local file", stdout.String())
+	}
+
+	if _, err := writeOut(filepath.Join(path, "nested"), "application/json", nil); err == nil {
+		t.Error("expected an error writing beneath a file")
+	}
+}
+
+func TestWriteOutInvalidS3(t *testing.T) {
+	for _, dest := range []string{"s3://bucket", "s3:///key", "s3://"} {
+		if _, err := writeOut(dest, "application/json", nil); err == nil || !strings.Contains(err.Error(), "want s3://bucket/key") {
+			t.Errorf("writeOut(%q) err = %v, want an invalid --out error", dest, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultMaxOutput is the default --max-output, enough for any sane inspect document.
+const defaultMaxOutput = "10MiB"
+
+// maxOutput is the --max-output value; "0" disables the cap.
+var maxOutput = defaultMaxOutput
+
+// runningCommand is the command being run, so the truncation trailer can suggest only
+// flags it has. Set in the root command's PersistentPreRunE.
+var runningCommand *cobra.Command
+
+// limitWriter passes through the first limit bytes written to it and counts the rest.
+// Writes always report success so callers render as usual while the excess is dropped.
+type limitWriter struct {
+	w       io.Writer
+	limit   int64 // 0 means unlimited
+	written int64
+	omitted int64
+	hint    string // How to get the full output, ending the trailer
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if l.limit <= 0 {
+		return l.w.Write(p)
+	}
+	remaining := l.limit - l.written
+	if remaining <= 0 {
+		l.omitted += int64(len(p))
+		return len(p), nil
+	}
+	keep := p
+	if int64(len(p)) > remaining {
+		keep = p[:remaining]
+	}
+	n, err := l.w.Write(keep)
+	l.written += int64(n)
+	if err != nil {
+		return n, err
+	}
+	l.omitted += int64(len(p) - len(keep))
+	return len(p), nil
+}
+
+// Close writes the truncation trailer, if anything was dropped.
+func (l *limitWriter) Close() error {
+	if l.omitted == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(l.w, "\n[enum] output truncated at %s: %d bytes omitted. %s\n",
+		formatBytes(l.limit), l.omitted, l.hint)
+	return err
+}
+
+// truncationHint tells the user how to get the full output of cmd: with its --out flag
+// when it has one, otherwise by lifting the cap and redirecting stdout.
+func truncationHint(cmd *cobra.Command) string {
+	if cmd != nil && cmd.Flags().Lookup("out") != nil {
+		return "Use --out FILE to write the full output, or raise --max-output."
+	}
+	return "Pass --max-output 0 and redirect stdout to a file for the full output."
+}
+
+// cappedStdout returns stdout limited to --max-output. Callers must Close it to print the trailer.
+// Streaming commands such as logs and shell write to stdout directly and are never capped.
+func cappedStdout() (*limitWriter, error) {
+	limit, err := parseMaxOutput(maxOutput)
+	if err != nil {
+		return nil, err
+	}
+	return &limitWriter{w: os.Stdout, limit: limit, hint: truncationHint(runningCommand)}, nil
+}
+
+// parseMaxOutput parses a --max-output size such as "10MiB" or "0".
+func parseMaxOutput(value string) (int64, error) {
+	if value == "0" {
+		return 0, nil
+	}
+	limit, err := parseSize(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --max-output %q: %v", value, err)
+	}
+	return limit, nil
+}
+
+// validateMaxOutput accepts the sizes parseMaxOutput understands.
+func validateMaxOutput(value string) error {
+	_, err := parseMaxOutput(value)
+	return err
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestLimitWriter(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       int64
+		writes      []string
+		wantKept    string
+		wantOmitted int64
+	}{
+		{name: "unlimited", limit: 0, writes: []string{"hello ", "world"}, wantKept: "hello world"},
+		{name: "under the limit", limit: 20, writes: []string{"hello ", "world"}, wantKept: "hello world"},
+		{name: "exactly the limit", limit: 11, writes: []string{"hello ", "world"}, wantKept: "hello world"},
+		{name: "split write", limit: 8, writes: []string{"hello ", "world"}, wantKept: "hello wo", wantOmitted: 3},
+		{name: "writes after the limit", limit: 6, writes: []string{"hello ", "world", "!"}, wantKept: "hello ", wantOmitted: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := &limitWriter{w: &buf, limit: tt.limit, hint: "HINT"}
+			for _, s := range tt.writes {
+				n, err := w.Write([]byte(s))
+				if err != nil || n != len(s) {
+					t.Fatalf("Write(%q) = %d, %v; want %d, nil", s, n, err, len(s))
+				}
+			}
+			if buf.String() != tt.wantKept {
+				t.Errorf("kept %q, want %q", buf.String(), tt.wantKept)
+			}
+			if w.omitted != tt.wantOmitted {
+				t.Errorf("omitted %d, want %d", w.omitted, tt.wantOmitted)
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			trailer := strings.TrimPrefix(buf.String(), tt.wantKept)
+			if tt.wantOmitted == 0 {
+				if trailer != "" {
+					t.Errorf("Close wrote %q with nothing omitted", trailer)
+				}
+				return
+			}
+			for _, want := range []string{"output truncated", formatBytes(tt.limit), "bytes omitted", "HINT"} {
+				if !strings.Contains(trailer, want) {
+					t.Errorf("trailer %q doesn't contain %q", trailer, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTruncationHint(t *testing.T) {
+	withOut := &cobra.Command{Use: "inspect"}
+	withOut.Flags().String("out", "", "")
+	withoutOut := &cobra.Command{Use: "drift"}
+
+	if hint := truncationHint(withOut); !strings.Contains(hint, "--out FILE") {
+		t.Errorf("hint for a command with --out = %q, want it to suggest --out", hint)
+	}
+	for _, cmd := range []*cobra.Command{withoutOut, nil} {
+		hint := truncationHint(cmd)
+		if strings.Contains(hint, "--out") {
+			t.Errorf("hint for a command without --out = %q, suggests --out", hint)
+		}
+		if !strings.Contains(hint, "--max-output 0") {
+			t.Errorf("hint = %q, want it to suggest --max-output 0", hint)
+		}
+	}
+}
+
+func TestParseMaxOutput(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "10MiB", want: 10 << 20},
+		{in: "1.5KiB", want: 1536},
+		{in: "500B", want: 500},
+		{in: "2MB", want: 2e6},
+		{in: "", wantErr: true},
+		{in: "10", wantErr: true},
+		{in: "lots", wantErr: true},
+		{in: "xMiB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseMaxOutput(tt.in)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "invalid --max-output") {
+					t.Fatalf("parseMaxOutput(%q) err = %v, want an invalid --max-output error", tt.in, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseMaxOutput(%q) = %d, %v; want %d, nil", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"enum/aws"
+
+	"golang.org/x/term"
+)
+
+// paletteActions are the actions offered by the interactive palette, in menu order.
+var paletteActions = []struct {
+	label   string
+	command string
+}{
+	{"Find containers", "find"},
+	{"List instances", "list-ec2"},
+	{"Follow container logs", "logs"},
+	{"Open a shell in a container", "shell"},
+}
+
+// isInteractiveTerminal reports whether both stdin and stdout are attached to a TTY.
+func isInteractiveTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// runPalette walks the user through picking a cluster, an action and a target,
+// and returns the equivalent command line arguments.
+func runPalette() ([]string, error) {
+	reader := bufio.NewReader(os.Stdin)
+
+	if ActiveConfig.ClusterName == "" {
+		clusters, err := cachedClusterNames()
+		if err != nil {
+			return nil, err
+		}
+		if len(clusters) == 0 {
+			return nil, fmt.Errorf("no ECS clusters found")
+		}
+		i, err := pick(reader, "Cluster", clusters)
+		if err != nil {
+			return nil, err
+		}
+		ActiveConfig.ClusterName = clusters[i]
+	}
+
+	var labels []string
+	for _, action := range paletteActions {
+		labels = append(labels, action.label)
+	}
+	i, err := pick(reader, "Action", labels)
+	if err != nil {
+		return nil, err
+	}
+	action := paletteActions[i].command
+	args := []string{"--cluster", ActiveConfig.ClusterName, action}
+
+	switch action {
+	case "find":
+		fmt.Print("Search term (leave blank for all containers): ")
+		searchTerm, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if searchTerm = strings.TrimSpace(searchTerm); searchTerm != "" {
+			args = append(args, searchTerm)
+		}
+	case "logs", "shell":
+		instances, err := fetchInstances(aws.RunningStates)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching EC2 instance data: %v", err)
+		}
+		records := scanContainers(instances, false)
+		if len(records) == 0 {
+			return nil, fmt.Errorf("no running containers found in cluster %s", ActiveConfig.ClusterName)
+		}
+		var choices []string
+		for _, record := range records {
+			choices = append(choices, fmt.Sprintf("%s (%s) on %s", record.Name, record.ID, record.Instance.Name))
+		}
+		i, err := pick(reader, "Container", choices)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, records[i].ID)
+	}
+
+	fmt.Printf("\nRunning: %s\n\n", commandLine(args))
+	return args, nil
+}
+
+// cachedClusterNames returns the cluster list, refreshing the on-disk cache when it is stale.
+func cachedClusterNames() ([]string, error) {
+	cacheName := clusterCacheName()
+	var clusters []string
+	if readCache(cacheName, clusterCacheTTL, &clusters) {
+		return clusters, nil
+	}
+
+	clients, err := aws.NewClients(awsProfile)
+	if err != nil {
+		return nil, err
+	}
+	clusters, err = aws.FetchECSClusterNames(clients.ECS)
+	if err != nil {
+		return nil, err
+	}
+	_ = writeCache(cacheName, "", clusters) // Caching is best effort
+	return clusters, nil
+}
+
+// pick prints a numbered menu and returns the index of the chosen entry.
+func pick(reader *bufio.Reader, title string, choices []string) (int, error) {
+	fmt.Printf("\n%s:\n", title)
+	for i, choice := range choices {
+		fmt.Printf("  %2d) %s\n", i+1, choice)
+	}
+
+	for {
+		fmt.Printf("Select %s [1-%d]: ", strings.ToLower(title), len(choices))
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return 0, fmt.Errorf("no %s selected", strings.ToLower(title))
+		}
+		if err != nil {
+			return 0, err
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(line))
+		if err == nil && n >= 1 && n <= len(choices) {
+			return n - 1, nil
+		}
+		fmt.Println("Invalid selection.")
+	}
+}
+
+// commandLine renders args as a copy-pasteable enum invocation.
+func commandLine(args []string) string {
+	quoted := []string{human_readable_comand_name}
+	for _, arg := range args {
+		if strings.ContainsAny(arg, " \t'\"") {
+			arg = strconv.Quote(arg)
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"enum/config"
+
+	"github.com/spf13/cobra"
+)
+
+// preferenceFlags maps preference keys to the command flags whose defaults they
+// set. Adding a preference only takes a new entry here.
+var preferenceFlags = []struct {
+	key      string
+	flag     string
+	commands []string
+	validate func(string) error
+}{
+	{key: "output", flag: "output", commands: []string{"list-ec2", "list-ecs"}, validate: oneOf("table", "json")},
+	{key: "logs.tail", flag: "tail", commands: []string{"logs"}, validate: validateTail},
+	{key: "max_output", flag: "max-output", commands: []string{"inspect", "list-ec2", "list-ecs", "stopped-tasks", "stopped", "ami-rollout", "api-describe", "drift-check", "cat"}, validate: validateMaxOutput},
+}
+
+// oneOf returns a validator accepting only the given values.
+func oneOf(values ...string) func(string) error {
+	return func(value string) error {
+		if !slices.Contains(values, value) {
+			return fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+		}
+		return nil
+	}
+}
+
+// validateTail accepts the values docker logs --tail understands.
+func validateTail(value string) error {
+	if _, err := strconv.Atoi(value); err != nil && value != "all" {
+		return fmt.Errorf("must be a number or \"all\"")
+	}
+	return nil
+}
+
+// applyPreferences sets the flags of cmd that weren't given explicitly to the user's preferred values.
+func applyPreferences(cmd *cobra.Command, preferences map[string]string) error {
+	for _, pref := range preferenceFlags {
+		value, ok := preferences[pref.key]
+		if !ok || !slices.Contains(pref.commands, cmd.Name()) {
+			continue
+		}
+		flag := cmd.Flags().Lookup(pref.flag)
+		if flag == nil || flag.Changed {
+			continue // Explicit flags always win
+		}
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid preference %s=%q: %v", pref.key, value, err)
+		}
+	}
+	return nil
+}
+
+// setPreference validates value for the preference and saves it to the config file.
+func setPreference(key, value string) error {
+	for _, pref := range preferenceFlags {
+		if pref.key != key {
+			continue
+		}
+		if err := pref.validate(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", key, err)
+		}
+
+		file, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if file.Preferences == nil {
+			file.Preferences = make(map[string]string)
+		}
+		file.Preferences[key] = value
+		return file.Save()
+	}
+	return fmt.Errorf("unknown preference %q", key)
+}
+
+// showConfig prints the config file location and the effective value of every preference.
+func showConfig(root *cobra.Command) error {
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+	file, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Config file: %s\n\n", path)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Preference\tValue\tSource\tFlag")
+	for _, pref := range preferenceFlags {
+		value, source := file.Preferences[pref.key], "preference"
+		if _, ok := file.Preferences[pref.key]; !ok {
+			source = "default"
+			if cmd, _, err := root.Find([]string{pref.commands[0]}); err == nil {
+				if flag := cmd.Flag(pref.flag); flag != nil {
+					value = flag.DefValue
+				}
+			}
+		}
+		for _, name := range pref.commands {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s --%s\n", pref.key, value, source, name, pref.flag)
+		}
+	}
+	w.Flush()
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"enum/aws"
+	"enum/ssh"
+)
+
+// probeTimeout caps each stage of a host probe.
+const probeTimeout = 5 * time.Second
+
+// Probe stages, in the order they run.
+const (
+	probeSSH    = "ssh"
+	probeSudo   = "sudo"
+	probeDocker = "docker"
+)
+
+// hostProbe is the outcome of probing a host before the first real command is sent to it.
+type hostProbe struct {
+	FailedStage string // Empty when every stage passed
+	Err         error
+	Runtime     string // runtimeDocker or runtimeContainerd, once the runtime stage passed
+}
+
+// Error describes the failed stage, so a warning says exactly what doesn't work.
+func (p hostProbe) Error() string {
+	switch p.FailedStage {
+	case probeSSH:
+		return fmt.Sprintf("SSH failed: %v", p.Err)
+	case probeSudo:
+		return fmt.Sprintf("SSH ok, sudo denied: %v", p.Err)
+	case probeDocker:
+		return fmt.Sprintf("SSH and sudo ok, container runtime not responding: %v", p.Err)
+	}
+	return "ok"
+}
+
+// Unwrap returns the failed stage's error.
+func (p hostProbe) Unwrap() error {
+	return p.Err
+}
+
+// probeCache keeps each host's probe for the rest of the process, so commands that
+// contact hosts in several phases only probe once. Hosts that couldn't be connected to
+// are tracked by hostRegistry instead.
+var probeCache = struct {
+	sync.Mutex
+	results map[string]hostProbe
+}{results: make(map[string]hostProbe)}
+
+// hostConn is the part of *ssh.Conn that probes and scans use, so tests can fake a host.
+type hostConn interface {
+	Run(command string) (ssh.CommandResult, error)
+	RunTimeout(command string, timeout time.Duration) (ssh.CommandResult, error)
+	Close() error
+}
+
+// connectHost opens an SSH connection to host. Tests replace it to avoid the network.
+var connectHost = func(host string) (hostConn, error) {
+	conn, err := ssh.Connect(host, verbose)
+	if err != nil {
+		return nil, err // Not conn: a nil *ssh.Conn would make a non-nil hostConn
+	}
+	return conn, nil
+}
+
+// connectProbed connects to the instance and, the first time in this process, checks in
+// turn that a command runs, that sudo works without a password and that the container
+// runtime answers. The returned error names the stage that failed; on success the
+// connection is ready for the caller's own commands and must be closed.
+func connectProbed(instance aws.InstanceData) (hostConn, hostProbe, error) {
+	if reason, skipped := hostRegistry.skip(instance.PrivateIP); skipped {
+		probe := hostProbe{FailedStage: probeSSH, Err: fmt.Errorf("skipped for the rest of the run: %s", reason)}
+		return nil, probe, probe
+	}
+	probeCache.Lock()
+	probe, probed := probeCache.results[instance.PrivateIP]
+	probeCache.Unlock()
+	if probed && probe.FailedStage != "" {
+		return nil, probe, probe
+	}
+
+	// Connection failures aren't cached: hostRegistry decides whether they are worth retrying.
+	conn, err := connectHost(instance.PrivateIP)
+	hostRegistry.record(instance.PrivateIP, err)
+	if err != nil {
+		probe = hostProbe{FailedStage: probeSSH, Err: err}
+		return nil, probe, probe
+	}
+	if probed {
+		return conn, probe, nil
+	}
+
+	probe = runProbe(conn)
+	storeProbe(instance.PrivateIP, probe)
+	if probe.FailedStage != "" {
+		conn.Close()
+		return nil, probe, probe
+	}
+	return conn, probe, nil
+}
+
+// runProbe runs the probe stages on conn, stopping at the first that fails.
+func runProbe(conn hostConn) hostProbe {
+	stages := []struct {
+		name    string
+		command string
+	}{
+		{probeSSH, "true"},
+		{probeSudo, "sudo -n true"},
+		{probeDocker, runtimeCommand(func(cli string) string {
+			return "sudo -n " + cli + " version --format '{{.Server.Version}}'"
+		})},
+	}
+
+	var probe hostProbe
+	for _, stage := range stages {
+		result, err := conn.RunTimeout(stage.command, probeTimeout)
+		if err == nil && result.ExitCode != 0 {
+			err = errors.New(firstLine(result.Stderr, fmt.Sprintf("exit status %d", result.ExitCode)))
+		}
+		if err != nil {
+			return hostProbe{FailedStage: stage.name, Err: err}
+		}
+		if stage.name == probeDocker {
+			probe.Runtime, _ = splitRuntimeOutput(result.Stdout)
+		}
+	}
+	return probe
+}
+
+func storeProbe(host string, probe hostProbe) {
+	probeCache.Lock()
+	defer probeCache.Unlock()
+	probeCache.results[host] = probe
+}
+
+// firstLine returns the first non-empty line of s, or fallback when there is none.
+func firstLine(s, fallback string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"enum/aws"
+	"enum/ssh"
+)
+
+// probeStage names the probe stage a command sent by runProbe belongs to.
+func probeStage(command string) string {
+	switch {
+	case command == "true":
+		return probeSSH
+	case command == "sudo -n true":
+		return probeSudo
+	case strings.Contains(command, " version "):
+		return probeDocker
+	}
+	return ""
+}
+
+func TestRunProbe(t *testing.T) {
+	timedOut := fmt.Errorf("command 'true' %w after 5s", ssh.ErrTimeout)
+	tests := []struct {
+		name    string
+		fail    string // Stage whose command fails
+		result  ssh.CommandResult
+		err     error
+		stage   string
+		message string
+		runs    int // Probe commands sent before stopping
+	}{
+		{name: "ssh times out", fail: probeSSH, err: timedOut, stage: probeSSH, message: "SSH failed", runs: 1},
+		{name: "sudo wants a password", fail: probeSudo, result: ssh.CommandResult{Stderr: "sudo: a password is required\n", ExitCode: 1}, stage: probeSudo, message: "sudo denied: sudo: a password is required", runs: 2},
+		{name: "docker hangs", fail: probeDocker, err: timedOut, stage: probeDocker, message: "container runtime not responding", runs: 3},
+		{name: "docker exits without stderr", fail: probeDocker, result: ssh.CommandResult{ExitCode: 1}, stage: probeDocker, message: "exit status 1", runs: 3},
+		{name: "every stage passes", runs: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := healthyHost()
+			healthy := conn.respond
+			conn.respond = func(command string) (ssh.CommandResult, error) {
+				if tt.fail != "" && probeStage(command) == tt.fail {
+					return tt.result, tt.err
+				}
+				return healthy(command)
+			}
+			instance := aws.InstanceData{PrivateIP: "10.0.0.9"}
+			setUpHosts(t, map[string]*fakeConn{instance.PrivateIP: conn})
+
+			_, probe, err := connectProbed(instance)
+			if probe.FailedStage != tt.stage {
+				t.Errorf("failed stage = %q, want %q", probe.FailedStage, tt.stage)
+			}
+			if tt.stage == "" {
+				if err != nil || probe.Runtime != runtimeDocker {
+					t.Errorf("probe = %+v, %v; want docker and no error", probe, err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.message)
+			}
+			if tt.err != nil && !errors.Is(err, ssh.ErrTimeout) {
+				t.Errorf("error %v doesn't wrap ssh.ErrTimeout", err)
+			}
+
+			if len(conn.timeouts) != tt.runs {
+				t.Errorf("sent %d probe commands, want %d: %v", len(conn.timeouts), tt.runs, conn.timeouts)
+			}
+			for command, timeout := range conn.timeouts {
+				if timeout != probeTimeout {
+					t.Errorf("%q ran with timeout %v, want %v", command, timeout, probeTimeout)
+				}
+			}
+
+			probeCache.Lock()
+			cached, ok := probeCache.results[instance.PrivateIP]
+			probeCache.Unlock()
+			if !ok || cached.FailedStage != tt.stage {
+				t.Errorf("probeCache has %+v (present %v), want failed stage %q", cached, ok, tt.stage)
+			}
+
+			// A cached failure is returned without connecting again.
+			if tt.stage != "" {
+				connectHost = func(host string) (hostConn, error) {
+					t.Fatalf("reconnected to %s after a cached probe failure", host)
+					return nil, nil
+				}
+				if _, again, _ := connectProbed(instance); again.FailedStage != tt.stage {
+					t.Errorf("second probe failed stage = %q, want %q", again.FailedStage, tt.stage)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"enum/aws"
+	"enum/ssh"
+)
+
+// pushAllowedDirs are the remote directories push writes to without --allow-any-path.
+var pushAllowedDirs = []string{"/tmp", "/home"}
+
+// pushedFile records a file left on a host by push so push --cleanup can remove it.
+type pushedFile struct {
+	Cluster     string    `json:"cluster"`
+	InstanceID  string    `json:"instance_id"`
+	Name        string    `json:"name"`
+	PrivateIP   string    `json:"private_ip"`
+	Path        string    `json:"path"`
+	RemoveAfter time.Time `json:"remove_after"`
+}
+
+// pushOptions are the flags of the push command.
+type pushOptions struct {
+	Mode         string
+	Filter       string
+	AllowAnyPath bool
+	CleanupAfter time.Duration
+}
+
+// push copies localFile to remotePath on every running instance whose name contains
+// opts.Filter, verifies the copy's checksum and reports the outcome per host.
+func push(localFile, remotePath string, opts pushOptions) error {
+	mode, err := strconv.ParseUint(opts.Mode, 8, 32)
+	if err != nil || mode > 0o7777 {
+		return fmt.Errorf("invalid --mode %q, want an octal mode such as 0755", opts.Mode)
+	}
+	remotePath, err = checkPushPath(remotePath, opts.AllowAnyPath)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(localFile)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	var selected []aws.InstanceData
+	for _, instance := range instances {
+		if instance.PrivateIP != "" && strings.Contains(instance.Name, opts.Filter) {
+			selected = append(selected, instance)
+		}
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no running instances match %q", opts.Filter)
+	}
+
+	// Write to a temporary name and rename, so a half-copied file never sits at remotePath.
+	staging := remotePath + ".enum-push"
+	copyCmd := fmt.Sprintf("cat > %[1]s && chmod %[2]o %[1]s && mv -f %[1]s %[3]s && sha256sum %[3]s",
+		ssh.ShellQuote(staging), mode, ssh.ShellQuote(remotePath))
+	if opts.CleanupAfter > 0 {
+		// Best effort: hosts without at still get the file, and push --cleanup removes it later.
+		minutes := int((opts.CleanupAfter + time.Minute - 1) / time.Minute)
+		copyCmd += fmt.Sprintf(" && { echo %s | at now + %d minutes >/dev/null 2>&1 || true; }",
+			ssh.ShellQuote("rm -f "+ssh.ShellQuote(remotePath)), minutes)
+	}
+
+	failures := make([]string, len(selected))
+	hostScheduler.Run(len(selected), func(i int) {
+		failures[i] = pushToHost(selected[i], copyCmd, content, checksum)
+	})
+
+	var failed int
+	var pushed []pushedFile
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Instance ID\tName\tResult")
+	for i, instance := range selected {
+		result := "ok"
+		if failures[i] != "" {
+			result = "failed: " + failures[i]
+			failed++
+		} else if opts.CleanupAfter > 0 {
+			pushed = append(pushed, pushedFile{
+				Cluster:     instance.Cluster,
+				InstanceID:  instance.InstanceID,
+				Name:        instance.Name,
+				PrivateIP:   instance.PrivateIP,
+				Path:        remotePath,
+				RemoveAfter: time.Now().Add(opts.CleanupAfter),
+			})
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", instance.InstanceID, instance.Name, result)
+	}
+	w.Flush()
+
+	if len(pushed) > 0 {
+		records, err := loadPushedFiles()
+		if err != nil {
+			return err
+		}
+		if err := savePushedFiles(append(records, pushed...)); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("push failed on %d of %d instances", failed, len(selected))
+	}
+	return nil
+}
+
+// pushToHost runs copyCmd on the instance with content as its input and checks the
+// checksum it prints. It returns why the push failed, or "" when it succeeded.
+func pushToHost(instance aws.InstanceData, copyCmd string, content []byte, checksum string) string {
+	conn, err := ssh.Connect(instance.PrivateIP, verbose)
+	if err != nil {
+		return err.Error()
+	}
+	defer conn.Close()
+
+	result, err := conn.RunInput(copyCmd, bytes.NewReader(content))
+	if err != nil {
+		return err.Error()
+	}
+	if result.ExitCode != 0 {
+		return fmt.Sprintf("exit status %d: %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	if remote, _, _ := strings.Cut(strings.TrimSpace(result.Stdout), " "); remote != checksum {
+		return fmt.Sprintf("checksum mismatch: remote %s, local %s", remote, checksum)
+	}
+	return ""
+}
+
+// checkPushPath cleans remotePath and, unless allowAny is set, refuses paths outside pushAllowedDirs.
+func checkPushPath(remotePath string, allowAny bool) (string, error) {
+	if !path.IsAbs(remotePath) {
+		return "", fmt.Errorf("remote path %q must be absolute", remotePath)
+	}
+	cleaned := path.Clean(remotePath)
+	if allowAny {
+		return cleaned, nil
+	}
+	for _, dir := range pushAllowedDirs {
+		if strings.HasPrefix(cleaned, dir+"/") {
+			return cleaned, nil
+		}
+	}
+	return "", fmt.Errorf("remote path %s is outside %s; use --allow-any-path to push there anyway",
+		cleaned, strings.Join(pushAllowedDirs, " and "))
+}
+
+// pushCleanup removes the files push --cleanup-after left on the cluster's hosts,
+// forgetting the ones it removed or that are already gone.
+func pushCleanup(cluster string) error {
+	records, err := loadPushedFiles()
+	if err != nil {
+		return err
+	}
+
+	var remaining []pushedFile
+	var removed int
+	for _, record := range records {
+		if record.Cluster != cluster {
+			remaining = append(remaining, record)
+			continue
+		}
+		if _, err := ssh.SSHCommand(record.PrivateIP, "rm -f "+ssh.ShellQuote(record.Path), verbose); err != nil {
+			fmt.Printf("%s (%s): failed to remove %s: %v\n", record.Name, record.InstanceID, record.Path, err)
+			remaining = append(remaining, record)
+			continue
+		}
+		fmt.Printf("%s (%s): removed %s\n", record.Name, record.InstanceID, record.Path)
+		removed++
+	}
+	if removed == 0 && len(remaining) == len(records) {
+		fmt.Printf("No pushed files recorded for cluster %s.\n", cluster)
+	}
+	return savePushedFiles(remaining)
+}
+
+// pushedFilesPath returns where push records files to clean up, next to the config file.
+func pushedFilesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to find config directory: %v", err)
+	}
+	return filepath.Join(dir, "enum", "pushed.json"), nil
+}
+
+// loadPushedFiles returns the recorded pushed files, none when the record doesn't exist yet.
+func loadPushedFiles() ([]pushedFile, error) {
+	path, err := pushedFilesPath()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []pushedFile
+	if err := json.Unmarshal(raw, &records); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	return records, nil
+}
+
+// savePushedFiles replaces the record of pushed files.
+func savePushedFiles(records []pushedFile) error {
+	path, err := pushedFilesPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0o600)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"enum/config"
+
+	"github.com/spf13/cobra"
+)
+
+// operatorReason is --reason, the operator's note on why they run a mutating command,
+// such as "INC-1234 rolling bad config".
+var operatorReason string
+
+// protectedEnvironment names the active environment when it is protected and the running
+// command mutates it, so confirmChange knows to insist on --reason. Set by requireReason.
+var protectedEnvironment string
+
+// requireReason notes whether cmd mutates a protected environment and, if so, refuses it
+// without --reason before it contacts AWS. confirmChange checks again when the command
+// asks before changing anything.
+func requireReason(cmd *cobra.Command, file *config.File) error {
+	class, err := operationClass(cmd)
+	if err != nil {
+		return err
+	}
+	protectedEnvironment = ""
+	if !strings.HasPrefix(class, "mutate-") {
+		return nil
+	}
+	if envName, env, ok := file.Environment(environmentName, ActiveConfig.ClusterName); ok && env.Protected {
+		protectedEnvironment = envName
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()), " ")
+	if err := missingReason(name + " is classed as " + class + " and"); err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+	return nil
+}
+
+// missingReason returns the error for changing a protected environment without --reason,
+// or nil. what starts the message, e.g. "restart-all is classed as mutate-container and".
+func missingReason(what string) error {
+	if protectedEnvironment == "" || strings.TrimSpace(operatorReason) != "" {
+		return nil
+	}
+	return fmt.Errorf("%s environment %q is protected; say why with --reason, e.g. --reason \"INC-1234 rolling bad config\"", what, protectedEnvironment)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os/user"
+	"regexp"
+	"strings"
+
+	"enum/config"
+	"enum/ssh"
+)
+
+// remoteEnvPlaceholder matches a {name} template in a remote_env value.
+var remoteEnvPlaceholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// remoteEnvName matches the variable names a POSIX shell accepts.
+var remoteEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// resolveRemoteEnv expands the configured remote_env for the active environment and hands it
+// to the ssh package, which sets it on every remote command so host-side audit hooks can see
+// who ran what. A --reason is added as ENUM_REASON unless remote_env sets that itself.
+func resolveRemoteEnv(file *config.File) error {
+	envName, env, _ := file.Environment(environmentName, ActiveConfig.ClusterName)
+
+	values := make(map[string]string)
+	for key, value := range file.RemoteEnv {
+		values[key] = value
+	}
+	for key, value := range env.RemoteEnv {
+		values[key] = value
+	}
+	if len(values) == 0 && operatorReason == "" {
+		return nil
+	}
+
+	localUser := ""
+	if current, err := user.Current(); err == nil {
+		localUser = current.Username
+	}
+	expanded, err := expandRemoteEnv(values, map[string]string{
+		"local_user": localUser,
+		"cluster":    ActiveConfig.ClusterName,
+		"env":        envName,
+		"reason":     operatorReason,
+	})
+	if err != nil {
+		return err
+	}
+	if _, ok := expanded["ENUM_REASON"]; !ok && operatorReason != "" {
+		expanded["ENUM_REASON"] = operatorReason
+	}
+	ssh.SetRemoteEnv(expanded)
+	return nil
+}
+
+// expandRemoteEnv replaces the {name} templates in values with vars. Unknown templates
+// and names that aren't valid shell variables are errors rather than being sent as-is.
+func expandRemoteEnv(values, vars map[string]string) (map[string]string, error) {
+	expanded := make(map[string]string, len(values))
+	for key, value := range values {
+		if !remoteEnvName.MatchString(key) {
+			return nil, fmt.Errorf("invalid remote_env name %q", key)
+		}
+		var unknown []string
+		expanded[key] = remoteEnvPlaceholder.ReplaceAllStringFunc(value, func(placeholder string) string {
+			if v, ok := vars[strings.Trim(placeholder, "{}")]; ok {
+				return v
+			}
+			unknown = append(unknown, placeholder)
+			return placeholder
+		})
+		if len(unknown) > 0 {
+			return nil, fmt.Errorf("unknown template %s in remote_env %s", strings.Join(unknown, ", "), key)
+		}
+	}
+	return expanded, nil
+}
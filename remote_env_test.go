@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandRemoteEnv(t *testing.T) {
+	vars := map[string]string{"local_user": "alice", "cluster": "prod", "env": "production", "reason": "INC-1 it's {env}"}
+	tests := []struct {
+		name    string
+		values  map[string]string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:   "static and templated",
+			values: map[string]string{"ENUM_OPERATOR": "{local_user}", "ENUM_TARGET": "{cluster}/{env}", "TEAM": "sre"},
+			want:   map[string]string{"ENUM_OPERATOR": "alice", "ENUM_TARGET": "prod/production", "TEAM": "sre"},
+		},
+		{
+			name:   "substituted values are not expanded again",
+			values: map[string]string{"ENUM_REASON": "{reason}"},
+			want:   map[string]string{"ENUM_REASON": "INC-1 it's {env}"},
+		},
+		{
+			name:   "text that isn't a template is kept",
+			values: map[string]string{"JSON": `{"a": 1} {Cluster}`},
+			want:   map[string]string{"JSON": `{"a": 1} {Cluster}`},
+		},
+		{
+			name:    "unknown template",
+			values:  map[string]string{"X": "{hostname}"},
+			wantErr: "unknown template {hostname}",
+		},
+		{
+			name:    "invalid name",
+			values:  map[string]string{"BAD-NAME": "x"},
+			wantErr: "invalid remote_env name",
+		},
+		{
+			name:    "name that would inject shell",
+			values:  map[string]string{"A=1; reboot; B": "x"},
+			wantErr: "invalid remote_env name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandRemoteEnv(tt.values, vars)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"enum/aws"
+)
+
+// healthPollInterval is how often restarted containers are checked while waiting for them.
+const healthPollInterval = 5 * time.Second
+
+// describeTask and setTaskProtection are the ECS calls behind --protect. Tests replace them.
+var (
+	describeTask      = aws.DescribeTask
+	setTaskProtection = aws.SetTaskProtection
+)
+
+// restartAll restarts every running container matching searchTerm, batch at a time,
+// waiting up to waitHealthy for each batch to become healthy before starting the next.
+// It returns an error, aborting the rollout, as soon as a batch fails to come back or
+// on Ctrl+C. A non-zero protect is how long to hold ECS scale-in protection on each
+// batch's service tasks; it must outlast the batch's wait.
+func restartAll(searchTerm string, batch int, waitHealthy, minUp, protect time.Duration, assumeYes bool) error {
+	if batch < 1 {
+		return fmt.Errorf("--batch must be at least 1")
+	}
+	if protect > 0 && protect < waitHealthy {
+		return fmt.Errorf("--protect-window must be at least --wait-healthy (%s)", waitHealthy)
+	}
+
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	var targets []containerRecord
+	for _, record := range scanContainers(instances, false) {
+		if len(matchingTerms(record, []string{searchTerm})) > 0 {
+			targets = append(targets, record)
+		}
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no running containers match %q", searchTerm)
+	}
+
+	fmt.Printf("%d containers will be restarted, %d at a time:\n", len(targets), batch)
+	for _, target := range targets {
+		fmt.Printf("  %s  %s  %s\n", target.Instance.Name, target.ID, target.Name)
+	}
+	if err := confirmChange("Restart these containers?", assumeYes); err == errNotConfirmed {
+		return fmt.Errorf("restart cancelled")
+	} else if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	batches := (len(targets) + batch - 1) / batch
+	for i := 0; i < len(targets); i += batch {
+		current := targets[i:min(i+batch, len(targets))]
+		fmt.Printf("\nBatch %d/%d:\n", i/batch+1, batches)
+		if err := restartContainers(ctx, current, waitHealthy, minUp, protect); err != nil {
+			return fmt.Errorf("aborting rollout: %v", err)
+		}
+	}
+
+	fmt.Printf("\nRestarted %d containers.\n", len(targets))
+	return nil
+}
+
+// restartContainers restarts a batch of containers and waits until waitHealthy from the start
+// of the batch for all of them to become healthy. When protect is set, their service tasks
+// are protected from scale-in first, so ECS doesn't replace a task while docker restarts its
+// container, and released again however the batch ends, cancelling ctx included. As the
+// protection is set after the deadline, a protect of at least waitHealthy outlasts the wait.
+func restartContainers(ctx context.Context, current []containerRecord, waitHealthy, minUp, protect time.Duration) error {
+	deadline := time.Now().Add(waitHealthy)
+	if protect > 0 {
+		protected, err := protectTasks(current, protect)
+		defer releaseTasks(protected)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, target := range current {
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted")
+		}
+		if _, err := sshCommand(target.Instance.PrivateIP, "sudo "+containerCLI(target.Instance)+" restart "+target.ID, false); err != nil {
+			return fmt.Errorf("restarting %s on %s failed: %v", target.Name, target.Instance.Name, err)
+		}
+		fmt.Printf("  restarted %s on %s\n", target.Name, target.Instance.Name)
+	}
+	for _, target := range current {
+		if err := waitForHealthy(ctx, target, deadline, waitHealthy, minUp); err != nil {
+			return fmt.Errorf("%s on %s: %v", target.Name, target.Instance.Name, err)
+		}
+		fmt.Printf("  %s on %s is healthy\n", target.Name, target.Instance.Name)
+	}
+	return nil
+}
+
+// protectedTask is an ECS task whose scale-in protection restartContainers turned on.
+type protectedTask struct {
+	Cluster string
+	TaskArn string
+}
+
+// protectTasks turns on scale-in protection for window on the service tasks running the
+// targets. Containers that ECS didn't start, or whose tasks don't belong to a service,
+// have nothing to protect them from and are noted and skipped. The tasks protected so
+// far are returned even on error, so the caller can release them.
+func protectTasks(targets []containerRecord, window time.Duration) ([]protectedTask, error) {
+	var protected []protectedTask
+	for _, target := range targets {
+		cmd := fmt.Sprintf("sudo %s inspect --format '{{index .Config.Labels %q}}\t{{index .Config.Labels %q}}' %s",
+			containerCLI(target.Instance), "com.amazonaws.ecs.cluster", "com.amazonaws.ecs.task-arn", target.ID)
+		output, err := sshCommand(target.Instance.PrivateIP, cmd, false)
+		if err != nil {
+			return protected, fmt.Errorf("reading the task of %s on %s failed: %v", target.Name, target.Instance.Name, err)
+		}
+		cluster, taskArn, _ := strings.Cut(strings.TrimSpace(output), "\t")
+		if taskArn == "" {
+			fmt.Printf("  %s on %s wasn't started by ECS; restarting it without task protection\n", target.Name, target.Instance.Name)
+			continue
+		}
+
+		task, err := describeTask(cluster, taskArn, awsProfile)
+		if err != nil {
+			return protected, err
+		}
+		if !strings.HasPrefix(task.Group, "service:") {
+			fmt.Printf("  %s on %s isn't part of a service; restarting it without task protection\n", target.Name, target.Instance.Name)
+			continue
+		}
+
+		if err := setTaskProtection(cluster, taskArn, window, awsProfile); err != nil {
+			return protected, err
+		}
+		protected = append(protected, protectedTask{Cluster: cluster, TaskArn: taskArn})
+		fmt.Printf("  protected task %s of %s for %s\n", taskID(taskArn), strings.TrimPrefix(task.Group, "service:"), window)
+	}
+	return protected, nil
+}
+
+// releaseTasks turns scale-in protection back off. Failures are only logged: the
+// protection still lapses when its window runs out.
+func releaseTasks(tasks []protectedTask) {
+	for _, task := range tasks {
+		if err := setTaskProtection(task.Cluster, task.TaskArn, 0, awsProfile); err != nil {
+			log.Printf("Error releasing protection of task %s, it lapses on its own: %v", taskID(task.TaskArn), err)
+			continue
+		}
+		fmt.Printf("  released protection of task %s\n", taskID(task.TaskArn))
+	}
+}
+
+// waitForHealthy polls a restarted container until docker reports it healthy or, when it
+// has no healthcheck, until it has stayed up for minUp. It gives up at deadline, timeout
+// being the --wait-healthy it was derived from, or when ctx is cancelled.
+func waitForHealthy(ctx context.Context, target containerRecord, deadline time.Time, timeout, minUp time.Duration) error {
+	cmd := "sudo " + containerCLI(target.Instance) + " inspect --format '{{.State.Status}}\t{{if .State.Health}}{{.State.Health.Status}}{{end}}\t{{.State.StartedAt}}' " + target.ID
+	last := "unknown"
+	for {
+		output, err := sshCommand(target.Instance.PrivateIP, cmd, false)
+		if err != nil {
+			return err
+		}
+		ready, state, err := containerReady(strings.TrimSpace(output), minUp, time.Now())
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+		last = state
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return fmt.Errorf("not healthy after %s (last state: %s)", timeout, last)
+		}
+		timer := time.NewTimer(min(wait, healthPollInterval))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("interrupted (last state: %s)", last)
+		case <-timer.C:
+		}
+	}
+}
+
+// containerReady interprets a status, health and start time line from docker inspect.
+// It fails fast when the container is no longer running or docker reports it unhealthy.
+func containerReady(line string, minUp time.Duration, now time.Time) (bool, string, error) {
+	parts := strings.Split(line, "\t")
+	if len(parts) < 3 {
+		return false, "", fmt.Errorf("unexpected inspect output %q", line)
+	}
+	status, health := parts[0], parts[1]
+	if status != "running" && status != "restarting" {
+		return false, status, fmt.Errorf("container is %s", status)
+	}
+
+	switch health {
+	case "healthy":
+		return true, health, nil
+	case "unhealthy":
+		return false, health, fmt.Errorf("container is unhealthy")
+	case "starting":
+		return false, health, nil
+	}
+
+	// No healthcheck: settle for the container staying up long enough.
+	startedAt, err := time.Parse(time.RFC3339Nano, parts[2])
+	if err != nil {
+		return false, status, fmt.Errorf("unable to parse start time %q", parts[2])
+	}
+	up := now.Sub(startedAt)
+	return status == "running" && up >= minUp, fmt.Sprintf("%s for %s", status, up.Round(time.Second)), nil
+}
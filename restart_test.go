@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"enum/aws"
+)
+
+// fakeRestartHosts answers restart-all's remote commands. health is the status and health
+// docker inspect reports for every container; onHealthPoll runs before each health check.
+type fakeRestartHosts struct {
+	mu           sync.Mutex
+	health       string
+	onHealthPoll func()
+	restarted    []string
+	protection   []time.Duration // Windows passed to setTaskProtection, 0 releasing
+}
+
+func setUpRestart(t *testing.T, hosts *fakeRestartHosts) {
+	t.Helper()
+	previousSSH, previousDescribe, previousProtect := sshCommand, describeTask, setTaskProtection
+	t.Cleanup(func() {
+		sshCommand, describeTask, setTaskProtection = previousSSH, previousDescribe, previousProtect
+	})
+	sshCommand = func(host, command string, verbose bool) (string, error) {
+		hosts.mu.Lock()
+		defer hosts.mu.Unlock()
+		switch {
+		case strings.Contains(command, " restart "):
+			hosts.restarted = append(hosts.restarted, command[strings.LastIndex(command, " ")+1:])
+			return "", nil
+		case strings.Contains(command, "com.amazonaws.ecs.task-arn"):
+			return "prod\tarn:aws:ecs:us-west-2:1:task/prod/" + command[strings.LastIndex(command, " ")+1:] + "\n", nil
+		default:
+			if hosts.onHealthPoll != nil {
+				hosts.onHealthPoll()
+			}
+			return hosts.health + "\t" + time.Now().Add(-time.Hour).Format(time.RFC3339Nano) + "\n", nil
+		}
+	}
+	describeTask = func(cluster, taskArn, awsProfile string) (aws.TaskDetail, error) {
+		return aws.TaskDetail{TaskArn: taskArn, Group: "service:web"}, nil
+	}
+	setTaskProtection = func(cluster, taskArn string, window time.Duration, awsProfile string) error {
+		hosts.mu.Lock()
+		defer hosts.mu.Unlock()
+		hosts.protection = append(hosts.protection, window)
+		return nil
+	}
+}
+
+func restartTargets(ids ...string) []containerRecord {
+	var targets []containerRecord
+	for _, id := range ids {
+		targets = append(targets, containerRecord{Instance: aws.InstanceData{Name: "web-1", PrivateIP: "10.0.0.1"}, ID: id, Name: "web-" + id})
+	}
+	return targets
+}
+
+func TestRestartContainersHealthy(t *testing.T) {
+	hosts := &fakeRestartHosts{health: "running\thealthy"}
+	setUpRestart(t, hosts)
+
+	if err := restartContainers(context.Background(), restartTargets("a", "b"), time.Minute, time.Second, 10*time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(hosts.restarted, ",") != "a,b" {
+		t.Errorf("restarted %v, want a and b", hosts.restarted)
+	}
+	want := []time.Duration{10 * time.Minute, 10 * time.Minute, 0, 0}
+	if len(hosts.protection) != len(want) {
+		t.Fatalf("protection calls %v, want %v", hosts.protection, want)
+	}
+	for i := range want {
+		if hosts.protection[i] != want[i] {
+			t.Errorf("protection calls %v, want %v", hosts.protection, want)
+			break
+		}
+	}
+}
+
+func TestRestartContainersInterruptReleasesProtection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hosts := &fakeRestartHosts{health: "running\tstarting", onHealthPoll: cancel}
+	setUpRestart(t, hosts)
+
+	start := time.Now()
+	err := restartContainers(ctx, restartTargets("a"), time.Minute, time.Second, 10*time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "interrupted") {
+		t.Fatalf("err = %v, want interrupted", err)
+	}
+	if elapsed := time.Since(start); elapsed >= healthPollInterval {
+		t.Errorf("took %s to notice the interrupt", elapsed)
+	}
+	if len(hosts.protection) != 2 || hosts.protection[1] != 0 {
+		t.Errorf("protection calls %v, want it set and then released", hosts.protection)
+	}
+}
+
+func TestRestartContainersSharesTheBatchDeadline(t *testing.T) {
+	hosts := &fakeRestartHosts{health: "running\tstarting"}
+	setUpRestart(t, hosts)
+
+	// Waiting on each container in turn must not give every one of them the full
+	// --wait-healthy, or the batch would outlast a --protect-window of the same length.
+	const waitHealthy = 300 * time.Millisecond
+	start := time.Now()
+	err := restartContainers(context.Background(), restartTargets("a", "b", "c"), waitHealthy, time.Second, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "not healthy after 300ms") {
+		t.Fatalf("err = %v, want not healthy", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 2*waitHealthy {
+		t.Errorf("batch took %s, want about %s", elapsed, waitHealthy)
+	}
+	if hosts.protection[len(hosts.protection)-1] != 0 {
+		t.Errorf("protection calls %v, want the last one releasing", hosts.protection)
+	}
+}
+
+func TestContainerReady(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	started := func(ago time.Duration) string { return now.Add(-ago).Format(time.RFC3339Nano) }
+	tests := []struct {
+		name      string
+		line      string
+		wantReady bool
+		wantErr   string
+	}{
+		{name: "healthy", line: "running\thealthy\t" + started(time.Second), wantReady: true},
+		{name: "starting", line: "running\tstarting\t" + started(time.Minute)},
+		{name: "unhealthy", line: "running\tunhealthy\t" + started(time.Minute), wantErr: "unhealthy"},
+		{name: "no healthcheck, up long enough", line: "running\t\t" + started(time.Minute), wantReady: true},
+		{name: "no healthcheck, just started", line: "running\t\t" + started(time.Second)},
+		{name: "restarting", line: "restarting\t\t" + started(time.Minute)},
+		{name: "exited", line: "exited\t\t" + started(time.Minute), wantErr: "container is exited"},
+		{name: "bad start time", line: "running\t\tyesterday", wantErr: "unable to parse start time"},
+		{name: "short line", line: "running", wantErr: "unexpected inspect output"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, _, err := containerReady(tt.line, 10*time.Second, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ready != tt.wantReady {
+				t.Errorf("ready = %v, want %v", ready, tt.wantReady)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runningForUnits maps the units docker uses in RunningFor and Status to their length.
+// Months and years use docker's own approximations of 30 and 365 days.
+var runningForUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+	"month":  30 * 24 * time.Hour,
+	"year":   365 * 24 * time.Hour,
+}
+
+// parseRunningFor converts docker's human readable durations, such as "9 minutes ago",
+// "About an hour ago", "Up 3 weeks (healthy)" or "Exited (0) 2 hours ago", into a duration.
+func parseRunningFor(s string) (time.Duration, bool) {
+	s = strings.ToLower(s)
+	for {
+		open, end := strings.Index(s, "("), strings.Index(s, ")")
+		if open == -1 || end < open {
+			break
+		}
+		s = s[:open] + s[end+1:] // "(healthy)", "(0)"
+	}
+	s = strings.TrimSpace(s)
+	for _, prefix := range []string{"up ", "exited "} {
+		s = strings.TrimPrefix(s, prefix)
+	}
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "ago"))
+
+	if s == "less than a second" {
+		return 0, true
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(s, "about "))
+	if len(fields) != 2 {
+		return 0, false
+	}
+	count := 1
+	switch fields[0] {
+	case "a", "an":
+	default:
+		n, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return 0, false
+		}
+		count = n
+	}
+	unit, ok := runningForUnits[strings.TrimSuffix(fields[1], "s")]
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(count) * unit, true
+}
+
+// sortRecords orders find results. "running-for" puts the most recently started
+// containers first and "created" the oldest first; ties fall back to instance and
+// container name, and durations docker phrased in a way we can't parse sort last.
+func sortRecords(records []containerRecord, sortBy string) error {
+	var newestFirst bool
+	switch sortBy {
+	case "":
+		return nil
+	case "running-for":
+		newestFirst = true
+	case "created":
+	default:
+		return fmt.Errorf("unsupported --sort value %q (want running-for or created)", sortBy)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		a, aok := parseRunningFor(records[i].RunningFor)
+		b, bok := parseRunningFor(records[j].RunningFor)
+		if aok != bok {
+			return aok
+		}
+		if a != b {
+			if newestFirst {
+				return a < b
+			}
+			return a > b
+		}
+		if records[i].Instance.Name != records[j].Instance.Name {
+			return records[i].Instance.Name < records[j].Instance.Name
+		}
+		return records[i].Name < records[j].Name
+	})
+	return nil
+}
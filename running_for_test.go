@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"enum/aws"
+)
+
+func TestParseRunningFor(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   time.Duration
+		wantOK bool
+	}{
+		{in: "9 minutes ago", want: 9 * time.Minute, wantOK: true},
+		{in: "About an hour ago", want: time.Hour, wantOK: true},
+		{in: "About a minute ago", want: time.Minute, wantOK: true},
+		{in: "Less than a second ago", want: 0, wantOK: true},
+		{in: "1 second ago", want: time.Second, wantOK: true},
+		{in: "2 days ago", want: 48 * time.Hour, wantOK: true},
+		{in: "3 weeks ago", want: 21 * 24 * time.Hour, wantOK: true},
+		{in: "2 months ago", want: 60 * 24 * time.Hour, wantOK: true},
+		{in: "1 year ago", want: 365 * 24 * time.Hour, wantOK: true},
+		{in: "Up 3 weeks (healthy)", want: 21 * 24 * time.Hour, wantOK: true},
+		{in: "Up 5 hours (unhealthy)", want: 5 * time.Hour, wantOK: true},
+		{in: "Exited (0) 2 hours ago", want: 2 * time.Hour, wantOK: true},
+		{in: "Exited (137) About a minute ago", want: time.Minute, wantOK: true},
+		{in: "Up 2 minutes (Paused)", want: 2 * time.Minute, wantOK: true},
+		{in: "", wantOK: false},
+		{in: "Created", wantOK: false},
+		{in: "ages ago", wantOK: false},
+		{in: "5 fortnights ago", wantOK: false},
+		{in: "many hours ago", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := parseRunningFor(tt.in)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parseRunningFor(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSortRecords(t *testing.T) {
+	record := func(instance, name, runningFor string) containerRecord {
+		return containerRecord{Instance: aws.InstanceData{Name: instance}, Name: name, RunningFor: runningFor}
+	}
+	input := []containerRecord{
+		record("b", "web", "2 hours ago"),
+		record("a", "odd", "some time ago"),
+		record("a", "api", "9 minutes ago"),
+		record("b", "db", "3 days ago"),
+		record("a", "web", "2 hours ago"),
+		record("a", "cron", "About an hour ago"),
+	}
+	names := func(records []containerRecord) []string {
+		var out []string
+		for _, r := range records {
+			out = append(out, r.Instance.Name+"/"+r.Name)
+		}
+		return out
+	}
+
+	tests := []struct {
+		sortBy  string
+		want    []string
+		wantErr string
+	}{
+		{sortBy: "", want: []string{"b/web", "a/odd", "a/api", "b/db", "a/web", "a/cron"}},
+		{sortBy: "running-for", want: []string{"a/api", "a/cron", "a/web", "b/web", "b/db", "a/odd"}},
+		{sortBy: "created", want: []string{"b/db", "a/web", "b/web", "a/cron", "a/api", "a/odd"}},
+		{sortBy: "name", wantErr: "unsupported --sort"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			records := append([]containerRecord(nil), input...)
+			err := sortRecords(records, tt.sortBy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := names(records); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"enum/aws"
+)
+
+// Container runtimes reported in aws.InstanceData.ContainerRuntime.
+const (
+	runtimeDocker     = "docker"
+	runtimeContainerd = "containerd"
+)
+
+// runtimeCommand wraps a remote command so it runs with the CLI of whichever runtime is
+// active on the host: docker when docker.service is up, nerdctl otherwise (containerd-only
+// ECS AMIs). The first line of output names the runtime; see splitRuntimeOutput.
+func runtimeCommand(build func(cli string) string) string {
+	return fmt.Sprintf("if systemctl is-active --quiet docker.service; then echo %s; %s; else echo %s; %s; fi",
+		runtimeDocker, build("docker"), runtimeContainerd, build("nerdctl"))
+}
+
+// splitRuntimeOutput separates the runtime line printed by a runtimeCommand from the command's own output,
+// skipping any banner printed before it.
+func splitRuntimeOutput(output string) (runtime, rest string) {
+	output, _ = stripBanner(output, isRuntimeLine)
+	runtime, rest, _ = strings.Cut(output, "\n")
+	return strings.TrimSpace(runtime), rest
+}
+
+// containerCLI returns the CLI to drive the instance's runtime with, assuming docker when unknown.
+func containerCLI(instance aws.InstanceData) string {
+	if instance.ContainerRuntime == runtimeContainerd {
+		return "nerdctl"
+	}
+	return "docker"
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"enum/aws"
+	"enum/ssh"
+	"enum/trace"
+)
+
+// containerRecord is a container seen on an instance during a cluster scan.
+type containerRecord struct {
+	Instance   aws.InstanceData
+	ID         string
+	Name       string
+	Image      string
+	Status     string
+	RunningFor string
+	ImageArch  string // Only set by populateImageArchitectures
+}
+
+// defaultParallel is how many hosts commands with --parallel, such as find, versions and
+// push, contact at once unless told otherwise.
+const defaultParallel = 10
+
+// containerFormat is the docker ps format parsed by scanContainers.
+const containerFormat = "{{.ID}}\t{{.Names}}\t{{.Image}}\t{{.Status}}\t{{.RunningFor}}"
+
+// scanContainers lists the containers on every reachable instance, including
+// stopped ones when all is set. Each record's Instance carries the runtime found on
+// the host.
+func scanContainers(instances []aws.InstanceData, all bool) []containerRecord {
+	return source.Containers(instances, all)
+}
+
+// Containers runs docker ps on each instance, scheduled by hostScheduler. Hosts that
+// fail are skipped and logged together once every host has answered, so concurrent
+// scans don't interleave their errors.
+func (liveSource) Containers(instances []aws.InstanceData, all bool) []containerRecord {
+	span := trace.Start("container scan", "main").Set("hosts", len(instances))
+	defer span.End()
+
+	// Each host fills its own slot so results and failures keep the instance order.
+	perHost := make([][]containerRecord, len(instances))
+	failures := make([]error, len(instances))
+	hostScheduler.Run(len(instances), func(i int) {
+		instance := instances[i]
+		if instance.PrivateIP == "" {
+			return // Skip if no SSH access
+		}
+
+		hostSpan := span.Child("host scan", instance.PrivateIP).Set("host", instance.PrivateIP)
+		perHost[i], failures[i] = scanHost(instance, all)
+		hostSpan.Set("containers", len(perHost[i])).End()
+	})
+
+	for i, err := range failures {
+		if err != nil {
+			log.Printf("Skipping instance %s: %v", instances[i].Name, err)
+		}
+	}
+
+	var records []containerRecord
+	for _, hostRecords := range perHost {
+		records = append(records, hostRecords...)
+	}
+	return records
+}
+
+// scanHost runs docker ps on one instance. Errors are already classified by classifyHostFailure.
+func scanHost(instance aws.InstanceData, all bool) ([]containerRecord, error) {
+	cmd := runtimeCommand(func(cli string) string {
+		if all {
+			return "sudo " + cli + " ps -a --format '" + containerFormat + "'"
+		}
+		return "sudo " + cli + " ps --format '" + containerFormat + "'"
+	})
+
+	conn, _, err := connectProbed(instance)
+	if err != nil {
+		return nil, classifyHostFailure(instance, err)
+	}
+	defer conn.Close()
+
+	result, err := conn.Run(cmd)
+	if err == nil && result.ExitCode != 0 {
+		err = fmt.Errorf("command '%s' exited with status %d\nStderr: %s", cmd, result.ExitCode, result.Stderr)
+	}
+	if err != nil {
+		return nil, classifyHostFailure(instance, err)
+	}
+	output, banner := stripBanner(result.Stdout, isRuntimeLine)
+	instance.ContainerRuntime, output = splitRuntimeOutput(output)
+	output, rowBanner := stripBanner(output, isContainerLine)
+	warnBanner(instance, banner+rowBanner)
+
+	var records []containerRecord
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.Split(line, "\t")
+		if len(parts) < 5 {
+			continue
+		}
+		records = append(records, containerRecord{
+			Instance:   instance,
+			ID:         parts[0],
+			Name:       parts[1],
+			Image:      parts[2],
+			Status:     parts[3],
+			RunningFor: parts[4],
+		})
+	}
+	return records, nil
+}
+
+// findResult is a containerRecord as written by find --out.
+type findResult struct {
+	InstanceID   string `json:"instance_id"`
+	InstanceName string `json:"instance_name"`
+	PrivateIP    string `json:"private_ip"`
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Image        string `json:"image"`
+	Status       string `json:"status"`
+	RunningFor   string `json:"running_for"`
+}
+
+// findResults flattens records for JSON output.
+func findResults(records []containerRecord) []findResult {
+	results := []findResult{}
+	for _, record := range records {
+		results = append(results, findResult{
+			InstanceID:   record.Instance.InstanceID,
+			InstanceName: record.Instance.Name,
+			PrivateIP:    record.Instance.PrivateIP,
+			ID:           record.ID,
+			Name:         record.Name,
+			Image:        record.Image,
+			Status:       record.Status,
+			RunningFor:   record.RunningFor,
+		})
+	}
+	return results
+}
+
+// populateImageArchitectures sets ImageArch on the records selected by include, inspecting
+// each host's images in one SSH session per host, scheduled by hostScheduler.
+func populateImageArchitectures(records []containerRecord, include func(containerRecord) bool) {
+	// Group the selected records by host, keeping each host's distinct images.
+	var hosts []aws.InstanceData
+	images := make(map[string][]string) // instance ID -> images
+	indexes := make(map[string][]int)   // instance ID -> record indexes
+	for i, record := range records {
+		if !include(record) {
+			continue
+		}
+		id := record.Instance.InstanceID
+		if _, ok := indexes[id]; !ok {
+			hosts = append(hosts, record.Instance)
+		}
+		indexes[id] = append(indexes[id], i)
+		if !slices.Contains(images[id], record.Image) {
+			images[id] = append(images[id], record.Image)
+		}
+	}
+
+	hostScheduler.Run(len(hosts), func(h int) {
+		instance := hosts[h]
+		hostImages := images[instance.InstanceID]
+		var quoted []string
+		for _, image := range hostImages {
+			quoted = append(quoted, ssh.ShellQuote(image))
+		}
+		// One line per image, in order; images that can't be inspected print "unknown".
+		cmd := fmt.Sprintf("for image in %s; do sudo %s image inspect --format '{{.Architecture}}' \"$image\" 2>/dev/null || echo unknown; done",
+			strings.Join(quoted, " "), containerCLI(instance))
+		output, err := ssh.SSHCommand(instance.PrivateIP, cmd, false)
+		if err != nil {
+			log.Printf("Error inspecting images on instance %s: %v", instance.Name, err)
+			return
+		}
+		arch := make(map[string]string)
+		for i, line := range strings.Split(strings.TrimSpace(output), "\n") {
+			if i < len(hostImages) {
+				arch[hostImages[i]] = strings.TrimSpace(line)
+			}
+		}
+		for _, i := range indexes[instance.InstanceID] {
+			records[i].ImageArch = arch[records[i].Image]
+		}
+	})
+}
+
+// archMismatch reports whether a container's image was built for another architecture
+// than its host's. EC2 and docker name x86 differently (x86_64 and amd64).
+func archMismatch(record containerRecord) bool {
+	if record.ImageArch == "" || record.ImageArch == "unknown" || record.Instance.Architecture == "" {
+		return false
+	}
+	host := record.Instance.Architecture
+	if host == "x86_64" {
+		host = "amd64"
+	}
+	return record.ImageArch != host
+}
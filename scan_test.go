@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"enum/aws"
+	"enum/ssh"
+	"enum/trace"
+)
+
+// fakeConn answers commands with respond and records the timeout each command was given.
+type fakeConn struct {
+	respond func(command string) (ssh.CommandResult, error)
+
+	mu       sync.Mutex
+	timeouts map[string]time.Duration
+}
+
+func (c *fakeConn) Run(command string) (ssh.CommandResult, error) {
+	return c.RunTimeout(command, 0)
+}
+
+func (c *fakeConn) RunTimeout(command string, timeout time.Duration) (ssh.CommandResult, error) {
+	c.mu.Lock()
+	if c.timeouts == nil {
+		c.timeouts = make(map[string]time.Duration)
+	}
+	c.timeouts[command] = timeout
+	c.mu.Unlock()
+	return c.respond(command)
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+// setUpHosts points connectHost at conns, keyed by private IP, and forgets the
+// hosts' probes when the test ends.
+func setUpHosts(t *testing.T, conns map[string]*fakeConn) {
+	previous := connectHost
+	t.Cleanup(func() {
+		connectHost = previous
+		for host := range conns {
+			probeCache.Lock()
+			delete(probeCache.results, host)
+			probeCache.Unlock()
+		}
+	})
+	connectHost = func(host string) (hostConn, error) {
+		conn, ok := conns[host]
+		if !ok {
+			t.Fatalf("unexpected connection to %s", host)
+		}
+		return conn, nil
+	}
+}
+
+// healthyHost answers the probe and lists containers with docker ps.
+func healthyHost(containers ...string) *fakeConn {
+	return &fakeConn{respond: func(command string) (ssh.CommandResult, error) {
+		switch {
+		case strings.Contains(command, " version "):
+			return ssh.CommandResult{Stdout: "docker\n24.0.5\n"}, nil
+		case strings.Contains(command, " ps "):
+			return ssh.CommandResult{Stdout: "docker\n" + strings.Join(containers, "\n") + "\n"}, nil
+		}
+		return ssh.CommandResult{}, nil
+	}}
+}
+
+func TestScanContainersTrace(t *testing.T) {
+	trace.Reset()
+	t.Cleanup(trace.Reset)
+	trace.Enable()
+	setUpHosts(t, map[string]*fakeConn{
+		"10.0.0.2": healthyHost("0a1b2c3d4e5f\tweb\tnginx:1.25\tUp 2 hours\t2 hours ago"),
+		"10.0.0.3": healthyHost(
+			"9f8e7d6c5b4a\tworker\tworker:7\tUp 5 minutes\t5 minutes ago",
+			"1234567890ab\tcron\tcron:2\tUp 1 hour\t1 hour ago",
+		),
+	})
+
+	records := scanContainers([]aws.InstanceData{
+		{InstanceID: "i-0abc", Name: "web-1", PrivateIP: "10.0.0.2"},
+		{InstanceID: "i-0def", Name: "web-2", PrivateIP: "10.0.0.3"},
+	}, false)
+	if len(records) != 3 {
+		t.Fatalf("scanned %d containers, want 3: %+v", len(records), records)
+	}
+
+	path := filepath.Join(t.TempDir(), "trace.json")
+	if err := trace.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		TraceEvents []struct {
+			Name     string         `json:"name"`
+			Start    int64          `json:"ts"`
+			Duration int64          `json:"dur"`
+			TID      int            `json:"tid"`
+			Args     map[string]any `json:"args"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	scan := -1
+	hosts := make(map[string]int) // Host to event index
+	for i, ev := range doc.TraceEvents {
+		switch ev.Name {
+		case "container scan":
+			scan = i
+		case "host scan":
+			hosts[ev.Args["host"].(string)] = i
+		}
+	}
+	if scan < 0 {
+		t.Fatalf("no container scan span in:\n%s", data)
+	}
+	parent := doc.TraceEvents[scan]
+	if len(hosts) != 2 {
+		t.Fatalf("got host spans for %v, want 10.0.0.2 and 10.0.0.3:\n%s", hosts, data)
+	}
+
+	lanes := make(map[int]bool)
+	for host, i := range hosts {
+		ev := doc.TraceEvents[i]
+		if ev.Args["parent"] != "container scan" {
+			t.Errorf("%s span parent = %v, want container scan", host, ev.Args["parent"])
+		}
+		if ev.Start < parent.Start || ev.Start+ev.Duration > parent.Start+parent.Duration {
+			t.Errorf("%s span [%d, +%d] is outside the scan span [%d, +%d]", host, ev.Start, ev.Duration, parent.Start, parent.Duration)
+		}
+		if ev.TID == parent.TID || lanes[ev.TID] {
+			t.Errorf("%s span shares lane %d with another span", host, ev.TID)
+		}
+		lanes[ev.TID] = true
+	}
+	if got := doc.TraceEvents[hosts["10.0.0.3"]].Args["containers"]; got != float64(2) {
+		t.Errorf("10.0.0.3 span containers = %v, want 2", got)
+	}
+}
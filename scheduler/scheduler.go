@@ -0,0 +1,56 @@
+// Package scheduler runs per-host operations of a cluster-wide fan-out with a bound
+// on how many run at once and an optional pause between starting each one, for
+// environments whose hosts can't take a burst of SSH sessions.
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source a Scheduler sleeps on. Tests substitute a fake.
+type Clock interface {
+	Sleep(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// Scheduler starts tasks in order, at most Concurrency at a time, waiting Throttle
+// between consecutive starts. The zero value runs tasks one at a time without pausing.
+type Scheduler struct {
+	Concurrency int
+	Throttle    time.Duration
+	Clock       Clock // Defaults to the real clock
+}
+
+// Run calls task for every index from 0 to n-1 and returns once all calls have finished.
+func (s Scheduler) Run(n int, task func(i int)) {
+	concurrency := s.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	clock := s.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		if i > 0 && s.Throttle > 0 {
+			clock.Sleep(s.Throttle)
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			task(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock advances a virtual now on every Sleep instead of blocking. If set, onSleep
+// runs first, letting a test hold the clock until the previous task has started.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Duration
+	sleeps  []time.Duration
+	onSleep func()
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	if c.onSleep != nil {
+		c.onSleep()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now += d
+	c.sleeps = append(c.sleeps, d)
+}
+
+func (c *fakeClock) Now() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func TestRunCallsEveryIndexOnce(t *testing.T) {
+	for _, concurrency := range []int{0, 1, 3, 20} {
+		var mu sync.Mutex
+		seen := make(map[int]int)
+		Scheduler{Concurrency: concurrency, Clock: &fakeClock{}}.Run(10, func(i int) {
+			mu.Lock()
+			seen[i]++
+			mu.Unlock()
+		})
+		if len(seen) != 10 {
+			t.Errorf("concurrency %d: ran %d distinct indexes, want 10", concurrency, len(seen))
+		}
+		for i, count := range seen {
+			if count != 1 {
+				t.Errorf("concurrency %d: index %d ran %d times", concurrency, i, count)
+			}
+		}
+	}
+}
+
+func TestRunBoundsConcurrency(t *testing.T) {
+	const concurrency, n = 3, 7
+	started := make(chan int, n)
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		Scheduler{Concurrency: concurrency, Clock: &fakeClock{}}.Run(n, func(i int) {
+			started <- i
+			<-release
+		})
+		close(done)
+	}()
+
+	seen := make(map[int]bool)
+	for i := 0; i < concurrency; i++ {
+		seen[<-started] = true
+	}
+	for next := concurrency; next < n; next++ {
+		select {
+		case i := <-started:
+			t.Fatalf("task %d started while %d were running", i, concurrency)
+		case <-time.After(20 * time.Millisecond):
+		}
+		release <- struct{}{}
+		seen[<-started] = true
+	}
+	if len(seen) != n {
+		t.Fatalf("%d distinct tasks started, want %d", len(seen), n)
+	}
+	for i := 0; i < concurrency; i++ {
+		release <- struct{}{}
+	}
+	<-done
+}
+
+func TestRunWaitsForAllTasks(t *testing.T) {
+	var mu sync.Mutex
+	finished := 0
+	Scheduler{Concurrency: 4}.Run(8, func(i int) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		finished++
+		mu.Unlock()
+	})
+	if finished != 8 {
+		t.Errorf("Run returned after %d of 8 tasks finished", finished)
+	}
+}
+
+func TestRunSpacesStartsByThrottle(t *testing.T) {
+	const throttle = 250 * time.Millisecond
+	started := make(chan struct{}, 1)
+	clock := &fakeClock{onSleep: func() { <-started }}
+	starts := make([]time.Duration, 5)
+	Scheduler{Concurrency: 5, Throttle: throttle, Clock: clock}.Run(len(starts), func(i int) {
+		starts[i] = clock.Now()
+		started <- struct{}{}
+	})
+
+	if len(clock.sleeps) != len(starts)-1 {
+		t.Fatalf("slept %d times, want %d (none before the first start)", len(clock.sleeps), len(starts)-1)
+	}
+	for _, d := range clock.sleeps {
+		if d != throttle {
+			t.Errorf("slept %v, want %v", d, throttle)
+		}
+	}
+	for i, at := range starts {
+		if want := time.Duration(i) * throttle; at != want {
+			t.Errorf("task %d started at %v, want %v", i, at, want)
+		}
+	}
+}
+
+func TestRunWithoutThrottleNeverSleeps(t *testing.T) {
+	clock := &fakeClock{}
+	Scheduler{Concurrency: 2, Clock: clock}.Run(6, func(int) {})
+	if len(clock.sleeps) != 0 {
+		t.Errorf("slept %v with no throttle", clock.sleeps)
+	}
+}
+
+func TestRunZeroTasks(t *testing.T) {
+	clock := &fakeClock{}
+	Scheduler{Throttle: time.Second, Clock: clock}.Run(0, func(int) {
+		t.Error("task called for n = 0")
+	})
+	if len(clock.sleeps) != 0 {
+		t.Errorf("slept %v for n = 0", clock.sleeps)
+	}
+}
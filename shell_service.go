@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"enum/aws"
+	"enum/ssh"
+)
+
+// replicaChoice is how shell --service picks one of the service's tasks. With none of
+// its fields set, successive calls take the tasks in turn.
+type replicaChoice struct {
+	Index  int // 1-based position among the running tasks, oldest first; 0 when unset
+	Newest bool
+	Oldest bool
+}
+
+// replicaTurn is the round-robin position stored per service between runs.
+type replicaTurn struct {
+	Next int `json:"next"`
+}
+
+// replicaTurnTTL is how long a round-robin position is remembered.
+const replicaTurnTTL = 24 * time.Hour
+
+// serviceShell opens an interactive shell in a container of one of the service's running
+// tasks, picked by choice. The chosen task and container are printed so follow-up
+// commands can target the same one.
+func serviceShell(service string, choice replicaChoice, args []string, limits ssh.SessionLimits) error {
+	picks := 0
+	for _, set := range []bool{choice.Index != 0, choice.Newest, choice.Oldest} {
+		if set {
+			picks++
+		}
+	}
+	if picks > 1 {
+		return fmt.Errorf("use only one of --index, --newest and --oldest")
+	}
+
+	tasks, err := aws.ListRunningServiceTasks(ActiveConfig.ClusterName, service, awsProfile)
+	if err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		return noRunningTasksError(service)
+	}
+
+	var task aws.RunningTaskInfo
+	switch {
+	case choice.Index != 0:
+		if choice.Index < 1 || choice.Index > len(tasks) {
+			return fmt.Errorf("--index %d is out of range: service %s has %d running tasks", choice.Index, service, len(tasks))
+		}
+		task = tasks[choice.Index-1]
+	case choice.Newest:
+		task = tasks[len(tasks)-1]
+	case choice.Oldest:
+		task = tasks[0]
+	default:
+		task = tasks[nextReplica(service, len(tasks))]
+	}
+	if task.InstanceID == "" {
+		return fmt.Errorf("task %s doesn't run on an EC2 instance of the cluster", taskID(task.TaskArn))
+	}
+	container, ok := serviceContainer(task, service)
+	if !ok {
+		return fmt.Errorf("task %s has no running containers", taskID(task.TaskArn))
+	}
+
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	var instance aws.InstanceData
+	for _, candidate := range instances {
+		if candidate.InstanceID == task.InstanceID {
+			instance = candidate
+		}
+	}
+	if instance.PrivateIP == "" {
+		return fmt.Errorf("instance %s of task %s is not reachable", task.InstanceID, taskID(task.TaskArn))
+	}
+
+	containerID := container.RuntimeID[:min(12, len(container.RuntimeID))]
+	fmt.Printf("Service %s: task %s, container %s (%s) on instance %s (%s)\n",
+		service, taskID(task.TaskArn), container.Name, containerID, instance.InstanceID, instance.Name)
+
+	output, err := ssh.SSHCommand(instance.PrivateIP, runtimeCommand(func(cli string) string { return "true" }), false)
+	if err != nil {
+		return fmt.Errorf("error connecting to %s: %v", instance.Name, err)
+	}
+	instance.ContainerRuntime, _ = splitRuntimeOutput(output)
+	return ssh.SSHInteractiveShell(instance.PrivateIP, containerCLI(instance), containerID, shellCommand(args), limits)
+}
+
+// serviceContainer picks the container to open the shell in: the one named after the
+// service if there is one, the first started container otherwise, so sidecars such as
+// log routers are passed over when the naming allows.
+func serviceContainer(task aws.RunningTaskInfo, service string) (aws.TaskContainer, bool) {
+	var first *aws.TaskContainer
+	for i, container := range task.Containers {
+		if container.RuntimeID == "" {
+			continue
+		}
+		if container.Name == service {
+			return container, true
+		}
+		if first == nil {
+			first = &task.Containers[i]
+		}
+	}
+	if first == nil {
+		return aws.TaskContainer{}, false
+	}
+	return *first, true
+}
+
+// nextReplica returns the index of the task to use for service when none was chosen,
+// advancing a position kept in the cache so successive shells spread across replicas.
+func nextReplica(service string, count int) int {
+	name := "shell-turn-" + ActiveConfig.ClusterName + "-" + service
+	var turn replicaTurn
+	readCache(name, replicaTurnTTL, &turn)
+	index := turn.Next % count
+	_ = writeCache(name, ActiveConfig.ClusterName, replicaTurn{Next: index + 1}) // Losing the position only repeats a replica
+	return index
+}
+
+// noRunningTasksError explains an empty service with the most recent task's stop reason.
+func noRunningTasksError(service string) error {
+	stopped, err := aws.ListStoppedServiceTasks(ActiveConfig.ClusterName, service, awsProfile, 1)
+	if err != nil || len(stopped) == 0 {
+		return fmt.Errorf("service %s has no running tasks", service)
+	}
+	last := stopped[0]
+	return fmt.Errorf("service %s has no running tasks; the last one (%s) stopped at %s: %s",
+		service, taskID(last.TaskArn), last.StoppedAt.Local().Format(time.RFC3339), last.StoppedReason)
+}
+
+// taskID returns the ID at the end of a task ARN.
+func taskID(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"enum/aws"
+
+	"github.com/spf13/cobra"
+)
+
+// clusterSource supplies the instances and containers that commands work from. The live
+// source asks AWS and SSHes to the hosts; a snapshotSource replays a file written by the
+// snapshot command.
+type clusterSource interface {
+	Instances(states aws.InstanceStates) ([]aws.InstanceData, error)
+	Containers(instances []aws.InstanceData, all bool) []containerRecord
+}
+
+// liveSource reads the cluster as it is now. Its methods live next to the code they
+// replaced: Instances in main.go and Containers in scan.go.
+type liveSource struct{}
+
+// source is where fetchInstances and scanContainers get their data.
+var source clusterSource = liveSource{}
+
+// fromSnapshot is the --from-snapshot file, if any.
+var fromSnapshot string
+
+// snapshotLiveFlags lists the commands that can run from a snapshot, with the flags of
+// theirs that still need AWS or SSH and are refused.
+var snapshotLiveFlags = map[string][]string{
+	"find":     {"wide"},
+	"list-ec2": {"ssm-active", "agent-cpu-gt", "agent-mem-gt", "show-cw-agent", "multi-cluster-only", "storage-driver-filter", "status-check-failed", "check-zombies", "zombies-gt", "has-registration-errors", "cgroup-v1", "cgroup-v2"},
+}
+
+// snapshotFile is the file written by the snapshot command.
+type snapshotFile struct {
+	CapturedAt time.Time           `json:"captured_at"`
+	Cluster    string              `json:"cluster"`
+	Instances  []aws.InstanceData  `json:"instances"`
+	Containers []snapshotContainer `json:"containers"`
+}
+
+// snapshotContainer is a containerRecord with its instance referenced by ID.
+type snapshotContainer struct {
+	InstanceID string `json:"instance_id"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Image      string `json:"image"`
+	Status     string `json:"status"`
+	RunningFor string `json:"running_for"`
+}
+
+// snapshotSource serves a snapshot file's instances and containers.
+type snapshotSource struct {
+	file snapshotFile
+}
+
+// Instances returns the snapshot's instances in the given states.
+func (s snapshotSource) Instances(states aws.InstanceStates) ([]aws.InstanceData, error) {
+	var instances []aws.InstanceData
+	for _, instance := range s.file.Instances {
+		if states.Includes(instance.State) {
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
+// Containers returns the snapshot's containers on the given instances. Snapshots hold
+// stopped containers too; without all only running ones ("Up ...") are returned.
+func (s snapshotSource) Containers(instances []aws.InstanceData, all bool) []containerRecord {
+	byID := make(map[string]aws.InstanceData)
+	for _, instance := range instances {
+		byID[instance.InstanceID] = instance
+	}
+	var records []containerRecord
+	for _, container := range s.file.Containers {
+		instance, ok := byID[container.InstanceID]
+		if !ok || !all && !strings.HasPrefix(container.Status, "Up") {
+			continue
+		}
+		records = append(records, containerRecord{
+			Instance:   instance,
+			ID:         container.ID,
+			Name:       container.Name,
+			Image:      container.Image,
+			Status:     container.Status,
+			RunningFor: container.RunningFor,
+		})
+	}
+	return records
+}
+
+// writeSnapshot captures every instance of the cluster and every container on them,
+// stopped ones included, to path for later use with --from-snapshot.
+func writeSnapshot(path string) error {
+	instances, err := fetchInstances(aws.AllStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	capturedAt := time.Now()
+	records := scanContainers(instances, true)
+
+	file := snapshotFile{CapturedAt: capturedAt, Cluster: ActiveConfig.ClusterName, Instances: instances, Containers: []snapshotContainer{}}
+	runtimes := make(map[string]string)
+	for _, record := range records {
+		runtimes[record.Instance.InstanceID] = record.Instance.ContainerRuntime
+		file.Containers = append(file.Containers, snapshotContainer{
+			InstanceID: record.Instance.InstanceID,
+			ID:         record.ID,
+			Name:       record.Name,
+			Image:      record.Image,
+			Status:     record.Status,
+			RunningFor: record.RunningFor,
+		})
+	}
+	for i := range file.Instances {
+		if runtime, ok := runtimes[file.Instances[i].InstanceID]; ok {
+			file.Instances[i].ContainerRuntime = runtime
+		}
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	fmt.Printf("Snapshot of %d instances and %d containers written to %s\n", len(file.Instances), len(file.Containers), path)
+	return nil
+}
+
+// useSnapshot switches source to the --from-snapshot file, refusing commands and flags
+// that need live AWS or SSH access. The capture time is printed to stderr so the output
+// is never mistaken for the cluster's current state.
+func useSnapshot(cmd *cobra.Command) error {
+	if fromSnapshot == "" {
+		return nil
+	}
+	if class, err := operationClass(cmd); err != nil || class == opLocal {
+		return err
+	}
+	liveFlags, ok := snapshotLiveFlags[cmd.Name()]
+	if !ok {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%s needs live AWS or SSH access; --from-snapshot only supports find and list-ec2", cmd.Name())
+	}
+	for _, flag := range liveFlags {
+		if cmd.Flags().Changed(flag) {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("--%s needs live AWS or SSH access and can't be used with --from-snapshot", flag)
+		}
+	}
+
+	data, err := os.ReadFile(fromSnapshot)
+	if err != nil {
+		return fmt.Errorf("error reading snapshot: %v", err)
+	}
+	var file snapshotFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("error parsing snapshot %s: %v", fromSnapshot, err)
+	}
+	if ActiveConfig.ClusterName != "" && file.Cluster != "" && ActiveConfig.ClusterName != file.Cluster {
+		return fmt.Errorf("snapshot %s is of cluster %s, not %s", fromSnapshot, file.Cluster, ActiveConfig.ClusterName)
+	}
+	ActiveConfig.ClusterName = file.Cluster
+
+	fmt.Fprintf(os.Stderr, "Offline: snapshot of %s captured %s (%s ago)\n",
+		file.Cluster, file.CapturedAt.Local().Format(time.RFC3339), time.Since(file.CapturedAt).Round(time.Second))
+	source = snapshotSource{file: file}
+	return nil
+}
@@ -0,0 +1,228 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"enum/trace"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrTimeout reports a command cut short by RunTimeout.
+var ErrTimeout = errors.New("timed out")
+
+// dialHost opens a client connection to a host. Tests replace it to avoid the network.
+var dialHost = dial
+
+// maxSessions is how many sessions a Conn runs at once on one TCP connection; see SetMaxSessions.
+var maxSessions = 2
+
+// SetMaxSessions limits how many sessions each Conn opens at once, for hosts whose sshd
+// MaxSessions is low. Further sessions wait for one to finish. n must be at least 1.
+func SetMaxSessions(n int) {
+	maxSessions = n
+}
+
+// Conn is an SSH connection kept open to run several commands on one host, for
+// callers that poll and would otherwise pay for a handshake on every command.
+// At most maxSessions sessions run on it at once.
+type Conn struct {
+	client   *ssh.Client
+	sessions chan struct{} // Semaphore of client's sessions
+	host     string
+	verbose  bool
+
+	// overflow is a second connection, dialled on first use, for latency-sensitive
+	// commands that would otherwise queue behind the sessions of client.
+	overflowMu       sync.Mutex
+	overflow         *ssh.Client
+	overflowSessions chan struct{}
+}
+
+// Connect opens a connection to host. Close it when done.
+func Connect(host string, verbose bool) (*Conn, error) {
+	client, err := dialHost(host, verbose)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{client: client, sessions: make(chan struct{}, maxSessions), host: host, verbose: verbose}, nil
+}
+
+// newSession opens a session once the session limit allows, returning a release func that
+// closes the session and frees its slot. An urgent session doesn't queue: when client is at
+// its limit, it runs on the overflow connection instead.
+func (c *Conn) newSession(urgent bool) (*ssh.Session, func(), error) {
+	client, slots := c.client, c.sessions
+	select {
+	case slots <- struct{}{}:
+	default:
+		if urgent {
+			var err error
+			if client, slots, err = c.overflowClient(); err != nil {
+				return nil, nil, err
+			}
+		}
+		slots <- struct{}{}
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		<-slots
+		return nil, nil, fmt.Errorf("failed to create SSH session: %v", err)
+	}
+	return session, func() {
+		session.Close()
+		<-slots
+	}, nil
+}
+
+// overflowClient returns the overflow connection, dialling it the first time.
+func (c *Conn) overflowClient() (*ssh.Client, chan struct{}, error) {
+	c.overflowMu.Lock()
+	defer c.overflowMu.Unlock()
+	if c.overflow == nil {
+		client, err := dialHost(c.host, c.verbose)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.overflow, c.overflowSessions = client, make(chan struct{}, maxSessions)
+	}
+	return c.overflow, c.overflowSessions, nil
+}
+
+// Run executes command in a new session on the connection and returns its output and exit code.
+// A non-zero exit code is not an error; errors mean the command could not be run at all.
+func (c *Conn) Run(command string) (CommandResult, error) {
+	return c.RunInput(command, nil)
+}
+
+// RunInput is Run with stdin fed to the command, e.g. to copy a file with cat.
+func (c *Conn) RunInput(command string, stdin io.Reader) (CommandResult, error) {
+	return c.run(command, stdin, 0)
+}
+
+// RunTimeout is Run giving up after timeout, for probes of hosts that may hang.
+// A command cut short returns an error wrapping ErrTimeout. So that the timeout isn't
+// spent waiting for a free session, it uses a second connection when the first is busy.
+func (c *Conn) RunTimeout(command string, timeout time.Duration) (CommandResult, error) {
+	return c.run(command, nil, timeout)
+}
+
+// run executes command in a new session, closing the session after timeout when it is positive.
+func (c *Conn) run(command string, stdin io.Reader, timeout time.Duration) (CommandResult, error) {
+	span := trace.Start("ssh exec", c.host).Set("host", c.host)
+	defer span.End()
+
+	// Create a new SSH session
+	session, release, err := c.newSession(timeout > 0)
+	if err != nil {
+		return CommandResult{}, err
+	}
+	defer release()
+
+	if c.verbose {
+		fmt.Printf("Running command: %s\n", command)
+	}
+
+	// Capture the output of the remote command
+	var stdoutBuf, stderrBuf bytes.Buffer
+	session.Stdout = &stdoutBuf
+	session.Stderr = &stderrBuf
+	session.Stdin = stdin
+	var timedOut atomic.Bool
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			timedOut.Store(true)
+			session.Close()
+		})
+		defer timer.Stop()
+	}
+	err = session.Run(withRemoteEnv(command))
+	if timedOut.Load() {
+		span.Set("timeout", true)
+		return CommandResult{}, fmt.Errorf("command '%s' %w after %s", command, ErrTimeout, timeout)
+	}
+
+	result := CommandResult{Stdout: stdoutBuf.String(), Stderr: stderrBuf.String()}
+	span.Set("bytes", stdoutBuf.Len()+stderrBuf.Len())
+	if exitErr, ok := err.(*ssh.ExitError); ok {
+		result.ExitCode = exitErr.ExitStatus()
+		span.Set("exit_code", result.ExitCode)
+		return result, nil
+	}
+	if err != nil {
+		// Without an exit status, e.g. when sshd or the connection gave up on the command,
+		// stderr is often all there is to say why.
+		return CommandResult{}, fmt.Errorf("failed to run command '%s': %v\nStderr: %s", command, err, result.Stderr)
+	}
+
+	span.Set("exit_code", 0)
+	return result, nil
+}
+
+// StreamLines runs command and calls onLine with each line of its output, stdout and
+// stderr alike, until the command exits or ctx is cancelled. Cancelling is not an error.
+func (c *Conn) StreamLines(ctx context.Context, command string, onLine func(line string)) error {
+	session, release, err := c.newSession(false)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	reader, writer := io.Pipe()
+	session.Stdout = writer
+	session.Stderr = writer
+	if err := session.Start(withRemoteEnv(command)); err != nil {
+		return fmt.Errorf("failed to start command '%s': %v", command, err)
+	}
+
+	waitErr := make(chan error, 1)
+	finished := make(chan struct{})
+	go func() {
+		err := session.Wait()
+		writer.Close()
+		waitErr <- err
+		close(finished)
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			session.Close() // Unblocks Wait, which closes the pipe
+		case <-finished:
+		}
+	}()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		onLine(scanner.Text())
+	}
+	scanErr := scanner.Err()
+	io.Copy(io.Discard, reader) // Keep the command from blocking on output the scanner gave up on
+	err = <-waitErr
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("command '%s' failed: %v", command, err)
+	}
+	return scanErr
+}
+
+// Close closes the connection, and the overflow connection if one was opened.
+func (c *Conn) Close() error {
+	c.overflowMu.Lock()
+	if c.overflow != nil {
+		c.overflow.Close()
+	}
+	c.overflowMu.Unlock()
+	return c.client.Close()
+}
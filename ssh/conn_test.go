@@ -0,0 +1,316 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is a loopback SSH server that accepts session channels, or rejects
+// them while reject is set. When exec is set, it runs a session's exec request and
+// the channel is closed once it returns.
+type testServer struct {
+	config *ssh.ServerConfig
+	reject atomic.Bool
+	exec   func(channel ssh.Channel)
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+	return &testServer{config: config}
+}
+
+// client opens a new connection to the server.
+func (s *testServer) client(t *testing.T) *ssh.Client {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		serverSide, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_, channels, requests, err := ssh.NewServerConn(serverSide, s.config)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(requests)
+		for newChannel := range channels {
+			if s.reject.Load() {
+				newChannel.Reject(ssh.Prohibited, "no sessions")
+				continue
+			}
+			channel, channelRequests, err := newChannel.Accept()
+			if err != nil {
+				continue
+			}
+			if s.exec == nil {
+				go ssh.DiscardRequests(channelRequests)
+				continue
+			}
+			go func() {
+				for req := range channelRequests {
+					req.Reply(req.Type == "exec", nil)
+					if req.Type == "exec" {
+						s.exec(channel)
+						channel.Close()
+					}
+				}
+			}()
+		}
+	}()
+
+	clientSide, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, channels, requests, err := ssh.NewClientConn(clientSide, "test", &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ssh.NewClient(conn, channels, requests)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// newTestConn returns a Conn on server whose overflow dials are counted in dials.
+func newTestConn(t *testing.T, server *testServer, limit int, dials *atomic.Int32) *Conn {
+	t.Helper()
+	previousDial, previousMax := dialHost, maxSessions
+	t.Cleanup(func() { dialHost, maxSessions = previousDial, previousMax })
+	dialHost = func(host string, verbose bool) (*ssh.Client, error) {
+		dials.Add(1)
+		return server.client(t), nil
+	}
+	SetMaxSessions(limit)
+	conn, err := Connect("test-host", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dials.Store(0) // Only count overflow dials
+	return conn
+}
+
+func TestNewSessionQueuesAtMaxSessions(t *testing.T) {
+	var dials atomic.Int32
+	conn := newTestConn(t, newTestServer(t), 2, &dials)
+
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		_, release, err := conn.newSession(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+
+	queued := make(chan func(), 1)
+	go func() {
+		_, release, err := conn.newSession(false)
+		if err != nil {
+			t.Error(err)
+		}
+		queued <- release
+	}()
+	select {
+	case <-queued:
+		t.Fatal("a third session opened while 2 were running with --max-sessions 2")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	releases[0]()
+	select {
+	case release := <-queued:
+		release()
+	case <-time.After(5 * time.Second):
+		t.Fatal("the queued session didn't open after a slot was released")
+	}
+	releases[1]()
+
+	if n := dials.Load(); n != 0 {
+		t.Errorf("queued sessions dialled %d overflow connections", n)
+	}
+	if n := len(conn.sessions); n != 0 {
+		t.Errorf("%d slots still held after every session was released", n)
+	}
+}
+
+func TestNewSessionUrgentUsesOverflow(t *testing.T) {
+	var dials atomic.Int32
+	conn := newTestConn(t, newTestServer(t), 1, &dials)
+
+	// With a free slot, urgent sessions use the main connection.
+	_, release, err := conn.newSession(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := dials.Load(); n != 0 {
+		t.Fatalf("urgent session with a free slot dialled %d overflow connections", n)
+	}
+
+	// With the main connection full, they go to the overflow connection at once.
+	done := make(chan func(), 1)
+	go func() {
+		_, overflowRelease, err := conn.newSession(true)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- overflowRelease
+	}()
+	var overflowRelease func()
+	select {
+	case overflowRelease = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("urgent session queued behind the main connection")
+	}
+	if n := dials.Load(); n != 1 {
+		t.Fatalf("dialled %d overflow connections, want 1", n)
+	}
+	if len(conn.overflowSessions) != 1 || len(conn.sessions) != 1 {
+		t.Fatalf("slots held: main %d, overflow %d; want 1 and 1", len(conn.sessions), len(conn.overflowSessions))
+	}
+
+	// The overflow connection is reused rather than dialled again.
+	overflowRelease()
+	_, again, err := conn.newSession(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("dialled %d overflow connections, want the first reused", n)
+	}
+	again()
+	release()
+
+	if len(conn.sessions) != 0 || len(conn.overflowSessions) != 0 {
+		t.Errorf("slots still held: main %d, overflow %d", len(conn.sessions), len(conn.overflowSessions))
+	}
+}
+
+func TestNewSessionOverflowDialError(t *testing.T) {
+	var dials atomic.Int32
+	conn := newTestConn(t, newTestServer(t), 1, &dials)
+	_, release, err := conn.newSession(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	dialHost = func(host string, verbose bool) (*ssh.Client, error) {
+		return nil, errors.New("connection refused")
+	}
+	if _, _, err := conn.newSession(true); err == nil {
+		t.Fatal("urgent session succeeded although the overflow dial failed")
+	}
+	if conn.overflow != nil {
+		t.Error("failed overflow dial was kept")
+	}
+}
+
+func TestNewSessionReleasesSlotOnError(t *testing.T) {
+	var dials atomic.Int32
+	server := newTestServer(t)
+	conn := newTestConn(t, server, 1, &dials)
+
+	server.reject.Store(true)
+	for i := 0; i < 3; i++ {
+		if _, _, err := conn.newSession(false); err == nil {
+			t.Fatal("newSession succeeded although the server rejected the channel")
+		}
+		if n := len(conn.sessions); n != 0 {
+			t.Fatalf("attempt %d: %d slots held after NewSession failed", i+1, n)
+		}
+	}
+
+	// Fill the main connection so the urgent session fails on the overflow one.
+	server.reject.Store(false)
+	_, release, err := conn.newSession(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.reject.Store(true)
+	if _, _, err := conn.newSession(true); err == nil {
+		t.Fatal("urgent newSession succeeded although the server rejected the channel")
+	}
+	if n := len(conn.overflowSessions); n != 0 {
+		t.Errorf("%d overflow slots held after NewSession failed", n)
+	}
+	release()
+
+	server.reject.Store(false)
+	_, release, err = conn.newSession(false)
+	if err != nil {
+		t.Fatalf("no session after earlier failures: %v", err)
+	}
+	release()
+}
+
+func TestRunReportsStderr(t *testing.T) {
+	tests := []struct {
+		name         string
+		exec         func(channel ssh.Channel)
+		wantExitCode int
+		wantErr      string
+	}{
+		{
+			name: "exit status",
+			exec: func(channel ssh.Channel) {
+				channel.Stderr().Write([]byte("permission denied\n"))
+				channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{3}))
+			},
+			wantExitCode: 3,
+		},
+		{
+			name: "no exit status",
+			exec: func(channel ssh.Channel) {
+				channel.Stderr().Write([]byte("sudo: a terminal is required\n"))
+			},
+			wantErr: "sudo: a terminal is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			server.exec = tt.exec
+			var dials atomic.Int32
+			conn := newTestConn(t, server, 2, &dials)
+
+			result, err := conn.Run("sudo true")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.ExitCode != tt.wantExitCode || !strings.Contains(result.Stderr, "permission denied") {
+				t.Errorf("result = %+v, want exit code %d with the stderr", result, tt.wantExitCode)
+			}
+		})
+	}
+}
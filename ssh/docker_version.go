@@ -0,0 +1,97 @@
+package ssh
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VersionCheckResult is the docker daemon version found on a host and how it compares to the minimum.
+type VersionCheckResult struct {
+	Host    string
+	Version string
+	OK      bool   // Version is at least the minimum
+	Error   string // Why the version couldn't be read, if it couldn't
+}
+
+// CheckMinDockerVersion runs versionCmd on each host and compares the docker daemon version
+// it prints with minVersion, e.g. "20.10". parse extracts the version from the command's
+// output, or says why there is none, such as a host running containerd without docker.
+// Hosts that can't be reached or read are reported in the result's Error rather than
+// failing the whole check.
+func CheckMinDockerVersion(hosts []string, minVersion, versionCmd string, parse func(output string) (string, error), opts SSHOptions) ([]VersionCheckResult, error) {
+	minimum, err := parseDockerVersion(minVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minimum version %q: %v", minVersion, err)
+	}
+
+	results := make([]VersionCheckResult, len(hosts))
+	opts.Scheduler.Run(len(hosts), func(i int) {
+		result := VersionCheckResult{Host: hosts[i]}
+		defer func() { results[i] = result }()
+
+		if result.Host == "" {
+			result.Error = "no private IP"
+			return
+		}
+		output, err := SSHCommand(result.Host, versionCmd, opts.Verbose)
+		if err != nil {
+			result.Error = err.Error()
+			return
+		}
+		if result.Version, err = parse(output); err != nil {
+			result.Error = err.Error()
+			return
+		}
+		version, err := parseDockerVersion(result.Version)
+		if err != nil {
+			result.Error = fmt.Sprintf("unrecognized version: %v", err)
+			return
+		}
+		result.OK = compareVersions(version, minimum) >= 0
+	})
+	return results, nil
+}
+
+// parseDockerVersion splits a docker version such as "20.10.25" or "24.0.5-ce" into its
+// numeric components. Pre-release and build suffixes are ignored.
+func parseDockerVersion(version string) ([]int, error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+~"); i != -1 {
+		version = version[:i]
+	}
+	if version == "" {
+		return nil, fmt.Errorf("empty version")
+	}
+
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%q is not a number", field)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+// compareVersions returns -1, 0 or 1 as a is older than, the same as, or newer than b.
+// Missing components count as 0, so 20.10 equals 20.10.0.
+func compareVersions(a, b []int) int {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package ssh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDockerVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{in: "20.10.25", want: []int{20, 10, 25}},
+		{in: "24.0.5-ce", want: []int{24, 0, 5}},
+		{in: "v25.0.3", want: []int{25, 0, 3}},
+		{in: " 20.10\n", want: []int{20, 10}},
+		{in: "26.1.0+dfsg1", want: []int{26, 1, 0}},
+		{in: "", wantErr: true},
+		{in: "latest", wantErr: true},
+		{in: "20..1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDockerVersion(tt.in)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseDockerVersion(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b []int
+		want int
+	}{
+		{a: []int{20, 10}, b: []int{20, 10, 0}, want: 0},
+		{a: []int{20, 10, 25}, b: []int{20, 10}, want: 1},
+		{a: []int{19, 3, 15}, b: []int{20, 10}, want: -1},
+		{a: []int{24}, b: []int{20, 10, 25}, want: 1},
+		{a: []int{20, 9, 99}, b: []int{20, 10}, want: -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
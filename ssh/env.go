@@ -0,0 +1,55 @@
+package ssh
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// remoteEnv is set on every remote command; see SetRemoteEnv.
+var remoteEnv map[string]string
+
+// SetRemoteEnv sets environment variables for every later remote command. Most sshd
+// configurations refuse Setenv, so they are exported at the start of the command instead.
+func SetRemoteEnv(env map[string]string) {
+	remoteEnv = env
+}
+
+// withRemoteEnv prefixes command with export assignments for the remote environment.
+// sudo's env_reset would drop them from the sudo commands almost every remote command
+// runs, so sudo is also wrapped in a function that asks it to preserve them.
+func withRemoteEnv(command string) string {
+	keys := remoteEnvKeys()
+	if len(keys) == 0 {
+		return command
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `sudo() { command sudo --preserve-env=%s "$@"; }; `, strings.Join(keys, ","))
+	for _, key := range keys {
+		fmt.Fprintf(&b, "export %s=%s; ", key, ShellQuote(remoteEnv[key]))
+	}
+	return b.String() + command
+}
+
+// execEnvFlags returns the -e flags that pass the remote environment into a container exec.
+func execEnvFlags() string {
+	var flags []string
+	for _, key := range remoteEnvKeys() {
+		flags = append(flags, "-e "+ShellQuote(key+"="+remoteEnv[key]))
+	}
+	return strings.Join(flags, " ")
+}
+
+func remoteEnvKeys() []string {
+	keys := make([]string, 0, len(remoteEnv))
+	for key := range remoteEnv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ShellQuote single-quotes s for a POSIX shell.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
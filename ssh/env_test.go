@@ -0,0 +1,86 @@
+package ssh
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func setRemoteEnvForTest(t *testing.T, env map[string]string) {
+	previous := remoteEnv
+	t.Cleanup(func() { remoteEnv = previous })
+	SetRemoteEnv(env)
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"":                 "''",
+		"plain":            "'plain'",
+		"it's":             `'it'\''s'`,
+		"$(reboot) `id`":   "'$(reboot) `id`'",
+		"a b\nc":           "'a b\nc'",
+		`back\slash "dq"`:  `'back\slash "dq"'`,
+		"''":               `''\'''\'''`,
+		"INC-1234; rm -rf": "'INC-1234; rm -rf'",
+	}
+	for in, want := range tests {
+		if got := ShellQuote(in); got != want {
+			t.Errorf("ShellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestWithRemoteEnvEmpty(t *testing.T) {
+	setRemoteEnvForTest(t, nil)
+	if got := withRemoteEnv("sudo docker ps"); got != "sudo docker ps" {
+		t.Errorf("got %q, want the command unchanged", got)
+	}
+}
+
+func TestWithRemoteEnvFormat(t *testing.T) {
+	setRemoteEnvForTest(t, map[string]string{"ENUM_REASON": "INC-1 it's broken", "ENUM_OPERATOR": "alice"})
+	want := `sudo() { command sudo --preserve-env=ENUM_OPERATOR,ENUM_REASON "$@"; }; ` +
+		`export ENUM_OPERATOR='alice'; export ENUM_REASON='INC-1 it'\''s broken'; sudo docker ps`
+	if got := withRemoteEnv("sudo docker ps"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestWithRemoteEnvReachesSudo runs the prefixed command in a local shell with a stand-in
+// sudo that, like env_reset, passes on only the variables it is asked to preserve.
+func TestWithRemoteEnvReachesSudo(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh to run the command with")
+	}
+	bin := t.TempDir()
+	fakeSudo := `#!/bin/sh
+keep=
+case "$1" in --preserve-env=*) keep=$(echo "${1#--preserve-env=}" | tr , ' '); shift ;; esac
+set -- env -i PATH="$PATH" $(for k in $keep; do eval "printf '%s=%s ' $k \"\$$k\""; done) "$@"
+exec "$@"
+`
+	if err := os.WriteFile(filepath.Join(bin, "sudo"), []byte(fakeSudo), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	setRemoteEnvForTest(t, map[string]string{"ENUM_REASON": "INC-1234", "ENUM_OPERATOR": "alice"})
+	cmd := exec.Command(sh, "-c", withRemoteEnv(`sudo sh -c 'echo "$ENUM_OPERATOR/$ENUM_REASON"'`))
+	cmd.Env = []string{"PATH=" + bin + string(os.PathListSeparator) + os.Getenv("PATH")}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if got := string(out); got != "alice/INC-1234\n" {
+		t.Errorf("sudo saw %q, want alice/INC-1234", got)
+	}
+}
+
+func TestExecEnvFlags(t *testing.T) {
+	setRemoteEnvForTest(t, map[string]string{"B": "two words", "A": "x'y"})
+	want := `-e 'A=x'\''y' -e 'B=two words'`
+	if got := execEnvFlags(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,130 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// knownHostsMu serialises writes to the enum known_hosts file from concurrent dials.
+var knownHostsMu sync.Mutex
+
+// errKeyCaptured stops a handshake once ScanHostKey has the host key it wanted.
+var errKeyCaptured = errors.New("host key captured")
+
+// KnownHostsPath returns the known_hosts file enum manages, enum/known_hosts under the user's config directory.
+func KnownHostsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to find config directory: %v", err)
+	}
+	return filepath.Join(dir, "enum", "known_hosts"), nil
+}
+
+// ScanHostKey connects to host just long enough to read the host key it presents,
+// without authenticating. The key is trusted on first use, so prefer out-of-band keys.
+func ScanHostKey(host string) (ssh.PublicKey, error) {
+	var key ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User: "enum",
+		HostKeyCallback: func(hostname string, remote net.Addr, k ssh.PublicKey) error {
+			key = k
+			return errKeyCaptured
+		},
+		Timeout: 10 * time.Second,
+	}
+	_, err := ssh.Dial("tcp", host+":22", config)
+	if key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("failed to read host key from %s: %v", host, err)
+}
+
+// SetKnownHostKeys replaces the entries for host in the enum known_hosts file with keys.
+func SetKnownHostKeys(host string, keys []ssh.PublicKey) error {
+	path, err := KnownHostsPath()
+	if err != nil {
+		return err
+	}
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to read %s: %v", path, err)
+	}
+
+	address := knownhosts.Normalize(host)
+	var lines []string
+	for _, line := range strings.Split(string(existing), "\n") {
+		if line == "" || strings.SplitN(line, " ", 2)[0] == address {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	for _, key := range keys {
+		lines = append(lines, knownhosts.Line([]string{address}, key))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+}
+
+// hostKeyCallback checks host keys against the enum known_hosts file. A host with no
+// entry yet is trusted on first use and recorded; a host whose key differs from the
+// recorded one is refused. Run trust-hosts to pre-seed keys verified out-of-band.
+func hostKeyCallback() (ssh.HostKeyCallback, error) {
+	path, err := KnownHostsPath()
+	if err != nil {
+		return nil, err
+	}
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %v", path, err)
+	}
+	f.Close()
+	check, err := knownhosts.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %v", path, err)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := check(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		if len(keyErr.Want) > 0 {
+			return fmt.Errorf("host key for %s does not match %s; if the host was replaced, run trust-hosts again: %v", hostname, path, err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: no known host key for %s, trusting it on first use\n", hostname)
+		return appendKnownHostKey(path, hostname, key)
+	}, nil
+}
+
+// appendKnownHostKey records key for hostname at the end of the known_hosts file at path.
+func appendKnownHostKey(path, hostname string, key ssh.PublicKey) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to record host key in %s: %v", path, err)
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+	return err
+}
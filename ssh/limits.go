@@ -0,0 +1,110 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// limitWarning is how long before a session limit is reached the user is warned.
+const limitWarning = time.Minute
+
+// SessionLimits bounds how long an interactive session may last. A zero value disables that limit.
+type SessionLimits struct {
+	MaxSession  time.Duration
+	IdleTimeout time.Duration
+}
+
+// activityTracker records when data last flowed through a session.
+type activityTracker struct {
+	mu   sync.Mutex
+	now  func() time.Time
+	last time.Time
+}
+
+func newActivityTracker(now func() time.Time) *activityTracker {
+	return &activityTracker{now: now, last: now()}
+}
+
+func (t *activityTracker) touch() {
+	t.mu.Lock()
+	t.last = t.now()
+	t.mu.Unlock()
+}
+
+// idle returns how long it has been since the last activity.
+func (t *activityTracker) idle() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.now().Sub(t.last)
+}
+
+// activityReader marks the tracker active whenever data is read.
+type activityReader struct {
+	r       io.Reader
+	tracker *activityTracker
+}
+
+func (a activityReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	if n > 0 {
+		a.tracker.touch()
+	}
+	return n, err
+}
+
+// activityWriter marks the tracker active whenever data is written.
+type activityWriter struct {
+	w       io.Writer
+	tracker *activityTracker
+}
+
+func (a activityWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		a.tracker.touch()
+	}
+	return a.w.Write(p)
+}
+
+// limitWatchdog enforces SessionLimits for interactiveSession, warning on notice before
+// check returns the reason and interactiveSession closes the ssh.Session.
+type limitWatchdog struct {
+	limits  SessionLimits
+	tracker *activityTracker
+	now     func() time.Time
+	started time.Time
+	notice  io.Writer
+
+	warnedMax  bool
+	warnedIdle bool
+}
+
+// check evaluates the limits once and reports the reason the session must close, or "" to keep going.
+func (w *limitWatchdog) check() string {
+	if w.limits.MaxSession > 0 {
+		remaining := w.limits.MaxSession - w.now().Sub(w.started)
+		if remaining <= 0 {
+			return fmt.Sprintf("maximum session length of %s reached", w.limits.MaxSession)
+		}
+		if remaining <= limitWarning && !w.warnedMax {
+			w.warnedMax = true
+			fmt.Fprintf(w.notice, "\r\n[enum] Session will close in %s (maximum session length %s)\r\n", remaining.Round(time.Second), w.limits.MaxSession)
+		}
+	}
+
+	if w.limits.IdleTimeout > 0 {
+		remaining := w.limits.IdleTimeout - w.tracker.idle()
+		if remaining <= 0 {
+			return fmt.Sprintf("idle for %s", w.limits.IdleTimeout)
+		}
+		if remaining > limitWarning {
+			w.warnedIdle = false // Activity resumed, warn again next time
+		} else if !w.warnedIdle {
+			w.warnedIdle = true
+			fmt.Fprintf(w.notice, "\r\n[enum] Session will close in %s unless there is activity\r\n", remaining.Round(time.Second))
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,186 @@
+package ssh
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable time source for activityTracker and limitWatchdog.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func TestActivityTrackerIdle(t *testing.T) {
+	clock := newFakeClock()
+	tracker := newActivityTracker(clock.Now)
+	if idle := tracker.idle(); idle != 0 {
+		t.Fatalf("idle = %v right after creation, want 0", idle)
+	}
+	clock.Advance(3 * time.Minute)
+	if idle := tracker.idle(); idle != 3*time.Minute {
+		t.Fatalf("idle = %v, want 3m", idle)
+	}
+	tracker.touch()
+	if idle := tracker.idle(); idle != 0 {
+		t.Fatalf("idle = %v after touch, want 0", idle)
+	}
+}
+
+func TestActivityReaderTouchesOnData(t *testing.T) {
+	clock := newFakeClock()
+	tracker := newActivityTracker(clock.Now)
+	r := activityReader{r: strings.NewReader("ls\n"), tracker: tracker}
+
+	clock.Advance(time.Minute)
+	buf := make([]byte, 16)
+	if n, err := r.Read(buf); n != 3 || err != nil {
+		t.Fatalf("Read = %d, %v", n, err)
+	}
+	if idle := tracker.idle(); idle != 0 {
+		t.Errorf("idle = %v after reading data, want 0", idle)
+	}
+
+	clock.Advance(time.Minute)
+	if _, err := r.Read(buf); err != io.EOF {
+		t.Fatalf("Read at end = %v, want EOF", err)
+	}
+	if idle := tracker.idle(); idle != time.Minute {
+		t.Errorf("idle = %v after an empty read, want 1m", idle)
+	}
+}
+
+func TestActivityWriterTouchesOnData(t *testing.T) {
+	clock := newFakeClock()
+	tracker := newActivityTracker(clock.Now)
+	var out bytes.Buffer
+	w := activityWriter{w: &out, tracker: tracker}
+
+	clock.Advance(time.Minute)
+	if _, err := w.Write(nil); err != nil {
+		t.Fatal(err)
+	}
+	if idle := tracker.idle(); idle != time.Minute {
+		t.Errorf("idle = %v after an empty write, want 1m", idle)
+	}
+	if _, err := w.Write([]byte("prompt$ ")); err != nil {
+		t.Fatal(err)
+	}
+	if idle := tracker.idle(); idle != 0 {
+		t.Errorf("idle = %v after writing data, want 0", idle)
+	}
+	if out.String() != "prompt$ " {
+		t.Errorf("wrote %q through the wrapper", out.String())
+	}
+}
+
+func newWatchdog(clock *fakeClock, limits SessionLimits) (*limitWatchdog, *activityTracker, *bytes.Buffer) {
+	tracker := newActivityTracker(clock.Now)
+	notice := &bytes.Buffer{}
+	return &limitWatchdog{limits: limits, tracker: tracker, now: clock.Now, started: clock.Now(), notice: notice}, tracker, notice
+}
+
+func TestWatchdogIdleTimeout(t *testing.T) {
+	clock := newFakeClock()
+	w, tracker, notice := newWatchdog(clock, SessionLimits{IdleTimeout: 10 * time.Minute})
+
+	clock.Advance(8 * time.Minute)
+	if reason := w.check(); reason != "" || notice.Len() != 0 {
+		t.Fatalf("at 8m idle: reason %q, notice %q", reason, notice)
+	}
+
+	clock.Advance(90 * time.Second)
+	if reason := w.check(); reason != "" {
+		t.Fatalf("closed at 9m30s idle: %q", reason)
+	}
+	if !strings.Contains(notice.String(), "will close in 30s unless there is activity") {
+		t.Fatalf("notice = %q, want the idle warning", notice)
+	}
+
+	notice.Reset()
+	clock.Advance(10 * time.Second)
+	w.check()
+	if notice.Len() != 0 {
+		t.Errorf("warned twice for one idle period: %q", notice)
+	}
+
+	// Activity resets the timer and re-arms the warning.
+	tracker.touch()
+	if reason := w.check(); reason != "" {
+		t.Fatalf("closed right after activity: %q", reason)
+	}
+	clock.Advance(9*time.Minute + 30*time.Second)
+	w.check()
+	if !strings.Contains(notice.String(), "unless there is activity") {
+		t.Errorf("no warning for the second idle period: %q", notice)
+	}
+
+	clock.Advance(30 * time.Second)
+	if reason := w.check(); reason != "idle for 10m0s" {
+		t.Errorf("reason = %q, want idle for 10m0s", reason)
+	}
+}
+
+func TestWatchdogMaxSession(t *testing.T) {
+	clock := newFakeClock()
+	w, tracker, notice := newWatchdog(clock, SessionLimits{MaxSession: 30 * time.Minute})
+
+	for i := 0; i < 28; i++ {
+		clock.Advance(time.Minute)
+		tracker.touch()
+		if reason := w.check(); reason != "" {
+			t.Fatalf("closed after %dm: %q", i+1, reason)
+		}
+	}
+	if notice.Len() != 0 {
+		t.Fatalf("warned early: %q", notice)
+	}
+
+	clock.Advance(time.Minute)
+	w.check()
+	if !strings.Contains(notice.String(), "will close in 1m0s (maximum session length 30m0s)") {
+		t.Fatalf("notice = %q, want the max session warning", notice)
+	}
+	notice.Reset()
+	clock.Advance(30 * time.Second)
+	w.check()
+	if notice.Len() != 0 {
+		t.Errorf("warned twice: %q", notice)
+	}
+
+	// Activity does not extend the maximum length.
+	tracker.touch()
+	clock.Advance(30 * time.Second)
+	if reason := w.check(); reason != "maximum session length of 30m0s reached" {
+		t.Errorf("reason = %q", reason)
+	}
+}
+
+func TestWatchdogDisabledLimits(t *testing.T) {
+	clock := newFakeClock()
+	w, _, notice := newWatchdog(clock, SessionLimits{})
+	clock.Advance(48 * time.Hour)
+	if reason := w.check(); reason != "" || notice.Len() != 0 {
+		t.Errorf("zero limits closed or warned: reason %q, notice %q", reason, notice)
+	}
+}
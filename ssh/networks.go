@@ -0,0 +1,61 @@
+package ssh
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DockerNetwork is a docker network on a host.
+type DockerNetwork struct {
+	ID      string
+	Name    string
+	Driver  string
+	Scope   string
+	Subnets []IPAMConfig
+}
+
+// IPAMConfig is one address pool of a network.
+type IPAMConfig struct {
+	Subnet  string
+	Gateway string
+}
+
+// FetchDockerNetworks lists the networks on host with their IPAM configuration, using cli
+// ("docker" or "nerdctl") to reach its container runtime. network ls doesn't show IPAM, so
+// every network it lists is inspected.
+func FetchDockerNetworks(host, cli string, opts SSHOptions) ([]DockerNetwork, error) {
+	cmd := fmt.Sprintf("sudo %[1]s network ls -q --no-trunc | xargs -r sudo %[1]s network inspect --format '{{json .}}'", cli)
+	output, err := SSHCommand(host, cmd, opts.Verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	var networks []DockerNetwork
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var raw struct {
+			ID     string `json:"Id"`
+			Name   string
+			Driver string
+			Scope  string
+			IPAM   struct {
+				Config []IPAMConfig
+			}
+		}
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			return nil, fmt.Errorf("unable to parse network %q: %v", line, err)
+		}
+		networks = append(networks, DockerNetwork{
+			ID:      raw.ID,
+			Name:    raw.Name,
+			Driver:  raw.Driver,
+			Scope:   raw.Scope,
+			Subnets: raw.IPAM.Config,
+		})
+	}
+	return networks, nil
+}
@@ -0,0 +1,58 @@
+package ssh
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"enum/scheduler"
+)
+
+// SSHOptions tunes how enum connects to a host for a remote operation.
+type SSHOptions struct {
+	Verbose   bool
+	Scheduler scheduler.Scheduler // Paces operations spanning several hosts; the zero value runs them one at a time
+}
+
+// DockerPlugin is a docker engine plugin, such as a volume driver, installed on a host.
+type DockerPlugin struct {
+	ID          string
+	Name        string
+	Description string
+	Enabled     bool
+	Version     string // Tag of the plugin reference, e.g. "latest"
+}
+
+// FetchDockerPlugins lists the plugins installed on host, using cli ("docker" or "nerdctl")
+// to reach its container runtime.
+func FetchDockerPlugins(host, cli string, opts SSHOptions) ([]DockerPlugin, error) {
+	output, err := SSHCommand(host, "sudo "+cli+" plugin ls --no-trunc --format '{{json .}}'", opts.Verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	var plugins []DockerPlugin
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var raw struct {
+			ID          string
+			Name        string
+			Description string
+			Enabled     bool
+		}
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			return nil, fmt.Errorf("unable to parse plugin %q: %v", line, err)
+		}
+
+		plugin := DockerPlugin{ID: raw.ID, Name: raw.Name, Description: raw.Description, Enabled: raw.Enabled}
+		// Plugin names carry their version as a tag: "rexray/ebs:0.11.4".
+		if i := strings.LastIndex(raw.Name, ":"); i > strings.LastIndex(raw.Name, "/") {
+			plugin.Name, plugin.Version = raw.Name[:i], raw.Name[i+1:]
+		}
+		plugins = append(plugins, plugin)
+	}
+	return plugins, nil
+}
@@ -1,42 +1,59 @@
 package ssh
 
 import (
-	"bytes"
 	"fmt"
 	"net"
 	"os"
 	"os/user"
+	"time"
+
+	"enum/trace"
 
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/term"
 )
 
-// SSHCommand executes a command on a remote host using SSH with the SSH agent and returns the output
-func SSHCommand(host, command string, verbose, ignoreExitCode bool) (string, error) {
+// CommandResult is the outcome of a remote command that ran to completion.
+type CommandResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// dial connects to host as the current user, authenticating with the SSH agent.
+func dial(host string, verbose bool) (*ssh.Client, error) {
+	span := trace.Start("ssh dial", host).Set("host", host)
+	defer span.End()
+
 	// Get the current system user
 	currentUser, err := user.Current()
 	if err != nil {
-		return "", fmt.Errorf("unable to get current user: %v", err)
+		return nil, fmt.Errorf("unable to get current user: %v", err)
 	}
 
 	// Connect to the SSH agent
 	sshAgent, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK"))
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to SSH agent: %v", err)
+		return nil, fmt.Errorf("failed to connect to SSH agent: %v", err)
 	}
-	defer sshAgent.Close()
+	defer sshAgent.Close() // Only needed for the handshake
 
 	agentClient := agent.NewClient(sshAgent)
 	authMethod := ssh.PublicKeysCallback(agentClient.Signers)
 
+	hostKeys, err := hostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+
 	// Set up the SSH client configuration
 	config := &ssh.ClientConfig{
 		User: currentUser.Username,
 		Auth: []ssh.AuthMethod{
 			authMethod,
 		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // Note: Insecure; see below for production recommendation
+		HostKeyCallback: hostKeys,
 	}
 
 	if verbose {
@@ -46,77 +63,61 @@ func SSHCommand(host, command string, verbose, ignoreExitCode bool) (string, err
 	// Establish the SSH connection
 	conn, err := ssh.Dial("tcp", host+":22", config)
 	if err != nil {
-		return "", fmt.Errorf("failed to dial SSH: %v", err)
+		return nil, fmt.Errorf("failed to dial SSH: %v", err)
 	}
-	defer conn.Close()
 
 	if verbose {
 		fmt.Println("SSH connection established")
 	}
+	return conn, nil
+}
 
-	// Create a new SSH session
-	session, err := conn.NewSession()
+// SSHRun executes a command on a remote host and returns its output and exit code.
+// A non-zero exit code is not an error; errors mean the command could not be run at all.
+func SSHRun(host, command string, verbose bool) (CommandResult, error) {
+	conn, err := Connect(host, verbose)
 	if err != nil {
-		return "", fmt.Errorf("failed to create SSH session: %v", err)
+		return CommandResult{}, err
 	}
-	defer session.Close()
-
-	if verbose {
-		fmt.Printf("Running command: %s\n", command)
-	}
-
-	// Capture the output of the remote command
-	var stdoutBuf, stderrBuf bytes.Buffer
-	session.Stdout = &stdoutBuf
-	session.Stderr = &stderrBuf
-	err = session.Run(command)
+	defer conn.Close()
+	return conn.Run(command)
+}
 
+// SSHCommand executes a command on a remote host using SSH with the SSH agent and returns the output.
+// Any non-zero exit code is reported as an error.
+func SSHCommand(host, command string, verbose bool) (string, error) {
+	result, err := SSHRun(host, command, verbose)
 	if err != nil {
-		_, ok := err.(*ssh.ExitError)
-		if ok && ignoreExitCode {
-			// If ignoring exit codes, return the output anyway
-			if verbose {
-				fmt.Println("Ignoring failed exit code")
-			}
-			return stdoutBuf.String(), nil
-		}
-		return "", fmt.Errorf("failed to run command '%s': %v\nStderr: %s", command, err, stderrBuf.String())
+		return "", err
 	}
-
-	return stdoutBuf.String(), nil
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("command '%s' exited with status %d\nStderr: %s", command, result.ExitCode, result.Stderr)
+	}
+	return result.Stdout, nil
 }
 
-// SSHCommand executes a command on a remote host using SSH with the SSH agent and streams the output to the console
-func SSHCommandStream(host, command string) error {
-	// Get the current system user
-	currentUser, err := user.Current()
+// SSHGrepCommand runs a remote pipeline that ends in grep. grep exiting 1 with no
+// output means nothing matched and yields an empty result; any other non-zero
+// exit is reported as an error.
+func SSHGrepCommand(host, command string, verbose bool) (string, error) {
+	result, err := SSHRun(host, command, verbose)
 	if err != nil {
-		return fmt.Errorf("unable to get current user: %v", err)
+		return "", err
 	}
-
-	// Connect to the SSH agent
-	sshAgent, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK"))
-	if err != nil {
-		return fmt.Errorf("failed to connect to SSH agent: %v", err)
+	if result.ExitCode == 1 && result.Stdout == "" {
+		return "", nil
 	}
-	defer sshAgent.Close()
-
-	agentClient := agent.NewClient(sshAgent)
-	authMethod := ssh.PublicKeysCallback(agentClient.Signers)
-
-	// Set up the SSH client configuration
-	config := &ssh.ClientConfig{
-		User: currentUser.Username,
-		Auth: []ssh.AuthMethod{
-			authMethod,
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // Note: Insecure; should implement proper host key checking
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("command '%s' exited with status %d\nStderr: %s", command, result.ExitCode, result.Stderr)
 	}
+	return result.Stdout, nil
+}
 
-	// Establish the SSH connection
-	conn, err := ssh.Dial("tcp", host+":22", config)
+// SSHCommandStream executes a command on a remote host using SSH with the SSH agent and streams the output to the console
+func SSHCommandStream(host, command string) error {
+	conn, err := dial(host, false)
 	if err != nil {
-		return fmt.Errorf("failed to dial: %v", err)
+		return err
 	}
 	defer conn.Close()
 
@@ -132,7 +133,7 @@ func SSHCommandStream(host, command string) error {
 	session.Stderr = os.Stderr
 
 	// Run the command
-	err = session.Run(command)
+	err = session.Run(withRemoteEnv(command))
 	if err != nil {
 		return fmt.Errorf("failed to run command: %v", err)
 	}
@@ -140,32 +141,28 @@ func SSHCommandStream(host, command string) error {
 	return nil
 }
 
-func SSHInteractiveShell(host string, containerID string, command string) error {
-	currentUser, err := user.Current()
-	if err != nil {
-		return fmt.Errorf("unable to get current user: %v", err)
-	}
-
-	sshAgent, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK"))
-	if err != nil {
-		return fmt.Errorf("failed to connect to SSH agent: %v", err)
+// SSHInteractiveShell runs command inside a container with the local terminal attached, using
+// cli ("docker" or "nerdctl") to exec into it, and closes the session when one of limits is reached.
+func SSHInteractiveShell(host string, cli string, containerID string, command string, limits SessionLimits) error {
+	execFlags := "-it"
+	if envFlags := execEnvFlags(); envFlags != "" {
+		execFlags += " " + envFlags
 	}
-	defer sshAgent.Close()
-
-	agentClient := agent.NewClient(sshAgent)
-	authMethod := ssh.PublicKeysCallback(agentClient.Signers)
+	return interactiveSession(host, withRemoteEnv(fmt.Sprintf("sudo %s exec %s %s %s", cli, execFlags, containerID, command)), limits)
+}
 
-	config := &ssh.ClientConfig{
-		User: currentUser.Username,
-		Auth: []ssh.AuthMethod{
-			authMethod,
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	}
+// SSHInteractiveHost opens a login shell on host with the local terminal attached, and
+// closes the session when one of limits is reached.
+func SSHInteractiveHost(host string, limits SessionLimits) error {
+	return interactiveSession(host, withRemoteEnv(`exec "${SHELL:-/bin/sh}" -l`), limits)
+}
 
-	conn, err := ssh.Dial("tcp", host+":22", config)
+// interactiveSession runs command on host in a pseudo terminal attached to the local one,
+// restoring the local terminal when it ends.
+func interactiveSession(host string, command string, limits SessionLimits) error {
+	conn, err := dial(host, false)
 	if err != nil {
-		return fmt.Errorf("failed to dial: %v", err)
+		return err
 	}
 	defer conn.Close()
 
@@ -200,24 +197,43 @@ func SSHInteractiveShell(host string, containerID string, command string) error
 		fmt.Fprintln(os.Stderr, "Warning: The input device is not a TTY. Interactive session may not behave as expected.")
 	}
 
-	session.Stdout = os.Stdout
-	session.Stderr = os.Stderr
-	session.Stdin = os.Stdin
-
-	// Concatenate shell command with arguments
-	fullCommand := fmt.Sprintf("sudo docker exec -it %s %s", containerID, command)
+	// Track traffic in both directions so idle sessions can be detected
+	tracker := newActivityTracker(time.Now)
+	session.Stdout = activityWriter{w: os.Stdout, tracker: tracker}
+	session.Stderr = activityWriter{w: os.Stderr, tracker: tracker}
+	session.Stdin = activityReader{r: os.Stdin, tracker: tracker}
+
+	closed := make(chan string, 1)
+	done := make(chan struct{})
+	defer close(done)
+	if limits.MaxSession > 0 || limits.IdleTimeout > 0 {
+		watchdog := &limitWatchdog{limits: limits, tracker: tracker, now: time.Now, started: time.Now(), notice: os.Stderr}
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					if reason := watchdog.check(); reason != "" {
+						fmt.Fprintf(os.Stderr, "\r\n[enum] Closing session: %s\r\n", reason)
+						closed <- reason
+						session.Close()
+						return
+					}
+				}
+			}
+		}()
+	}
 
-	if fullCommand != "" {
-		if err := session.Run(fullCommand); err != nil {
-			return fmt.Errorf("failed to run command: %v", err)
-		}
-	} else {
-		if err := session.Shell(); err != nil {
-			return fmt.Errorf("failed to start shell: %v", err)
-		}
-		if err := session.Wait(); err != nil {
-			return fmt.Errorf("shell exited with error: %v", err)
+	if err := session.Run(command); err != nil {
+		select {
+		case <-closed:
+			return nil // We closed the session on purpose
+		default:
 		}
+		return fmt.Errorf("failed to run command: %v", err)
 	}
 
 	return nil
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"enum/aws"
+	"enum/ssh"
+)
+
+// ecrWorkers bounds the number of concurrent ECR lookups.
+const ecrWorkers = 4
+
+// staleImages reports containers running an older digest than their tag currently points to in ECR.
+func staleImages() error {
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+
+	records := scanContainers(instances, false)
+	digests := runningDigests(records)
+
+	// Deduplicate the ECR lookups: one per repo:tag, one per running digest.
+	type lookup struct {
+		image  aws.ECRImage
+		digest string
+	}
+	var lookups []lookup
+	seen := make(map[lookup]bool)
+	for _, record := range records {
+		image, ok := aws.ParseECRImage(record.Image)
+		if !ok || image.Tag == "" {
+			continue
+		}
+		candidates := []lookup{{image: image}}
+		if digest := digests[record.ID]; digest != "" {
+			candidates = append(candidates, lookup{image: image, digest: digest})
+		}
+		for _, l := range candidates {
+			if !seen[l] {
+				seen[l] = true
+				lookups = append(lookups, l)
+			}
+		}
+	}
+
+	details := make(map[lookup]aws.ECRImageDetail)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan lookup)
+	for i := 0; i < ecrWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for l := range jobs {
+				detail, err := aws.FetchECRImageDetail(l.image, l.digest, awsProfile)
+				if err != nil {
+					log.Printf("Error querying ECR: %v", err)
+					continue
+				}
+				mu.Lock()
+				details[l] = detail
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, l := range lookups {
+		jobs <- l
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Image != records[j].Image {
+			return records[i].Image < records[j].Image
+		}
+		return records[i].Instance.Name < records[j].Instance.Name
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Instance\tContainer\tImage\tRunning Digest\tLatest Digest\tBehind By")
+	stale := 0
+	for _, record := range records {
+		image, ok := aws.ParseECRImage(record.Image)
+		if !ok {
+			fmt.Fprintf(w, "%s\t%s\t%s\tunknown\tunknown\tunknown\n", record.Instance.Name, record.Name, record.Image)
+			continue
+		}
+		if image.Tag == "" {
+			continue // Pinned to a digest, so there is no newer image for it to fall behind
+		}
+
+		latest, ok := details[lookup{image: image}]
+		if !ok {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\tunknown\tunknown\n", record.Instance.Name, record.Name, record.Image, shortDigest(digests[record.ID]))
+			continue
+		}
+		if digests[record.ID] == "" || digests[record.ID] == latest.Digest {
+			continue // Up to date, or nothing to compare against
+		}
+
+		behind := "unknown"
+		if running, ok := details[lookup{image: image, digest: digests[record.ID]}]; ok {
+			behind = latest.PushedAt.Sub(running.PushedAt).Round(time.Minute).String()
+		}
+		stale++
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.Instance.Name,
+			record.Name,
+			record.Image,
+			shortDigest(digests[record.ID]),
+			shortDigest(latest.Digest),
+			behind)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d of %d containers are running a stale image.\n", stale, len(records))
+	return nil
+}
+
+// runningDigests resolves the repo digest each scanned container is running, keyed by container ID.
+func runningDigests(records []containerRecord) map[string]string {
+	byInstance := make(map[string][]containerRecord)
+	for _, record := range records {
+		byInstance[record.Instance.PrivateIP] = append(byInstance[record.Instance.PrivateIP], record)
+	}
+
+	digests := make(map[string]string)
+	for host, hostRecords := range byInstance {
+		var ids []string
+		for _, record := range hostRecords {
+			ids = append(ids, record.ID)
+		}
+		cli := containerCLI(hostRecords[0].Instance)
+
+		// Map each container to the image ID it was created from.
+		output, err := ssh.SSHCommand(host, "sudo "+cli+" inspect --format '{{.Id}}\t{{.Image}}' "+strings.Join(ids, " "), false)
+		if err != nil {
+			log.Printf("Error inspecting containers on %s: %v", host, err)
+			continue
+		}
+		imageIDs := make(map[string]string)
+		var uniqueImages []string
+		for _, line := range strings.Split(output, "\n") {
+			parts := strings.Split(line, "\t")
+			if len(parts) < 2 {
+				continue
+			}
+			if !slices.Contains(uniqueImages, parts[1]) {
+				uniqueImages = append(uniqueImages, parts[1])
+			}
+			imageIDs[parts[0]] = parts[1]
+		}
+		if len(uniqueImages) == 0 {
+			continue
+		}
+
+		// Map each image ID to the repo digests it is known by.
+		output, err = ssh.SSHCommand(host, "sudo "+cli+" image inspect --format '{{.Id}}\t{{join .RepoDigests \" \"}}' "+strings.Join(uniqueImages, " "), false)
+		if err != nil {
+			log.Printf("Error inspecting images on %s: %v", host, err)
+			continue
+		}
+		repoDigests := make(map[string][]string)
+		for _, line := range strings.Split(output, "\n") {
+			parts := strings.Split(line, "\t")
+			if len(parts) < 2 {
+				continue
+			}
+			repoDigests[parts[0]] = strings.Fields(parts[1])
+		}
+
+		for _, record := range hostRecords {
+			repository := strings.SplitN(record.Image, ":", 2)[0]
+			if at := strings.Index(repository, "@"); at != -1 {
+				repository = repository[:at]
+			}
+			for fullID, imageID := range imageIDs {
+				if !strings.HasPrefix(fullID, record.ID) {
+					continue
+				}
+				for _, repoDigest := range repoDigests[imageID] {
+					if strings.HasPrefix(repoDigest, repository+"@") {
+						digests[record.ID] = aws.RepoDigest(repoDigest)
+					}
+				}
+			}
+		}
+	}
+
+	return digests
+}
+
+// shortDigest abbreviates a sha256 digest for display.
+func shortDigest(digest string) string {
+	digest = strings.TrimPrefix(digest, "sha256:")
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	if digest == "" {
+		return "unknown"
+	}
+	return digest
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"enum/aws"
+	"enum/timeparse"
+
+	"github.com/spf13/cobra"
+)
+
+// stoppedTaskFilter narrows stopped-tasks. The zero value shows every stopped task ECS retains.
+type stoppedTaskFilter struct {
+	Since   string // Only tasks stopped after this, in a timeparse format; empty for no cutoff
+	Last    int64  // At most this many of the newest tasks; 0 for all
+	Service string // Only tasks of this ECS service
+	Grep    string // Only tasks whose stopped reason matches this regular expression, case-insensitively
+}
+
+// runStoppedTasks is what the stopped and stopped-tasks commands run. Tests replace it.
+var runStoppedTasks = stoppedTasks
+
+// newStoppedTasksCmd builds stopped-tasks and stopped, which differ only in their default
+// window: stopped-tasks shows the last few tasks, stopped every task of the last 2h.
+func newStoppedTasksCmd(use, short, defaultSince string, defaultLast int64) *cobra.Command {
+	var filter stoppedTaskFilter
+	var output string
+	cmd := &cobra.Command{
+		Use:         use,
+		Short:       short,
+		Annotations: map[string]string{outputFormatsAnnotation: "table,json"},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runStoppedTasks(filter, output); err != nil {
+				log.Printf("Error listing stopped tasks: %v", err)
+			}
+		},
+	}
+	cmd.Flags().Int64Var(&filter.Last, "last", defaultLast, "Number of most recently stopped tasks to show; 0 shows all")
+	cmd.Flags().StringVar(&filter.Since, "since", defaultSince, "Only show tasks stopped after this: "+timeparse.Formats)
+	cmd.Flags().StringVar(&filter.Service, "service", "", "Only show tasks of this ECS service")
+	cmd.Flags().StringVar(&filter.Grep, "grep", "", "Only show tasks whose stopped reason matches this regular expression (case-insensitive)")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table or json")
+	return cmd
+}
+
+// stoppedTasks prints the tasks that stopped, newest first, narrowed by filter.
+func stoppedTasks(filter stoppedTaskFilter, output string) error {
+	if err := oneOf("table", "json")(output); err != nil {
+		return fmt.Errorf("unsupported output format %q: %v", output, err)
+	}
+	var since *timeparse.Time
+	if filter.Since != "" {
+		parsed, err := timeparse.ParseSinceUntil(filter.Since, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid --since: %v", err)
+		}
+		since = &parsed
+	}
+	var pattern *regexp.Regexp
+	if filter.Grep != "" {
+		var err error
+		if pattern, err = regexp.Compile("(?i)" + filter.Grep); err != nil {
+			return fmt.Errorf("invalid --grep pattern: %v", err)
+		}
+	}
+
+	// Fetch every retained task, so --last counts the tasks left after filtering.
+	tasks, err := aws.ListStoppedServiceTasks(ActiveConfig.ClusterName, filter.Service, awsProfile, 0)
+	if err != nil {
+		return err
+	}
+	matches := filterStoppedTasks(tasks, since, pattern, filter.Last)
+
+	if output == "json" {
+		if matches == nil {
+			matches = []aws.StoppedTaskInfo{}
+		}
+		return printJSON(matches)
+	}
+	if len(matches) == 0 {
+		window := ""
+		if since != nil {
+			window = " " + since.Since()
+		}
+		fmt.Printf("No stopped tasks found%s. ECS only keeps stopped tasks for about an hour, so older stops are no longer visible.\n", window)
+		return nil
+	}
+	aws.DisplayStoppedTasks(matches)
+	return nil
+}
+
+// filterStoppedTasks keeps the tasks, already newest first, that stopped after since and
+// whose reason matches pattern, when those are set, up to last of them when last is positive.
+func filterStoppedTasks(tasks []aws.StoppedTaskInfo, since *timeparse.Time, pattern *regexp.Regexp, last int64) []aws.StoppedTaskInfo {
+	var matches []aws.StoppedTaskInfo
+	for _, task := range tasks {
+		if since != nil && task.StoppedAt.Before(since.At) {
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(task.StoppedReason) {
+			continue
+		}
+		matches = append(matches, task)
+		if last > 0 && int64(len(matches)) == last {
+			break
+		}
+	}
+	return matches
+}
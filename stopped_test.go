@@ -0,0 +1,118 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"enum/aws"
+	"enum/timeparse"
+)
+
+func TestFilterStoppedTasks(t *testing.T) {
+	now := time.Date(2024, 6, 1, 14, 0, 0, 0, time.UTC)
+	task := func(arn, reason string, ago time.Duration) aws.StoppedTaskInfo {
+		return aws.StoppedTaskInfo{TaskArn: arn, StoppedReason: reason, StoppedAt: now.Add(-ago)}
+	}
+	tasks := []aws.StoppedTaskInfo{ // Newest first, as ListStoppedServiceTasks returns them
+		task("a", "Essential container in task exited", time.Minute),
+		task("b", "OutOfMemoryError: Container killed", 20*time.Minute),
+		task("c", "Scaling activity initiated by deployment", 40*time.Minute),
+		task("d", "OutOfMemoryError: Container killed", 3*time.Hour),
+	}
+	since := func(ago time.Duration) *timeparse.Time {
+		return &timeparse.Time{At: now.Add(-ago), Ago: ago}
+	}
+
+	tests := []struct {
+		name    string
+		since   *timeparse.Time
+		pattern string
+		last    int64
+		want    []string
+	}{
+		{name: "everything", want: []string{"a", "b", "c", "d"}},
+		{name: "last", last: 2, want: []string{"a", "b"}},
+		{name: "last beyond the tasks", last: 10, want: []string{"a", "b", "c", "d"}},
+		{name: "since", since: since(time.Hour), want: []string{"a", "b", "c"}},
+		{name: "grep", pattern: "outofmemory", want: []string{"b", "d"}},
+		{name: "last counts after grep", pattern: "outofmemory", last: 1, want: []string{"b"}},
+		{name: "since and grep", since: since(time.Hour), pattern: "OutOfMemory", want: []string{"b"}},
+		{name: "nothing", since: since(30 * time.Second), want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pattern *regexp.Regexp
+			if tt.pattern != "" {
+				pattern = regexp.MustCompile("(?i)" + tt.pattern)
+			}
+			var got []string
+			for _, task := range filterStoppedTasks(tasks, tt.since, pattern, tt.last) {
+				got = append(got, task.TaskArn)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoppedTasksRejectsBadFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter stoppedTaskFilter
+		output string
+	}{
+		{name: "output", output: "csv"},
+		{name: "since", filter: stoppedTaskFilter{Since: "yesterday-ish"}, output: "table"},
+		{name: "grep", filter: stoppedTaskFilter{Grep: "("}, output: "table"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// These fail before any AWS call.
+			if err := stoppedTasks(tt.filter, tt.output); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestStoppedCommandDefaults(t *testing.T) {
+	tests := []struct {
+		use       string
+		args      []string
+		wantSince string
+		wantLast  int64
+	}{
+		{use: "stopped", wantSince: "2h", wantLast: 0},
+		{use: "stopped", args: []string{"--since", "30m", "--service", "web"}, wantSince: "30m", wantLast: 0},
+		{use: "stopped-tasks", wantSince: "", wantLast: 10},
+		{use: "stopped-tasks", args: []string{"--last", "3"}, wantSince: "", wantLast: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.use+" "+strings.Join(tt.args, " "), func(t *testing.T) {
+			var got stoppedTaskFilter
+			previous := runStoppedTasks
+			t.Cleanup(func() { runStoppedTasks = previous })
+			runStoppedTasks = func(filter stoppedTaskFilter, output string) error {
+				got = filter
+				return nil
+			}
+
+			cmd, _, err := newRootCmd().Find([]string{tt.use})
+			if err != nil || cmd.Name() != tt.use {
+				t.Fatalf("no %s command: %v", tt.use, err)
+			}
+			// Run the command itself, skipping the root's config and AWS checks.
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			cmd.Run(cmd, nil)
+			if got.Since != tt.wantSince || got.Last != tt.wantLast {
+				t.Errorf("filter = %+v, want since %q and last %d", got, tt.wantSince, tt.wantLast)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestions caps how many names a "did you mean" hint lists.
+const maxSuggestions = 5
+
+// suggestNames returns the names that look like a misspelling of term, closest first.
+// Names are compared piece by piece (split on '-', '_' and '.'), so "paymnts" finds
+// "ecs-payments-web-3-payments-web-a1b2". Only already scanned names are used.
+func suggestNames(term string, names []string) []string {
+	term = strings.ToLower(strings.ReplaceAll(term, " ", ""))
+	if term == "" {
+		return nil
+	}
+	// Allow roughly one edit per three characters, and at least one.
+	maxDistance := max(1, len(term)/3)
+
+	best := make(map[string]int)
+	for _, name := range names {
+		lower := strings.ToLower(name)
+		candidates := strings.FieldsFunc(lower, func(r rune) bool {
+			return r == '-' || r == '_' || r == '.'
+		})
+		candidates = append(candidates, lower)
+		for _, candidate := range candidates {
+			distance := levenshtein(term, candidate)
+			// A candidate that starts with something close to the term also counts.
+			if len(candidate) > len(term) {
+				distance = min(distance, levenshtein(term, candidate[:len(term)])+1)
+			}
+			if distance > maxDistance {
+				continue
+			}
+			if current, ok := best[name]; !ok || distance < current {
+				best[name] = distance
+			}
+		}
+	}
+
+	var suggestions []string
+	for name := range best {
+		suggestions = append(suggestions, name)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if best[suggestions[i]] != best[suggestions[j]] {
+			return best[suggestions[i]] < best[suggestions[j]]
+		}
+		return suggestions[i] < suggestions[j]
+	})
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return suggestions
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// suggestionHint formats the stderr hint for a term with no matches, or "" when nothing is close.
+func suggestionHint(term string, records []containerRecord) string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, record := range records {
+		if !seen[record.Name] {
+			seen[record.Name] = true
+			names = append(names, record.Name)
+		}
+	}
+	suggestions := suggestNames(term, names)
+	if len(suggestions) == 0 {
+		return ""
+	}
+	return "no matches for '" + term + "'; did you mean: " + strings.Join(suggestions, ", ") + "?"
+}
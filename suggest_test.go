@@ -0,0 +1,31 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSuggestNames(t *testing.T) {
+	corpus := []string{"payments-web", "payments-worker", "billing-api", "orders", "redis"}
+
+	tests := []struct {
+		term string
+		want []string
+	}{
+		{"paymnts", []string{"payments-web", "payments-worker"}},
+		{"PAYMNTS", []string{"payments-web", "payments-worker"}},
+		{"payments-wrker", []string{"payments-worker", "payments-web"}},
+		{"ordrs", []string{"orders"}},     // One edit, within the cutoff of one for five characters
+		{"odrs", nil},                     // Two edits for four characters is past the cutoff
+		{"bill", []string{"billing-api"}}, // Close to the start of a piece
+		{"reddis", []string{"redis"}},
+		{"kafka", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := suggestNames(tt.term, corpus); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("suggestNames(%q) = %q, want %q", tt.term, got, tt.want)
+		}
+	}
+}
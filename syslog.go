@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+
+	"enum/aws"
+
+	"golang.org/x/term"
+)
+
+// syslogCommand prints the last lines of the host's system log, following it when follow
+// is set. It prefers the journal and falls back to /var/log/messages.
+func syslogCommand(lines int, follow bool) string {
+	journalFollow, tailFollow := "", ""
+	if follow {
+		journalFollow, tailFollow = " -f", " -F"
+	}
+	return fmt.Sprintf("if command -v journalctl >/dev/null 2>&1; then sudo journalctl --no-pager -o short-iso -n %d%s; else sudo tail -n %d%s /var/log/messages; fi",
+		lines, journalFollow, lines, tailFollow)
+}
+
+// syslog streams the system log of every running instance whose name contains filter,
+// interleaved and prefixed with the instance name, keeping only lines matching grep.
+func syslog(filter, grep string, lines int, follow bool) error {
+	var pattern *regexp.Regexp
+	if grep != "" {
+		var err error
+		if pattern, err = regexp.Compile(grep); err != nil {
+			return fmt.Errorf("invalid --grep pattern: %v", err)
+		}
+	}
+
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+	command := syslogCommand(lines, follow)
+	var sources []streamSource
+	for _, instance := range instances {
+		if instance.PrivateIP != "" && strings.Contains(instance.Name, filter) {
+			sources = append(sources, streamSource{Label: instance.Name, Host: instance.PrivateIP, Command: command})
+		}
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("no running instances match %q", filter)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	mux := &streamMultiplexer{
+		Out:    os.Stdout,
+		Filter: pattern,
+		Color:  term.IsTerminal(int(os.Stdout.Fd())),
+		Follow: follow,
+	}
+	mux.Run(ctx, sources)
+	return nil
+}
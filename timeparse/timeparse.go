@@ -0,0 +1,83 @@
+// Package timeparse parses the --since and --until style flags shared by commands that
+// look back over a window, so every command accepts the same human durations and
+// absolute timestamps.
+package timeparse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats lists what ParseSinceUntil accepts, for flag help and error messages.
+const Formats = `a duration such as 90m, 2h30m or 3d, or a time such as 2024-06-01T14:00:00Z, "2024-06-01 14:00" or 2024-06-01`
+
+// absoluteLayouts are tried in order. Layouts without a zone are read in the location
+// of the now passed to ParseSinceUntil.
+var absoluteLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Time is a point given either relative to now or as an absolute timestamp.
+type Time struct {
+	At  time.Time
+	Ago time.Duration // How far before now At is, when given as a duration; 0 for absolute times
+}
+
+// Since describes the window from t to now, e.g. "in the last 2h0m0s" or
+// "since 2024-06-01T14:00:00Z".
+func (t Time) Since() string {
+	if t.Ago > 0 {
+		return "in the last " + t.Ago.String()
+	}
+	return "since " + t.At.Format(time.RFC3339)
+}
+
+// ParseSinceUntil parses s as either a duration before now or an absolute timestamp.
+// Durations accept the time.ParseDuration units plus d for days, e.g. 3d or 1d12h.
+func ParseSinceUntil(s string, now time.Time) (Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Time{}, fmt.Errorf("empty time; use %s", Formats)
+	}
+	if d, err := ParseDuration(s); err == nil {
+		if d <= 0 {
+			return Time{}, fmt.Errorf("duration %q must be positive", s)
+		}
+		return Time{At: now.Add(-d), Ago: d}, nil
+	}
+	for _, layout := range absoluteLayouts {
+		if at, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return Time{At: at}, nil
+		}
+	}
+	return Time{}, fmt.Errorf("invalid time %q; use %s", s, Formats)
+}
+
+// ParseDuration is time.ParseDuration with d for 24-hour days, which may lead the
+// rest of the duration as in 1d12h.
+func ParseDuration(s string) (time.Duration, error) {
+	days, rest, found := strings.Cut(s, "d")
+	if !found {
+		return time.ParseDuration(s)
+	}
+	n, err := strconv.Atoi(days)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	d := time.Duration(n) * 24 * time.Hour
+	if rest == "" {
+		return d, nil
+	}
+	extra, err := time.ParseDuration(rest)
+	if err != nil || extra < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d + extra, nil
+}
@@ -0,0 +1,150 @@
+package timeparse
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "90m", want: 90 * time.Minute},
+		{in: "2h30m", want: 2*time.Hour + 30*time.Minute},
+		{in: "3d", want: 72 * time.Hour},
+		{in: "0d", want: 0},
+		{in: "1d12h", want: 36 * time.Hour},
+		{in: "1d30m15s", want: 24*time.Hour + 30*time.Minute + 15*time.Second},
+		{in: "0", want: 0},
+		{in: "-2h", want: -2 * time.Hour},
+		{in: "-1d", wantErr: true},
+		{in: "1d-2h", wantErr: true},
+		{in: "d", wantErr: true},
+		{in: "1.5d", wantErr: true},
+		{in: "1d2", wantErr: true},
+		{in: "2h1d", wantErr: true},
+		{in: "3days", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDuration(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDuration(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseSinceUntil(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, tokyo)
+
+	tests := []struct {
+		name    string
+		in      string
+		want    time.Time
+		wantAgo time.Duration
+	}{
+		{name: "minutes", in: "90m", want: now.Add(-90 * time.Minute), wantAgo: 90 * time.Minute},
+		{name: "days", in: "3d", want: now.Add(-72 * time.Hour), wantAgo: 72 * time.Hour},
+		{name: "days and hours", in: "1d12h", want: now.Add(-36 * time.Hour), wantAgo: 36 * time.Hour},
+		{name: "surrounding space", in: "  2h ", want: now.Add(-2 * time.Hour), wantAgo: 2 * time.Hour},
+		{name: "RFC3339 UTC", in: "2024-06-01T14:00:00Z", want: time.Date(2024, 6, 1, 14, 0, 0, 0, time.UTC)},
+		{name: "RFC3339 with offset", in: "2024-06-01T14:00:00-07:00", want: time.Date(2024, 6, 1, 21, 0, 0, 0, time.UTC)},
+		{name: "RFC3339 fractional seconds", in: "2024-06-01T14:00:00.250+02:00", want: time.Date(2024, 6, 1, 12, 0, 0, 250e6, time.UTC)},
+		{name: "zoneless T seconds", in: "2024-06-01T14:00:05", want: time.Date(2024, 6, 1, 14, 0, 5, 0, tokyo)},
+		{name: "zoneless space seconds", in: "2024-06-01 14:00:05", want: time.Date(2024, 6, 1, 14, 0, 5, 0, tokyo)},
+		{name: "zoneless T minutes", in: "2024-06-01T14:00", want: time.Date(2024, 6, 1, 14, 0, 0, 0, tokyo)},
+		{name: "zoneless space minutes", in: "2024-06-01 14:00", want: time.Date(2024, 6, 1, 14, 0, 0, 0, tokyo)},
+		{name: "date only", in: "2024-06-01", want: time.Date(2024, 6, 1, 0, 0, 0, 0, tokyo)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSinceUntil(tt.in, now)
+			if err != nil {
+				t.Fatalf("ParseSinceUntil(%q) error: %v", tt.in, err)
+			}
+			if !got.At.Equal(tt.want) {
+				t.Errorf("At = %v, want %v", got.At, tt.want)
+			}
+			if got.Ago != tt.wantAgo {
+				t.Errorf("Ago = %v, want %v", got.Ago, tt.wantAgo)
+			}
+		})
+	}
+}
+
+func TestParseSinceUntilZonelessUsesNowLocation(t *testing.T) {
+	at := "2024-06-01 14:00"
+	utc, err := ParseSinceUntil(at, time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork := time.FixedZone("EDT", -4*60*60)
+	local, err := ParseSinceUntil(at, time.Date(2024, 6, 10, 0, 0, 0, 0, newYork))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := local.At.Sub(utc.At); diff != 4*time.Hour {
+		t.Errorf("EDT reading is %v after the UTC one, want 4h", diff)
+	}
+	if local.At.Location() != newYork {
+		t.Errorf("location = %v, want %v", local.At.Location(), newYork)
+	}
+}
+
+func TestParseSinceUntilErrors(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: "empty time"},
+		{in: "   ", want: "empty time"},
+		{in: "0", want: "must be positive"},
+		{in: "0d", want: "must be positive"},
+		{in: "-2h", want: "must be positive"},
+		{in: "-1d", want: "invalid time"},
+		{in: "yesterday", want: "invalid time"},
+		{in: "2024-13-01", want: "invalid time"},
+		{in: "2024-06-01 25:00", want: "invalid time"},
+		{in: "06/01/2024", want: "invalid time"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSinceUntil(tt.in, now)
+		if err == nil {
+			t.Errorf("ParseSinceUntil(%q) succeeded, want error", tt.in)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseSinceUntil(%q) error %q, want containing %q", tt.in, err, tt.want)
+		}
+		if strings.HasPrefix(tt.want, "empty") || strings.HasPrefix(tt.want, "invalid") {
+			if !strings.Contains(err.Error(), Formats) {
+				t.Errorf("ParseSinceUntil(%q) error %q doesn't list the accepted formats", tt.in, err)
+			}
+		}
+	}
+}
+
+func TestTimeSince(t *testing.T) {
+	if got := (Time{Ago: 2 * time.Hour}).Since(); got != "in the last 2h0m0s" {
+		t.Errorf("relative Since() = %q", got)
+	}
+	at := time.Date(2024, 6, 1, 14, 0, 0, 0, time.UTC)
+	if got := (Time{At: at}).Since(); got != "since 2024-06-01T14:00:00Z" {
+		t.Errorf("absolute Since() = %q", got)
+	}
+}
@@ -0,0 +1,128 @@
+// Package trace records timed spans of a command's phases and writes them in the
+// Chrome trace-event format, which chrome://tracing and Perfetto can load.
+// Until Enable is called every function is a cheap no-op.
+package trace
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Span is a timed phase. A nil Span is valid and ignores every call.
+type Span struct {
+	name  string
+	start time.Time
+	lane  int
+	attrs map[string]any
+}
+
+// event is one complete ("X") event in the Chrome trace-event format.
+type event struct {
+	Name     string         `json:"name"`
+	Phase    string         `json:"ph"`
+	Start    int64          `json:"ts"`  // microseconds since the trace began
+	Duration int64          `json:"dur"` // microseconds
+	PID      int            `json:"pid"`
+	TID      int            `json:"tid"`
+	Args     map[string]any `json:"args,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	enabled bool
+	began   time.Time
+	events  []event
+	lanes   = make(map[string]int)
+)
+
+// Enable starts recording spans. Calling it again keeps the original start time.
+func Enable() {
+	mu.Lock()
+	defer mu.Unlock()
+	if !enabled {
+		enabled, began = true, time.Now()
+	}
+}
+
+// Start begins a span. Spans sharing a lane, typically the host they talk to, are
+// drawn on the same row of the viewer so concurrent hosts don't overlap.
+func Start(name, lane string) *Span {
+	return StartAt(name, lane, time.Now())
+}
+
+// StartAt begins a span that started at start, for phases timed by someone else,
+// such as the AWS SDK's request attempts.
+func StartAt(name, lane string, start time.Time) *Span {
+	mu.Lock()
+	defer mu.Unlock()
+	if !enabled {
+		return nil
+	}
+	id, ok := lanes[lane]
+	if !ok {
+		id = len(lanes) + 1
+		lanes[lane] = id
+	}
+	return &Span{name: name, start: start, lane: id, attrs: make(map[string]any)}
+}
+
+// Child begins a span within s on lane, such as one host's share of a cluster-wide
+// phase. Viewers only nest spans that share a lane, so the parent's name is recorded too.
+func (s *Span) Child(name, lane string) *Span {
+	if s == nil {
+		return nil
+	}
+	return Start(name, lane).Set("parent", s.name)
+}
+
+// Set records an attribute such as a host, byte count or exit code on the span.
+func (s *Span) Set(key string, value any) *Span {
+	if s != nil {
+		s.attrs[key] = value
+	}
+	return s
+}
+
+// End finishes the span and adds it to the trace.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	events = append(events, event{
+		Name:     s.name,
+		Phase:    "X",
+		Start:    s.start.Sub(began).Microseconds(),
+		Duration: time.Since(s.start).Microseconds(),
+		PID:      1,
+		TID:      s.lane,
+		Args:     s.attrs,
+	})
+}
+
+// Reset discards the recorded spans and disables tracing again, for tests.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled, began, events, lanes = false, time.Time{}, nil, make(map[string]int)
+}
+
+// WriteFile writes the recorded spans to path. It does nothing when tracing is disabled.
+func WriteFile(path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if !enabled {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(struct {
+		TraceEvents []event `json:"traceEvents"`
+	}{events}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
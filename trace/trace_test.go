@@ -0,0 +1,97 @@
+package trace
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// reset puts the package back to its disabled state when the test ends.
+func reset(t *testing.T) {
+	t.Helper()
+	t.Cleanup(Reset)
+}
+
+func TestDisabled(t *testing.T) {
+	reset(t)
+	span := Start("ssh exec", "10.0.0.1")
+	if span != nil {
+		t.Fatalf("Start returned %v while disabled, want nil", span)
+	}
+	span.Set("host", "10.0.0.1").End() // Must not panic
+
+	path := filepath.Join(t.TempDir(), "trace.json")
+	if err := WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("WriteFile wrote %s while disabled", path)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	reset(t)
+	Enable()
+	Start("fetch instances", "aws").Set("instances", 3).End()
+	Start("ssh exec", "10.0.0.1").Set("exit_code", 0).End()
+	Start("ssh exec", "10.0.0.2").End()
+	StartAt("aws request", "aws", time.Now().Add(-time.Second)).End()
+
+	path := filepath.Join(t.TempDir(), "trace.json")
+	if err := WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode loosely, as a trace viewer would, rather than into the package's own type.
+	var trace struct {
+		TraceEvents []map[string]any `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(data, &trace); err != nil {
+		t.Fatalf("trace is not valid JSON: %v\n%s", err, data)
+	}
+	if len(trace.TraceEvents) != 4 {
+		t.Fatalf("got %d events, want 4:\n%s", len(trace.TraceEvents), data)
+	}
+
+	wantNames := []string{"fetch instances", "ssh exec", "ssh exec", "aws request"}
+	wantLanes := []float64{1, 2, 3, 1} // Lanes are numbered in order of first use
+	for i, ev := range trace.TraceEvents {
+		for _, field := range []string{"name", "ph", "ts", "dur", "pid", "tid"} {
+			if _, ok := ev[field]; !ok {
+				t.Errorf("event %d lacks %q: %v", i, field, ev)
+			}
+		}
+		if ev["name"] != wantNames[i] {
+			t.Errorf("event %d name = %v, want %q", i, ev["name"], wantNames[i])
+		}
+		if ev["ph"] != "X" {
+			t.Errorf("event %d ph = %v, want X", i, ev["ph"])
+		}
+		if ev["tid"] != wantLanes[i] {
+			t.Errorf("event %d tid = %v, want %v", i, ev["tid"], wantLanes[i])
+		}
+		if dur, _ := ev["dur"].(float64); dur < 0 {
+			t.Errorf("event %d has negative duration %v", i, dur)
+		}
+	}
+
+	if args, _ := trace.TraceEvents[0]["args"].(map[string]any); args["instances"] != float64(3) {
+		t.Errorf("event 0 args = %v, want instances 3", trace.TraceEvents[0]["args"])
+	}
+	if _, ok := trace.TraceEvents[2]["args"]; ok {
+		t.Errorf("event 2 has args %v, want none", trace.TraceEvents[2]["args"])
+	}
+	// StartAt backdates the span, so it starts before the trace began and lasts about a second.
+	if ts, _ := trace.TraceEvents[3]["ts"].(float64); ts >= 0 {
+		t.Errorf("backdated event ts = %v, want negative", ts)
+	}
+	if dur, _ := trace.TraceEvents[3]["dur"].(float64); dur < float64(time.Second.Microseconds()) {
+		t.Errorf("backdated event dur = %v, want at least a second", dur)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"enum/aws"
+	"enum/ssh"
+
+	cryptossh "golang.org/x/crypto/ssh"
+)
+
+// trustHosts records each instance's SSH host keys in the enum known_hosts file,
+// preferring the keys cloud-init printed to the console at boot and falling back to
+// trusting whatever key the host presents now.
+func trustHosts() error {
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Instance\tPrivate IP\tKeys\tVerification")
+	verified := 0
+	for _, instance := range instances {
+		if instance.PrivateIP == "" {
+			continue
+		}
+
+		var keys []cryptossh.PublicKey
+		lines, err := aws.FetchConsoleHostKeys(instance.InstanceID, awsProfile)
+		if err != nil {
+			log.Printf("Error reading console output of %s: %v", instance.Name, err)
+		}
+		for _, line := range lines {
+			key, _, _, _, err := cryptossh.ParseAuthorizedKey([]byte(line))
+			if err == nil {
+				keys = append(keys, key)
+			}
+		}
+
+		method := "console output"
+		if len(keys) == 0 {
+			key, err := ssh.ScanHostKey(instance.PrivateIP)
+			if err != nil {
+				fmt.Fprintf(w, "%s\t%s\t0\tfailed: %v\n", instance.Name, instance.PrivateIP, err)
+				continue
+			}
+			log.Printf("Warning: no host keys in the console output of %s, trusting the key it presents on first use", instance.Name)
+			keys = []cryptossh.PublicKey{key}
+			method = "unverified (trust on first use)"
+		} else {
+			verified++
+		}
+
+		if err := ssh.SetKnownHostKeys(instance.PrivateIP, keys); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", instance.Name, instance.PrivateIP, len(keys), method)
+	}
+	w.Flush()
+
+	path, _ := ssh.KnownHostsPath()
+	fmt.Printf("\n%d of %d hosts verified out-of-band. Keys written to %s.\n", verified, len(instances), path)
+	return nil
+}
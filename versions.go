@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"enum/aws"
+	"enum/ssh"
+)
+
+// Components reported by the versions command, in display order.
+var versionComponents = []string{"docker", "containerd", "agent"}
+
+// versionsCommand prints the docker daemon's version as JSON, then a separator line,
+// then containerd's. Either half is empty when that binary is missing or fails.
+const versionsCommand = "sudo docker version --format '{{json .}}' 2>/dev/null; echo; echo ---; containerd --version 2>/dev/null; true"
+
+// unknownVersion stands in for a version that couldn't be read from a host.
+const unknownVersion = "unknown"
+
+// versionGroup is the instances of the fleet running one version of a component.
+type versionGroup struct {
+	Component string
+	Version   string
+	Instances []string
+}
+
+// fleetVersions reports the spread of docker, containerd and ECS agent versions across
+// the running instances. With expect ("docker=24.0.7,agent=1.79.0") it returns the
+// number of instances deviating from the expected versions.
+func fleetVersions(expect string) (int, error) {
+	expected, err := parseExpectedVersions(expect)
+	if err != nil {
+		return 0, err
+	}
+
+	instances, err := fetchInstances(aws.RunningStates)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching EC2 instance data: %v", err)
+	}
+
+	// Each host fills its own slot so results keep the instance order.
+	perHost := make([]map[string]string, len(instances))
+	hostScheduler.Run(len(instances), func(i int) {
+		instance := instances[i]
+		perHost[i] = map[string]string{"docker": unknownVersion, "containerd": unknownVersion, "agent": instance.AgentVersion}
+		if instance.AgentVersion == "" {
+			perHost[i]["agent"] = unknownVersion
+		}
+		if instance.PrivateIP == "" {
+			return
+		}
+		output, err := ssh.SSHCommand(instance.PrivateIP, versionsCommand, verbose)
+		if err != nil {
+			log.Printf("Error reading versions on instance %s: %v", instance.Name, err)
+			return
+		}
+		docker, containerd := parseVersionsOutput(output)
+		if docker != "" {
+			perHost[i]["docker"] = docker
+		}
+		if containerd != "" {
+			perHost[i]["containerd"] = containerd
+		}
+	})
+
+	var groups []versionGroup
+	for _, component := range versionComponents {
+		byVersion := make(map[string][]string)
+		for i, versions := range perHost {
+			byVersion[versions[component]] = append(byVersion[versions[component]], instances[i].Name)
+		}
+		var componentGroups []versionGroup
+		for version, names := range byVersion {
+			componentGroups = append(componentGroups, versionGroup{Component: component, Version: version, Instances: names})
+		}
+		sort.Slice(componentGroups, func(i, j int) bool {
+			if len(componentGroups[i].Instances) != len(componentGroups[j].Instances) {
+				return len(componentGroups[i].Instances) > len(componentGroups[j].Instances)
+			}
+			return componentGroups[i].Version < componentGroups[j].Version
+		})
+		groups = append(groups, componentGroups...)
+	}
+
+	// The most common version of each component comes first; the instances on any
+	// other version are listed as outliers.
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Component\tVersion\tInstances\tOutliers")
+	for i, group := range groups {
+		outliers := "-"
+		if i > 0 && groups[i-1].Component == group.Component {
+			outliers = strings.Join(group.Instances, ", ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", group.Component, group.Version, len(group.Instances), outliers)
+	}
+	w.Flush()
+
+	if len(expected) == 0 {
+		return 0, nil
+	}
+	var deviating []string
+	for i, versions := range perHost {
+		var wrong []string
+		for _, component := range versionComponents {
+			if want, ok := expected[component]; ok && versions[component] != want {
+				wrong = append(wrong, fmt.Sprintf("%s=%s", component, versions[component]))
+			}
+		}
+		if len(wrong) > 0 {
+			deviating = append(deviating, fmt.Sprintf("%s (%s)", instances[i].Name, strings.Join(wrong, ", ")))
+		}
+	}
+	if len(deviating) > 0 {
+		fmt.Printf("\n%d of %d instances deviate from %s:\n", len(deviating), len(instances), expect)
+		for _, line := range deviating {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	return len(deviating), nil
+}
+
+// parseExpectedVersions parses --expect, e.g. "docker=24.0.7,agent=1.79.0".
+func parseExpectedVersions(expect string) (map[string]string, error) {
+	expected := make(map[string]string)
+	if expect == "" {
+		return expected, nil
+	}
+	for _, pair := range strings.Split(expect, ",") {
+		component, version, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || version == "" {
+			return nil, fmt.Errorf("invalid --expect entry %q, want component=version", pair)
+		}
+		if err := oneOf(versionComponents...)(component); err != nil {
+			return nil, fmt.Errorf("invalid --expect component %q: %v", component, err)
+		}
+		expected[component] = version
+	}
+	return expected, nil
+}
+
+// parseVersionsOutput extracts the docker server and containerd versions from the output
+// of versionsCommand. containerd --version prints e.g.
+// "containerd github.com/containerd/containerd 1.7.11 64b8a811b07b".
+func parseVersionsOutput(output string) (docker, containerd string) {
+	dockerPart, containerdPart, _ := strings.Cut(output, "\n---\n")
+
+	var info struct {
+		Server *struct {
+			Version string
+		}
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(dockerPart)), &info); err == nil && info.Server != nil {
+		docker = info.Server.Version
+	}
+
+	if fields := strings.Fields(containerdPart); len(fields) >= 3 {
+		containerd = strings.TrimPrefix(fields[2], "v")
+	}
+	return docker, containerd
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"enum/aws"
+	"enum/ssh"
+)
+
+// ecsLabels are the docker labels the ECS agent puts on the containers it starts.
+var ecsLabels = []string{
+	"com.amazonaws.ecs.task-arn",
+	"com.amazonaws.ecs.task-definition-family",
+	"com.amazonaws.ecs.task-definition-version",
+	"com.amazonaws.ecs.container-name",
+	"com.amazonaws.ecs.cluster",
+}
+
+// whois maps a docker container back to the ECS task and service that own it.
+func whois(containerID string) error {
+	instance, err := locateContainer(containerID)
+	if err != nil {
+		return err
+	}
+
+	var format []string
+	for _, label := range ecsLabels {
+		format = append(format, fmt.Sprintf("{{index .Config.Labels %q}}", label))
+	}
+	cmd := fmt.Sprintf("sudo %s inspect --format '{{.Name}}\t%s' %s", containerCLI(instance), strings.Join(format, "\t"), containerID)
+	output, err := ssh.SSHCommand(instance.PrivateIP, cmd, false)
+	if err != nil {
+		return fmt.Errorf("error inspecting container on instance %s: %v", instance.Name, err)
+	}
+	parts := strings.Split(strings.TrimSpace(output), "\t")
+	if len(parts) < len(ecsLabels)+1 {
+		return fmt.Errorf("unexpected inspect output %q", output)
+	}
+	name := strings.TrimPrefix(parts[0], "/")
+	taskArn, family, revision, containerName, cluster := parts[1], parts[2], parts[3], parts[4], parts[5]
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintf(w, "Container:\t%s (%s)\n", name, containerID)
+	fmt.Fprintf(w, "Instance:\t%s (%s, %s)\n", instance.Name, instance.InstanceID, instance.PrivateIP)
+	if taskArn == "" {
+		fmt.Fprintln(w, "ECS:\tunmanaged (no ECS labels on this container)")
+		return nil
+	}
+
+	fmt.Fprintf(w, "Cluster:\t%s\n", cluster)
+	fmt.Fprintf(w, "Task definition:\t%s:%s\n", family, revision)
+	fmt.Fprintf(w, "Container name:\t%s\n", containerName)
+	fmt.Fprintf(w, "Task:\t%s\n", taskArn)
+
+	task, err := aws.DescribeTask(cluster, taskArn, awsProfile)
+	if err != nil {
+		fmt.Fprintf(w, "Status:\tunavailable (%v)\n", err)
+	} else {
+		fmt.Fprintf(w, "Group:\t%s\n", task.Group)
+		fmt.Fprintf(w, "Status:\t%s (desired %s)\n", task.LastStatus, task.DesiredStatus)
+		fmt.Fprintf(w, "Started by:\t%s\n", task.StartedBy)
+		if !task.StartedAt.IsZero() {
+			fmt.Fprintf(w, "Started at:\t%s\n", task.StartedAt.Local().Format(time.RFC3339))
+		}
+	}
+	fmt.Fprintf(w, "Console:\t%s\n", aws.TaskConsoleURL(cluster, taskArn))
+	return nil
+}